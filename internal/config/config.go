@@ -7,8 +7,50 @@ type Config struct {
 	Addr string
 	Host string
 	Jwt  struct {
-		Secret string
-		Expire int64
+		Secret        string
+		Expire        int64 // 访问令牌有效期（秒）
+		RefreshExpire int64 // 刷新令牌有效期（秒）
+	}
+	// Login 登录失败锁定策略
+	Login struct {
+		MaxFailures   int   // 账号在统计窗口内允许的失败次数
+		IpMaxFailures int   // 同一IP在统计窗口内允许的失败次数
+		FailureWindow int64 // 失败次数的统计窗口（秒）
+		LockDuration  int64 // 达到失败次数后锁定的时长（秒）
+	}
+	// Password 密码策略
+	Password struct {
+		MinLength     int
+		RequireUpper  bool
+		RequireLower  bool
+		RequireDigit  bool
+		RequireSymbol bool
+		ExpireDays    int // 密码有效天数，0 表示不过期
+	}
+	TwoFactor struct {
+		Issuer string // 验证器应用中显示的发行方名称
+	}
+	// Oidc 使用公司身份提供方单点登录
+	Oidc struct {
+		Enable       bool
+		Issuer       string
+		ClientId     string
+		ClientSecret string
+		RedirectUrl  string   // 登录完成后身份提供方跳转的前端地址，前端再调用 /v1/sso/callback
+		Scopes       []string // 为空时使用 openid profile email
+		GroupsClaim  string   // 用户组的声明字段，为空时使用 groups
+		Provision    bool     // 首次登录时自动创建账号
+		LinkEmail    bool     // 默认租户按已验证的邮箱关联已有账号，其他租户见租户配置，默认不关联
+		// GroupMappings 用户组与部门、角色的对应关系，每次登录时按用户组同步
+		GroupMappings []struct {
+			Group    string
+			DepName  string
+			RoleCode string
+		}
+	}
+	// Audit 审计日志
+	Audit struct {
+		RetentionDays int // 保留天数，为0时默认保留180天
 	}
 	MysqlDns string
 	Mongo    struct {
@@ -27,7 +69,16 @@ type Config struct {
 	}
 
 	Ws struct {
-		Addr string
+		Addr      string
+		PongWait  int64 // 等待客户端响应心跳的时长（秒），为0时默认60秒，心跳间隔为其9/10
+		SendQueue int   // 每个连接待发送消息的队列长度，队列满时视为慢速连接并断开，为0时默认256
+		// Cluster 集群模式，多个实例部署在负载均衡之后，通过Redis记录用户的连接所在的实例并转发消息
+		Cluster bool
+		NodeId  string // 实例ID，为空时每次启动随机生成
+	}
+	// Chat 即时消息
+	Chat struct {
+		RecallWindow int64 // 消息发送后允许撤回的时长（秒），为0时默认120秒
 	}
 
 	Langchain struct {
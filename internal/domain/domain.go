@@ -19,11 +19,44 @@ type User struct {
 	Password string `json:"password,omitempty"`
 	Name     string `json:"name,omitempty"`
 	Status   int    `json:"status,omitempty"`
+
+	Email        string `json:"email,omitempty"`
+	Phone        string `json:"phone,omitempty"`
+	EmployeeNo   string `json:"employeeNo,omitempty"`
+	JobTitle     string `json:"jobTitle,omitempty"`
+	HireDate     int64  `json:"hireDate,omitempty"`
+	ManagerId    string `json:"managerId,omitempty"`
+	ManagerName  string `json:"managerName,omitempty"`
+	Avatar       string `json:"avatar,omitempty"`
+	WorkLocation string `json:"workLocation,omitempty"`
+	DepId        string `json:"depId,omitempty"`
+	DepName      string `json:"depName,omitempty"`
+	Position     string `json:"position,omitempty"`
+	Presence     string `json:"presence,omitempty"` // 在线状态，online 在线，away 离开，offline 离线
+	LastSeen     int64  `json:"lastSeen,omitempty"` // 最后在线的时间
+
+	TwoFactorEnabled bool `json:"twoFactorEnabled,omitempty"`
+	ServiceAccount   bool `json:"serviceAccount,omitempty"` // 服务账号不能登录，只能使用API密钥访问
 }
 
 type LoginReq struct {
+	Tenant   string `json:"tenant,omitempty"` // 租户编码，默认租户不填
 	Name     string `json:"name,omitempty"`
 	Password string `json:"password,omitempty"`
+
+	Ip        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// LoginPasswordReq 登录时密码需要修改，修改密码后完成登录
+type LoginPasswordReq struct {
+	Tenant string `json:"tenant,omitempty"`
+	Name   string `json:"name"`
+	OldPwd string `json:"oldPwd"`
+	NewPwd string `json:"newPwd"`
+
+	Ip        string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type LoginResp struct {
@@ -33,13 +66,70 @@ type LoginResp struct {
 	AccessToken  string `json:"token,omitempty"`
 	AccessExpire int64  `json:"accessExpire,omitempty"`
 	RefreshAfter int64  `json:"refreshAfter,omitempty"`
+	// 访问令牌过期后使用刷新令牌换取新的令牌，刷新令牌每次使用后都会轮换
+	RefreshToken  string `json:"refreshToken,omitempty"`
+	RefreshExpire int64  `json:"refreshExpire,omitempty"`
+	// 开启了二次验证时返回，使用该令牌和验证码完成登录
+	TwoFactorToken string `json:"twoFactorToken,omitempty"`
+}
+
+// TwoFactorLoginReq 登录时的二次验证，Code 为验证器应用的验证码或恢复码
+type TwoFactorLoginReq struct {
+	Token string `json:"token"`
+	Code  string `json:"code"`
+
+	Ip        string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+type TwoFactorCodeReq struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorEnrollResp struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"` // otpauth地址，客户端渲染为二维码供验证器应用扫描
+}
+
+type RecoveryCodesResp struct {
+	Codes []string `json:"codes"`
+}
+
+// SsoAuthorizeReq 获取单点登录地址，tenant 为登录的租户编码，默认租户不填
+type SsoAuthorizeReq struct {
+	Tenant string `json:"tenant,omitempty" form:"tenant,omitempty"`
+}
+
+type SsoAuthorizeResp struct {
+	Url   string `json:"url"`   // 身份提供方的登录地址
+	State string `json:"state"` // 回调时需要原样传回，客户端应校验回调中的state与之一致
+
+	Binding string `json:"-"` // 写入浏览器Cookie的绑定值
+}
+
+// SsoCallbackReq 身份提供方登录完成后回调地址中的参数
+type SsoCallbackReq struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+
+	Ip        string `json:"-"`
+	UserAgent string `json:"-"`
+	Binding   string `json:"-"` // 发起登录时写入浏览器Cookie的绑定值
+}
+
+type RefreshReq struct {
+	RefreshToken string `json:"refreshToken"`
 }
 
 type UserListReq struct {
-	Ids   []string `json:"ids,omitempty"`
-	Name  string   `json:"name,omitempty"`
-	Page  int      `json:"page,omitempty"`
-	Count int      `json:"count,omitempty"`
+	Ids          []string `json:"ids,omitempty" form:"ids,omitempty"`
+	Name         string   `json:"name,omitempty" form:"name,omitempty"`
+	Keyword      string   `json:"keyword,omitempty" form:"keyword,omitempty"`
+	WorkLocation string   `json:"workLocation,omitempty" form:"workLocation,omitempty"`
+	ManagerId    string   `json:"managerId,omitempty" form:"managerId,omitempty"`
+	DepName      string   `json:"depName,omitempty" form:"depName,omitempty"`
+	Page         int      `json:"page,omitempty" form:"page,omitempty"`
+	Count        int      `json:"count,omitempty" form:"count,omitempty"`
 }
 
 type UserListResp struct {
@@ -47,12 +137,269 @@ type UserListResp struct {
 	List  []*User `json:"data"`
 }
 
+type LoginEventListReq struct {
+	UserId string `json:"userId,omitempty" form:"userId,omitempty"`
+	Name   string `json:"name,omitempty" form:"name,omitempty"`
+	Ip     string `json:"ip,omitempty" form:"ip,omitempty"`
+	Result int    `json:"result,omitempty" form:"result,omitempty"`
+	Page   int    `json:"page,omitempty" form:"page,omitempty"`
+	Count  int    `json:"count,omitempty" form:"count,omitempty"`
+}
+
+type LoginEvent struct {
+	Id         string `json:"id"`
+	UserId     string `json:"userId,omitempty"`
+	Name       string `json:"name"`
+	Ip         string `json:"ip"`
+	UserAgent  string `json:"userAgent"`
+	Result     int    `json:"result"`
+	ResultName string `json:"resultName"`
+	Reason     string `json:"reason,omitempty"`
+	CreateAt   int64  `json:"createAt"`
+}
+
+type LoginEventListResp struct {
+	Count int64         `json:"count"`
+	List  []*LoginEvent `json:"data"`
+}
+
 type UpPasswordReq struct {
 	Id     string `json:"id"`
 	OldPwd string `json:"oldPwd"`
 	NewPwd string `json:"newPwd"`
 }
 
+type Permission struct {
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+type Role struct {
+	Id          string        `json:"id,omitempty"`
+	Code        string        `json:"code,omitempty"`
+	Name        string        `json:"name,omitempty"`
+	Permissions []*Permission `json:"permissions,omitempty"`
+	IsSystem    bool          `json:"isSystem,omitempty"`
+
+	RequireTwoFactor bool `json:"requireTwoFactor,omitempty"` // 拥有该角色的用户必须开启二次验证
+}
+
+type RoleTwoFactorReq struct {
+	Id               string `json:"id"`
+	RequireTwoFactor bool   `json:"requireTwoFactor"`
+}
+
+type RoleListResp struct {
+	List []*Role `json:"data"`
+}
+
+type UserRole struct {
+	Id       string `json:"id,omitempty"`
+	UserId   string `json:"userId,omitempty"`
+	RoleId   string `json:"roleId,omitempty"`
+	RoleCode string `json:"roleCode,omitempty"`
+	RoleName string `json:"roleName,omitempty"`
+	DepId    string `json:"depId,omitempty"`
+}
+
+type UserRoleListResp struct {
+	List []*UserRole `json:"data"`
+}
+
+// ApiKeyCreateReq 创建API密钥，UserId 为空时为当前用户创建，管理员可以为服务账号创建
+type ApiKeyCreateReq struct {
+	UserId   string        `json:"userId,omitempty"`
+	Name     string        `json:"name"`
+	Scopes   []*Permission `json:"scopes"`
+	ExpireAt int64         `json:"expireAt,omitempty"` // 为0时不过期
+}
+
+// ApiKeyCreateResp 密钥只在创建时返回一次
+type ApiKeyCreateResp struct {
+	Id  string `json:"id"`
+	Key string `json:"key"`
+}
+
+type ApiKey struct {
+	Id         string        `json:"id"`
+	UserId     string        `json:"userId"`
+	UserName   string        `json:"userName,omitempty"`
+	CreatorId  string        `json:"creatorId"`
+	Name       string        `json:"name"`
+	Prefix     string        `json:"prefix"`
+	Scopes     []*Permission `json:"scopes"`
+	ExpireAt   int64         `json:"expireAt,omitempty"`
+	LastUsedAt int64         `json:"lastUsedAt,omitempty"`
+	RevokedAt  int64         `json:"revokedAt,omitempty"`
+	CreateAt   int64         `json:"createAt"`
+}
+
+type ApiKeyListReq struct {
+	UserId  string `json:"userId,omitempty" form:"userId,omitempty"`
+	Revoked bool   `json:"revoked,omitempty" form:"revoked,omitempty"` // 是否包含已撤销的密钥
+	Page    int    `json:"page,omitempty" form:"page,omitempty"`
+	Count   int    `json:"count,omitempty" form:"count,omitempty"`
+}
+
+type ApiKeyListResp struct {
+	Count int64     `json:"count"`
+	List  []*ApiKey `json:"data"`
+}
+
+type Tenant struct {
+	Id       string        `json:"id"`
+	Code     string        `json:"code"`
+	Name     string        `json:"name"`
+	Status   int           `json:"status"`
+	Config   *TenantConfig `json:"config,omitempty"`
+	CreateAt int64         `json:"createAt,omitempty"`
+}
+
+// TenantConfig 租户配置，查询时不返回大模型的密钥，修改时密钥为空表示不修改
+type TenantConfig struct {
+	Llm               *TenantLlm          `json:"llm,omitempty"`
+	ApprovalTemplates []*ApprovalTemplate `json:"approvalTemplates,omitempty"`
+	SsoLinkEmail      bool                `json:"ssoLinkEmail,omitempty"`
+}
+
+type TenantLlm struct {
+	Url    string `json:"url"`
+	ApiKey string `json:"apiKey,omitempty"`
+	Model  string `json:"model,omitempty"`
+}
+
+type ApprovalTemplate struct {
+	Type      int      `json:"type"`
+	Disabled  bool     `json:"disabled,omitempty"`
+	Approvers []string `json:"approvers,omitempty"`
+}
+
+// TenantCreateReq 创建租户，同时创建租户的管理员账号，管理员首次登录需要修改密码
+type TenantCreateReq struct {
+	Code          string        `json:"code"`
+	Name          string        `json:"name"`
+	AdminName     string        `json:"adminName"`
+	AdminPassword string        `json:"adminPassword"`
+	Config        *TenantConfig `json:"config,omitempty"`
+}
+
+type TenantEditReq struct {
+	Id     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Status *int   `json:"status,omitempty"` // 不传时不修改状态
+}
+
+type TenantListReq struct {
+	Page  int `json:"page,omitempty" form:"page,omitempty"`
+	Count int `json:"count,omitempty" form:"count,omitempty"`
+}
+
+type TenantListResp struct {
+	Count int64     `json:"count"`
+	List  []*Tenant `json:"data"`
+}
+
+type AuditListReq struct {
+	ActorId    string `json:"actorId,omitempty" form:"actorId,omitempty"`
+	Action     string `json:"action,omitempty" form:"action,omitempty"`
+	Resource   string `json:"resource,omitempty" form:"resource,omitempty"`
+	ResourceId string `json:"resourceId,omitempty" form:"resourceId,omitempty"`
+	TraceId    string `json:"traceId,omitempty" form:"traceId,omitempty"`
+	StartTime  int64  `json:"startTime,omitempty" form:"startTime,omitempty"`
+	EndTime    int64  `json:"endTime,omitempty" form:"endTime,omitempty"`
+	Page       int    `json:"page,omitempty" form:"page,omitempty"`
+	Count      int    `json:"count,omitempty" form:"count,omitempty"`
+}
+
+type AuditChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+type AuditLog struct {
+	Id         string         `json:"id"`
+	ActorId    string         `json:"actorId,omitempty"`
+	ActorName  string         `json:"actorName,omitempty"`
+	ApiKeyId   string         `json:"apiKeyId,omitempty"`
+	Action     string         `json:"action"`
+	Resource   string         `json:"resource"`
+	ResourceId string         `json:"resourceId,omitempty"`
+	Diff       []*AuditChange `json:"diff,omitempty"`
+	Method     string         `json:"method,omitempty"`
+	Path       string         `json:"path,omitempty"`
+	Status     int            `json:"status,omitempty"`
+	Ip         string         `json:"ip,omitempty"`
+	UserAgent  string         `json:"userAgent,omitempty"`
+	TraceId    string         `json:"traceId,omitempty"`
+	CreateAt   int64          `json:"createAt"`
+}
+
+type AuditListResp struct {
+	Count int64       `json:"count"`
+	List  []*AuditLog `json:"data"`
+}
+
+type Group struct {
+	Id        string   `json:"id"`
+	Name      string   `json:"name"`
+	OwnerId   string   `json:"ownerId,omitempty"`
+	AdminIds  []string `json:"adminIds,omitempty"`
+	MemberIds []string `json:"memberIds,omitempty"`
+	DepId     string   `json:"depId,omitempty"`
+	CreateAt  int64    `json:"createAt,omitempty"`
+}
+
+type GroupCreateReq struct {
+	Name      string   `json:"name"`
+	MemberIds []string `json:"memberIds"`
+}
+
+type GroupEditReq struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type GroupMemberReq struct {
+	Id      string   `json:"id"`
+	UserIds []string `json:"userIds"`
+}
+
+type GroupListResp struct {
+	List []*Group `json:"data"`
+}
+
+type ImportReq struct {
+	DryRun bool   `form:"dryRun,omitempty"`
+	Format string `form:"-"`
+	Data   []byte `form:"-"`
+}
+
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+type ImportResp struct {
+	DryRun       bool              `json:"dryRun"`
+	Total        int               `json:"total"`
+	CreatedUsers int               `json:"createdUsers"`
+	UpdatedUsers int               `json:"updatedUsers"`
+	CreatedDeps  int               `json:"createdDeps"`
+	UpdatedDeps  int               `json:"updatedDeps"`
+	Errors       []*ImportRowError `json:"errors,omitempty"`
+}
+
+type ExportReq struct {
+	Format string `form:"format,omitempty"`
+}
+
+type ExportResp struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
 type Department struct {
 	Id         string        `json:"id, omitempty"`
 	Name       string        `json:"name, omitempty"`
@@ -61,20 +408,68 @@ type Department struct {
 	Level      int           `json:"level, omitempty"`
 	LeaderId   string        `json:"leaderId, omitempty"`
 	Leader     string        `json:"leader, omitempty"`
+	NeedLeader bool          `json:"needLeader,omitempty"`
+	Group      bool          `json:"group,omitempty"`
 	Count      int64         `json:"count, omitempty"`
 	Child      []*Department `json:"child, omitempty"`
 }
 
 type DepartmentUser struct {
-	Id       string `json:"id,omitempty"`
-	UserId   string `json:"user,omitempty"`
-	DepId    string `json:"dep,omitempty"`
-	UserName string `json:"userName,omitempty"`
+	Id        string `json:"id,omitempty"`
+	UserId    string `json:"user,omitempty"`
+	DepId     string `json:"dep,omitempty"`
+	UserName  string `json:"userName,omitempty"`
+	DepName   string `json:"depName,omitempty"`
+	IsPrimary bool   `json:"isPrimary,omitempty"`
+	Position  string `json:"position,omitempty"`
+}
+
+type DepartmentMemberReq struct {
+	DepId     string `json:"depId,omitempty" form:"depId,omitempty"`
+	UserId    string `json:"userId,omitempty" form:"userId,omitempty"`
+	IsPrimary bool   `json:"isPrimary,omitempty"`
+	Position  string `json:"position,omitempty"`
+}
+
+type DepartmentUserListResp struct {
+	List []*DepartmentUser `json:"data"`
+}
+
+type OrgUser struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	JobTitle    string `json:"jobTitle,omitempty"`
+	Position    string `json:"position,omitempty"`
+	Avatar      string `json:"avatar,omitempty"`
+	ManagerId   string `json:"managerId,omitempty"`
+	ManagerName string `json:"managerName,omitempty"`
+	IsPrimary   bool   `json:"isPrimary,omitempty"`
+}
+
+type OrgChartNode struct {
+	Id       string          `json:"id"`
+	Name     string          `json:"name"`
+	ParentId string          `json:"parentId,omitempty"`
+	Level    int             `json:"level"`
+	Leader   *OrgUser        `json:"leader,omitempty"`
+	Members  []*OrgUser      `json:"members"`
+	Child    []*OrgChartNode `json:"child,omitempty"`
+}
+
+type OrgChartResp struct {
+	Child      []*OrgChartNode `json:"child"`
+	Unassigned []*OrgUser      `json:"unassigned,omitempty"`
+}
+
+type MoveDepartmentReq struct {
+	Id       string `json:"id"`
+	ParentId string `json:"parentId"`
 }
 
 type DepartmentListReq struct {
 	DepId  string   `json:"depId,omitempty"`
 	DepIds []string `json:"depIds,omitempty"`
+	Name   string   `json:"name,omitempty"`
 }
 
 type DepPathReq struct {
@@ -119,6 +514,8 @@ type Todo struct {
 	Records     []*TodoRecord `json:"records,omitempty"`
 	ExecuteIds  []string      `json:"executeIds,omitempty"`
 	TodoStatus  int           `json:"todoStatus,omitempty"`
+	ColumnId    string        `json:"columnId,omitempty"`
+	Sort        int           `json:"sort,omitempty"`
 }
 
 type UserTodo struct {
@@ -161,11 +558,53 @@ type TodoListResp struct {
 	List  []*Todo `json:"data"`
 }
 
+type TodoColumn struct {
+	Id         string `json:"id,omitempty"`
+	Name       string `json:"name,omitempty"`
+	DepId      string `json:"depId,omitempty"`
+	TodoStatus int    `json:"todoStatus,omitempty"`
+	Sort       int    `json:"sort,omitempty"`
+}
+
+type TodoColumnListReq struct {
+	DepId string `form:"depId,omitempty"`
+}
+
+type TodoColumnListResp struct {
+	List []*TodoColumn `json:"data"`
+}
+
+type TodoBoardReq struct {
+	DepId string `form:"depId,omitempty"`
+}
+
+type TodoBoardColumn struct {
+	Id         string  `json:"id"`
+	Name       string  `json:"name"`
+	TodoStatus int     `json:"todoStatus"`
+	Sort       int     `json:"sort"`
+	Count      int64   `json:"count"`
+	List       []*Todo `json:"data"`
+}
+
+type TodoBoardResp struct {
+	DepId   string             `json:"depId,omitempty"`
+	Columns []*TodoBoardColumn `json:"columns"`
+}
+
+type MoveTodoReq struct {
+	TodoId   string `json:"todoId"`
+	ColumnId string `json:"columnId"`
+	Sort     int    `json:"sort"`
+}
+
 type Approver struct {
 	UserId   string `json:"userId"`
 	UserName string `json:"userName"`
 	Status   int    `json:"status"`
-	Reason   string `json:"reason,omitempty"` //请假原由
+	Reason   string `json:"reason,omitempty"`   //请假原由
+	Presence string `json:"presence,omitempty"` // 在线状态
+	LastSeen int64  `json:"lastSeen,omitempty"`
 }
 
 type MakeCard struct {
@@ -191,29 +630,37 @@ type GoOut struct {
 	Reason    string  `json:"reason,omitempty" mapstructure:"omitempty"`    //请假原由
 }
 
+type Dimission struct {
+	LastDay int64  `json:"lastDay,omitempty" mapstructure:"lastDay,omitempty"` //最后工作日
+	Reason  string `json:"reason,omitempty" mapstructure:"reason,omitempty"`   //离职原因
+}
+
 type Approval struct {
-	Id          string    `json:"id,omitempty"`
-	UserId      string    `json:"userId,omitempty"`
-	No          string    `json:"no,omitempty"`
-	Type        int       `json:"type,omitempty"`
-	Status      int       `json:"status,omitempty"`
-	Title       string    `json:"title,omitempty"`
-	Abstract    string    `json:"abstract,omitempty"`
-	Reason      string    `json:"reason,omitempty"`
-	FinishAt    int64     `json:"finishAt,omitempty"`
-	FinishDay   int64     `json:"finishDay,omitempty"`
-	FinishMonth int64     `json:"finishMonth,omitempty"`
-	FinishYeas  int64     `json:"finishYeas,omitempty"`
-	MakeCard    *MakeCard `json:"makeCard,omitempty"`
-	Leave       *Leave    `json:"leave,omitempty"`
-	GoOut       *GoOut    `json:"goOut,omitempty"`
-	UpdateAt    int64     `json:"updateAt,omitempty"`
-	CreateAt    int64     `json:"createAt,omitempty"`
+	Id          string     `json:"id,omitempty"`
+	UserId      string     `json:"userId,omitempty"`
+	DepId       string     `json:"depId,omitempty"`
+	No          string     `json:"no,omitempty"`
+	Type        int        `json:"type,omitempty"`
+	Status      int        `json:"status,omitempty"`
+	Title       string     `json:"title,omitempty"`
+	Abstract    string     `json:"abstract,omitempty"`
+	Reason      string     `json:"reason,omitempty"`
+	FinishAt    int64      `json:"finishAt,omitempty"`
+	FinishDay   int64      `json:"finishDay,omitempty"`
+	FinishMonth int64      `json:"finishMonth,omitempty"`
+	FinishYeas  int64      `json:"finishYeas,omitempty"`
+	MakeCard    *MakeCard  `json:"makeCard,omitempty"`
+	Leave       *Leave     `json:"leave,omitempty"`
+	GoOut       *GoOut     `json:"goOut,omitempty"`
+	Dimission   *Dimission `json:"dimission,omitempty"`
+	UpdateAt    int64      `json:"updateAt,omitempty"`
+	CreateAt    int64      `json:"createAt,omitempty"`
 }
 
 type ApprovalInfoResp struct {
 	Id          string      `json:"id"`
 	User        *Approver   `json:"user"`
+	DepId       string      `json:"depId"`
 	No          string      `json:"no"`
 	Type        int         `json:"type"`
 	Status      int         `json:"status"`
@@ -230,6 +677,7 @@ type ApprovalInfoResp struct {
 	MakeCard    *MakeCard   `json:"makeCard"`
 	Leave       *Leave      `json:"leave"`
 	GoOut       *GoOut      `json:"goOut"`
+	Dimission   *Dimission  `json:"dimission"`
 	UpdateAt    int64       `json:"updateAt"`
 	CreateAt    int64       `json:"createAt"`
 }
@@ -256,6 +704,8 @@ type ApprovalList struct {
 	Abstract        string `json:"abstract"`
 	CreateId        string `json:"createId"`
 	ParticipatingId string `json:"participatingId"`
+	// Approver 当前的审批人及其在线状态，审批结束后为空
+	Approver *Approver `json:"approver,omitempty"`
 }
 
 type ApprovalListResp struct {
@@ -267,9 +717,11 @@ type ChatReq struct {
 	Prompts    string `json:"prompts,omitempty"`
 	ChatType   int    `json:"chatType,omitempty"`
 	RelationId int    `json:"relationId,omitempty"`
-	StartTime  int64  `json:"startTime,omitempty"`
-	EndTime    int64  `json:"endTime,omitempty"`
-	Voice      string `json:"voice,omitempty"`
+	// ConversationId 在会话中使用助手时的会话，助手可以读取该会话的聊天记录，对话的上下文在会话中共享
+	ConversationId string `json:"conversationId,omitempty"`
+	StartTime      int64  `json:"startTime,omitempty"`
+	EndTime        int64  `json:"endTime,omitempty"`
+	Voice          string `json:"voice,omitempty"`
 }
 
 type ChatResp struct {
@@ -277,6 +729,24 @@ type ChatResp struct {
 	Data     interface{} `json:"data"`
 }
 
+// 流式对话的事件类型
+const (
+	ChatStreamProgress = "progress" // 处理进度
+	ChatStreamChunk    = "chunk"    // 回复的部分文本
+	ChatStreamDone     = "done"     // 对话结束
+	ChatStreamError    = "error"    // 对话失败
+)
+
+// ChatStream 流式对话的事件，progress 的 step 为 route 时 name 为选择的处理器，为 tool 时 name 为调用的工具；
+// chunk 的 text 为回复的部分文本；最后以 done 结束，resp 为与非流式接口相同的结构化回复
+type ChatStream struct {
+	Type string    `json:"type"`
+	Step string    `json:"step,omitempty"`
+	Name string    `json:"name,omitempty"`
+	Text string    `json:"text,omitempty"`
+	Resp *ChatResp `json:"resp,omitempty"`
+}
+
 type FileResp struct {
 	Host     string `json:"host"`
 	File     string `json:"file"`
@@ -15,6 +15,8 @@ const (
 
 	ImgAndText // 图片+文本
 	File       //
+
+	UserFind // 人员查询
 )
 
 type ChatFile struct {
@@ -1,12 +1,156 @@
 package domain
 
+// 消息事件，为空时为聊天消息
+const (
+	// EventRead 已读，客户端读到会话中的消息 id 后发送，同步到用户的其他设备
+	EventRead = "read"
+	// EventDelivered 送达回执，消息送达接收者（recvId）的设备后发送给发送者
+	EventDelivered = "delivered"
+	// EventReadReceipt 已读回执，接收者（recvId）读到消息 id 后发送给该会话中消息的发送者
+	EventReadReceipt = "readReceipt"
+	// EventRecall 撤回消息 id，发送者在时限内可以撤回，通知会话的所有参与者
+	EventRecall = "recall"
+	// EventEdit 编辑消息 id 的文本内容，通知会话的所有参与者
+	EventEdit = "edit"
+	// EventDelete 删除消息 id，forEveryone 为true时由发送者为所有人删除，否则只为自己删除
+	EventDelete = "delete"
+	// EventPresence 在线状态，服务端推送订阅的用户（presence.userId）的状态变化；
+	// 客户端发送时设置自己的状态为 online 或 away
+	EventPresence = "presence"
+	// EventSubscribe 订阅 userIds 的在线状态，为空时订阅群聊 conversationId 的成员，都为空时订阅私聊过的联系人；
+	// 每次订阅替换该连接之前的订阅，订阅后立即推送各用户当前的状态
+	EventSubscribe = "subscribe"
+	// EventTyping 正在输入，转发给会话 conversationId 的其他参与者，不保存
+	EventTyping = "typing"
+	// EventAIChat 与AI对话，content 为提问，填写 conversationId 时在会话中使用；处理过程中推送 aiStream
+	EventAIChat = "aiChat"
+	// EventAIStream 流式对话的事件，reqId 为对应的 aiChat，stream 为处理进度、回复的部分文本或最终的回复
+	EventAIStream = "aiStream"
+	// EventAck 服务端处理完客户端带有 reqId 的消息后回复，聊天消息回填保存后的 id 和 sendTime
+	EventAck = "ack"
+	// EventError 服务端处理客户端的消息失败，reqId 为失败的消息，code 和 error 为失败原因
+	EventError = "error"
+)
+
+// 错误帧的错误码
+const (
+	WsErrBadFrame  = 400 // 消息格式错误或不支持的事件
+	WsErrForbidden = 403 // 没有操作权限
+	WsErrHandle    = 500 // 消息处理失败
+)
+
 type Message struct {
+	Event          string `json:"event,omitempty"`
+	ReqId          string `json:"reqId,omitempty"` // 客户端生成的消息编号，用于对应服务端的 ack 和 error
+	Id             string `json:"id,omitempty"`    // 消息ID，保存后由服务端生成
 	ConversationId string `json:"conversationId"`
 
 	RecvId string `json:"recvId"`
 	SendId string `json:"sendId"`
 
-	ChatType    int    `json:"chatType"`
-	Content     string `json:"content"`
-	ContentType int    `json:"contentType"`
+	ChatType    int                `json:"chatType"`
+	Content     string             `json:"content"`
+	ContentType int                `json:"contentType"`
+	Attachment  *MessageAttachment `json:"attachment,omitempty"`
+	Card        *MessageCard       `json:"card,omitempty"`
+	SendTime    int64              `json:"sendTime,omitempty"`
+
+	EditAt      int64          `json:"editAt,omitempty"`
+	History     []*MessageEdit `json:"history,omitempty"` // 编辑前的内容
+	RecallAt    int64          `json:"recallAt,omitempty"`
+	DeleteAt    int64          `json:"deleteAt,omitempty"`
+	ForEveryone bool           `json:"forEveryone,omitempty"`
+
+	Presence *Presence `json:"presence,omitempty"`
+	UserIds  []string  `json:"userIds,omitempty"`
+
+	Stream *ChatStream `json:"stream,omitempty"`
+
+	Code  int    `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Presence 用户的在线状态
+type Presence struct {
+	UserId   string `json:"userId,omitempty"`
+	Status   string `json:"status"`             // online 在线，away 离开，offline 离线
+	LastSeen int64  `json:"lastSeen,omitempty"` // 最后在线的时间
+}
+
+// MessageEdit 消息编辑前的内容
+type MessageEdit struct {
+	Content string `json:"content"`
+	EditAt  int64  `json:"editAt"`
+}
+
+// MessageAttachment 图片、文件和语音消息引用的上传文件
+type MessageAttachment struct {
+	File     string `json:"file"` // 上传接口返回的 file
+	Name     string `json:"name,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Duration int    `json:"duration,omitempty"` // 语音的时长，单位秒
+}
+
+// MessageCard 卡片消息，发送时只需要填写 type 和 id
+type MessageCard struct {
+	Type   string `json:"type"` // todo 待办，approval 审批
+	Id     string `json:"id"`
+	Title  string `json:"title,omitempty"`
+	Status string `json:"status,omitempty"`
+	Desc   string `json:"desc,omitempty"`
+}
+
+// WsSession 用户在一个设备上的websocket连接
+type WsSession struct {
+	UserId    string `json:"userId"`
+	DeviceId  string `json:"deviceId"`
+	Ip        string `json:"ip,omitempty"`
+	UserAgent string `json:"userAgent,omitempty"`
+	ConnectAt int64  `json:"connectAt"`
+}
+
+type WsSessionListReq struct {
+	UserId string `json:"userId,omitempty" form:"userId,omitempty"` // 为空时为当前用户
+}
+
+type WsSessionListResp struct {
+	List []*WsSession `json:"data"`
+}
+
+type WsSessionCloseReq struct {
+	DeviceId string `uri:"deviceId" form:"-"`
+	UserId   string `json:"userId,omitempty" form:"userId,omitempty"` // 为空时为当前用户
+}
+
+type ConversationListReq struct {
+	Page  int `json:"page,omitempty" form:"page,omitempty"`
+	Count int `json:"count,omitempty" form:"count,omitempty"`
+}
+
+// Conversation 当前用户的一个会话
+type Conversation struct {
+	Id          string   `json:"id"`
+	ChatType    int      `json:"chatType"`
+	PeerId      string   `json:"peerId"` // 私聊为对方的用户ID，群聊为群ID
+	PeerName    string   `json:"peerName,omitempty"`
+	Unread      int64    `json:"unread"`
+	LastMessage *Message `json:"lastMessage,omitempty"`
+	ReadMsgId   string   `json:"readMsgId,omitempty"`
+}
+
+type ConversationListResp struct {
+	Count int64           `json:"count"`
+	List  []*Conversation `json:"data"`
+}
+
+type ConversationMessagesReq struct {
+	Id     string `uri:"id" form:"-"`
+	Before string `json:"before,omitempty" form:"before,omitempty"` // 查询该消息之前的消息
+	After  string `json:"after,omitempty" form:"after,omitempty"`   // 查询该消息之后的消息
+	Count  int    `json:"count,omitempty" form:"count,omitempty"`
+}
+
+type ConversationMessagesResp struct {
+	List    []*Message `json:"data"`
+	HasMore bool       `json:"hasMore"` // 翻页方向上是否还有消息
 }
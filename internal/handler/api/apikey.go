@@ -0,0 +1,96 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"ai/internal/domain"
+	"ai/internal/logic"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/httpx"
+)
+
+type ApiKey struct {
+	svcCtx *svc.ServiceContext
+	apiKey logic.ApiKey
+}
+
+func NewApiKey(svcCtx *svc.ServiceContext, apiKey logic.ApiKey) *ApiKey {
+	return &ApiKey{
+		svcCtx: svcCtx,
+		apiKey: apiKey,
+	}
+}
+
+// InitRegister API密钥只能使用登录令牌管理，不能用API密钥创建新的密钥
+func (h *ApiKey) InitRegister(engine *gin.Engine) {
+	g := engine.Group("v1/apikey", h.svcCtx.Jwt.SessionHandler)
+	g.POST("", h.Create)
+	g.GET("/list", h.List)
+	g.GET("/all", h.svcCtx.Rbac.Permission(model.ResourceUser, model.ActionRead), h.All)
+	g.DELETE("/:id", h.Revoke)
+}
+
+// Create 创建API密钥
+func (h *ApiKey) Create(ctx *gin.Context) {
+	var req domain.ApiKeyCreateReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.apiKey.Create(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// List 当前用户的API密钥
+func (h *ApiKey) List(ctx *gin.Context) {
+	var req domain.ApiKeyListReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.apiKey.List(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// All 所有用户的API密钥
+func (h *ApiKey) All(ctx *gin.Context) {
+	var req domain.ApiKeyListReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.apiKey.All(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Revoke 撤销API密钥
+func (h *ApiKey) Revoke(ctx *gin.Context) {
+	var req domain.IdPathReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.apiKey.Revoke(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
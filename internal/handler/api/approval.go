@@ -5,6 +5,7 @@ import (
 
 	"ai/internal/domain"
 	"ai/internal/logic"
+	"ai/internal/model"
 	"ai/internal/svc"
 	"ai/pkg/httpx"
 )
@@ -22,11 +23,13 @@ func NewApproval(svcCtx *svc.ServiceContext, approval logic.Approval) *Approval
 }
 
 func (h *Approval) InitRegister(engine *gin.Engine) {
+	perm := h.svcCtx.Rbac.Permission
+
 	g := engine.Group("v1/approval", h.svcCtx.Jwt.Handler)
-	g.GET("/:id", h.Info)
-	g.POST("", h.Create)
-	g.PUT("/dispose", h.Dispose)
-	g.GET("/list", h.List)
+	g.GET("/:id", perm(model.ResourceApproval, model.ActionRead), h.Info)
+	g.POST("", perm(model.ResourceApproval, model.ActionCreate), h.Create)
+	g.PUT("/dispose", perm(model.ResourceApproval, model.ActionUpdate), h.Dispose)
+	g.GET("/list", perm(model.ResourceApproval, model.ActionRead), h.List)
 }
 
 func (h *Approval) Info(ctx *gin.Context) {
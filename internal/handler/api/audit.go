@@ -0,0 +1,44 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"ai/internal/domain"
+	"ai/internal/logic"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/httpx"
+)
+
+type Audit struct {
+	svcCtx *svc.ServiceContext
+	audit  logic.Audit
+}
+
+func NewAudit(svcCtx *svc.ServiceContext, audit logic.Audit) *Audit {
+	return &Audit{
+		svcCtx: svcCtx,
+		audit:  audit,
+	}
+}
+
+func (h *Audit) InitRegister(engine *gin.Engine) {
+	g := engine.Group("v1/audit", h.svcCtx.Jwt.Handler, h.svcCtx.Rbac.Permission(model.ResourceAudit, model.ActionRead))
+	g.GET("/list", h.List)
+}
+
+// List 审计日志列表
+func (h *Audit) List(ctx *gin.Context) {
+	var req domain.AuditListReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.audit.List(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"ai/internal/domain"
 	"ai/internal/logic"
+	"ai/internal/model"
 	"ai/internal/svc"
 	"ai/pkg/httpx"
 )
@@ -22,8 +23,9 @@ func NewChat(svcCtx *svc.ServiceContext, chat logic.Chat) *Chat {
 }
 
 func (h *Chat) InitRegister(engine *gin.Engine) {
-	g := engine.Group("v1/chat", h.svcCtx.Jwt.Handler)
+	g := engine.Group("v1/chat", h.svcCtx.Jwt.Handler, h.svcCtx.Rbac.Permission(model.ResourceChat, model.ActionCreate))
 	g.POST("", h.Chat)
+	g.POST("stream", h.Stream)
 }
 
 func (h *Chat) Chat(ctx *gin.Context) {
@@ -40,3 +42,23 @@ func (h *Chat) Chat(ctx *gin.Context) {
 		httpx.OkWithData(ctx, res)
 	}
 }
+
+// Stream 以SSE推送对话的处理进度和回复的部分文本，最后推送 done 事件，失败时推送 error 事件
+func (h *Chat) Stream(ctx *gin.Context) {
+	var req domain.ChatReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no") // 避免反向代理缓冲
+	err := h.chat.AIChatStream(ctx.Request.Context(), &req, func(ev *domain.ChatStream) {
+		ctx.SSEvent(ev.Type, ev)
+		ctx.Writer.Flush()
+	})
+	if err != nil {
+		ctx.SSEvent(domain.ChatStreamError, httpx.ErrorResult(ctx, err))
+		ctx.Writer.Flush()
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"ai/internal/domain"
+	"ai/internal/logic"
+	"ai/internal/svc"
+	"ai/pkg/httpx"
+)
+
+type Conversation struct {
+	svcCtx       *svc.ServiceContext
+	conversation logic.Conversation
+}
+
+func NewConversation(svcCtx *svc.ServiceContext, conversation logic.Conversation) *Conversation {
+	return &Conversation{
+		svcCtx:       svcCtx,
+		conversation: conversation,
+	}
+}
+
+func (h *Conversation) InitRegister(engine *gin.Engine) {
+	g := engine.Group("v1/conversations", h.svcCtx.Jwt.Handler)
+	g.GET("", h.List)
+	g.GET("/:id/messages", h.Messages)
+}
+
+// List 当前用户的会话列表
+func (h *Conversation) List(ctx *gin.Context) {
+	var req domain.ConversationListReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.conversation.List(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Messages 会话的历史消息
+func (h *Conversation) Messages(ctx *gin.Context) {
+	var req domain.ConversationMessagesReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.conversation.Messages(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
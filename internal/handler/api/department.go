@@ -5,6 +5,7 @@ import (
 
 	"ai/internal/domain"
 	"ai/internal/logic"
+	"ai/internal/model"
 	"ai/internal/svc"
 	"ai/pkg/httpx"
 )
@@ -22,14 +23,22 @@ func NewDepartment(svcCtx *svc.ServiceContext, department logic.Department) *Dep
 }
 
 func (h *Department) InitRegister(engine *gin.Engine) {
+	// 部门的修改只在路由上校验用户在某个部门范围内具有权限，具体部门的权限在业务逻辑中校验
+	perm, depPerm := h.svcCtx.Rbac.Permission, h.svcCtx.Rbac.DepPermission
+
 	g := engine.Group("v1/dep", h.svcCtx.Jwt.Handler)
-	g.GET("/soa", h.Soa)
-	g.GET("/:id", h.Info)
-	g.POST("", h.Create)
-	g.PUT("", h.Edit)
-	g.DELETE("/:id", h.Delete)
-	g.POST("/user", h.SetDepUsers)
-	g.GET("/user/:id", h.DepUserInfo)
+	g.GET("/soa", perm(model.ResourceDepartment, model.ActionRead), h.Soa)
+	g.GET("/org", perm(model.ResourceDepartment, model.ActionRead), h.OrgChart)
+	g.GET("/:id", perm(model.ResourceDepartment, model.ActionRead), h.Info)
+	g.POST("", depPerm(model.ResourceDepartment, model.ActionCreate), h.Create)
+	g.PUT("", depPerm(model.ResourceDepartment, model.ActionUpdate), h.Edit)
+	g.PUT("/move", depPerm(model.ResourceDepartment, model.ActionUpdate), h.Move)
+	g.DELETE("/:id", depPerm(model.ResourceDepartment, model.ActionDelete), h.Delete)
+	g.POST("/user", depPerm(model.ResourceDepartment, model.ActionUpdate), h.SetDepUsers)
+	g.GET("/user/:id", perm(model.ResourceDepartment, model.ActionRead), h.DepUserInfo)
+	g.POST("/member", depPerm(model.ResourceDepartment, model.ActionUpdate), h.SetMember)
+	g.DELETE("/member", depPerm(model.ResourceDepartment, model.ActionUpdate), h.RemoveMember)
+	g.GET("/member/:id", perm(model.ResourceDepartment, model.ActionRead), h.UserDeps)
 }
 
 func (h *Department) Soa(ctx *gin.Context) {
@@ -41,6 +50,16 @@ func (h *Department) Soa(ctx *gin.Context) {
 	}
 }
 
+// OrgChart 组织架构图
+func (h *Department) OrgChart(ctx *gin.Context) {
+	res, err := h.department.OrgChart(ctx.Request.Context())
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
 func (h *Department) Info(ctx *gin.Context) {
 	var req domain.IdPathReq
 	if err := httpx.BindAndValidate(ctx, &req); err != nil {
@@ -86,6 +105,22 @@ func (h *Department) Edit(ctx *gin.Context) {
 	}
 }
 
+// Move 调整部门的上级部门
+func (h *Department) Move(ctx *gin.Context) {
+	var req domain.MoveDepartmentReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.department.Move(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
 func (h *Department) Delete(ctx *gin.Context) {
 	var req domain.IdPathReq
 	if err := httpx.BindAndValidate(ctx, &req); err != nil {
@@ -131,3 +166,51 @@ func (h *Department) DepUserInfo(ctx *gin.Context) {
 		httpx.OkWithData(ctx, res)
 	}
 }
+
+// SetMember 添加或修改部门成员
+func (h *Department) SetMember(ctx *gin.Context) {
+	var req domain.DepartmentMemberReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.department.SetMember(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// RemoveMember 将用户移出部门
+func (h *Department) RemoveMember(ctx *gin.Context) {
+	var req domain.DepartmentMemberReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.department.RemoveMember(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// UserDeps 获取用户所属的所有部门
+func (h *Department) UserDeps(ctx *gin.Context) {
+	var req domain.IdPathReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.department.UserDeps(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
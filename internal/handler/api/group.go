@@ -0,0 +1,156 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"ai/internal/domain"
+	"ai/internal/logic"
+	"ai/internal/svc"
+	"ai/pkg/httpx"
+)
+
+type Group struct {
+	svcCtx *svc.ServiceContext
+	group  logic.Group
+}
+
+func NewGroup(svcCtx *svc.ServiceContext, group logic.Group) *Group {
+	return &Group{
+		svcCtx: svcCtx,
+		group:  group,
+	}
+}
+
+func (h *Group) InitRegister(engine *gin.Engine) {
+	g := engine.Group("v1/group", h.svcCtx.Jwt.Handler)
+	g.POST("", h.Create)
+	g.PUT("", h.Edit)
+	g.GET("/list", h.List)
+	g.POST("/member", h.AddMembers)
+	g.DELETE("/member", h.RemoveMembers)
+	g.PUT("/admin", h.SetAdmins)
+	g.GET("/:id", h.Info)
+	g.DELETE("/:id", h.Delete)
+}
+
+// Create 创建群聊
+func (h *Group) Create(ctx *gin.Context) {
+	var req domain.GroupCreateReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.group.Create(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Edit 修改群名称
+func (h *Group) Edit(ctx *gin.Context) {
+	var req domain.GroupEditReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.group.Edit(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// List 我的群聊
+func (h *Group) List(ctx *gin.Context) {
+	res, err := h.group.List(ctx.Request.Context())
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// AddMembers 添加群成员
+func (h *Group) AddMembers(ctx *gin.Context) {
+	var req domain.GroupMemberReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.group.AddMembers(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// RemoveMembers 移出群成员或退出群聊
+func (h *Group) RemoveMembers(ctx *gin.Context) {
+	var req domain.GroupMemberReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.group.RemoveMembers(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// SetAdmins 设置群管理员
+func (h *Group) SetAdmins(ctx *gin.Context) {
+	var req domain.GroupMemberReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.group.SetAdmins(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// Info 群聊详情
+func (h *Group) Info(ctx *gin.Context) {
+	var req domain.IdPathReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.group.Info(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Delete 解散群聊
+func (h *Group) Delete(ctx *gin.Context) {
+	var req domain.IdPathReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.group.Delete(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
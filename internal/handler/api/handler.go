@@ -2,9 +2,11 @@ package api
 
 import (
 	"ai/internal/handler"
+	"ai/internal/logic"
 	"ai/internal/middleware"
 	"ai/internal/svc"
 	"ai/pkg/httpx"
+	"context"
 	"time"
 
 	"gitee.com/dn-jinmin/tlog"
@@ -23,8 +25,9 @@ type Handler interface {
 // handle 实现了API服务的处理器结构体
 // 包含Gin引擎实例和服务监听地址
 type handle struct {
-	srv  *gin.Engine // Gin框架的引擎实例，用于路由管理和HTTP处理
-	addr string      // 服务监听的地址，格式为"IP:端口"
+	srv  *gin.Engine         // Gin框架的引擎实例，用于路由管理和HTTP处理
+	addr string              // 服务监听的地址，格式为"IP:端口"
+	svc  *svc.ServiceContext // 服务上下文，用于启动后台定时任务
 }
 
 // NewHandle 创建一个新的API处理器实例
@@ -34,6 +37,7 @@ func NewHandle(svc *svc.ServiceContext) *handle {
 	h := &handle{
 		srv:  gin.Default(),
 		addr: "0.0.0.0:8080",
+		svc:  svc,
 	}
 
 	// 如果配置中指定了服务地址，则使用配置中的地址
@@ -49,6 +53,7 @@ func NewHandle(svc *svc.ServiceContext) *handle {
 	)
 
 	h.srv.Use(middleware.NewLog().Handler)
+	h.srv.Use(middleware.NewAudit(svc.AuditBegin, svc.AuditWrite).Handler)
 	h.srv.Use(cors.New(cors.Config{
 		AllowOrigins: []string{
 			"http://localhost:63342",
@@ -76,9 +81,17 @@ func NewHandle(svc *svc.ServiceContext) *handle {
 	return h
 }
 
-// Run 启动HTTP服务，开始监听并处理请求
+// Run 启动后台定时任务和HTTP服务，开始监听并处理请求，服务退出时结束定时任务
 // 返回值：启动服务过程中可能出现的错误
 func (h *handle) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 定时执行到期的离职交接
+	go logic.NewOffboarding(h.svc).Run(ctx)
+	// 定时清理过期的审计日志
+	go logic.NewAudit(h.svc).Run(ctx)
+
 	// 调用Gin引擎的Run方法，在指定地址启动服务
 	return h.srv.Run(h.addr)
 }
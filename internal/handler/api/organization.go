@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"ai/internal/domain"
+	"ai/internal/logic"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/httpx"
+)
+
+type Organization struct {
+	svcCtx       *svc.ServiceContext
+	organization logic.Organization
+}
+
+func NewOrganization(svcCtx *svc.ServiceContext, organization logic.Organization) *Organization {
+	return &Organization{
+		svcCtx:       svcCtx,
+		organization: organization,
+	}
+}
+
+func (h *Organization) InitRegister(engine *gin.Engine) {
+	perm := h.svcCtx.Rbac.Permission
+
+	g := engine.Group("v1/org", h.svcCtx.Jwt.Handler)
+	g.POST("/import", perm(model.ResourceUser, model.ActionCreate), perm(model.ResourceDepartment, model.ActionCreate),
+		h.Import)
+	g.GET("/export", perm(model.ResourceUser, model.ActionRead), perm(model.ResourceDepartment, model.ActionRead),
+		h.Export)
+}
+
+// Import 从xlsx或csv文件导入用户和部门
+func (h *Organization) Import(ctx *gin.Context) {
+	var req domain.ImportReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	file, header, err := ctx.Request.FormFile("file")
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+	defer file.Close()
+
+	if req.Data, err = io.ReadAll(file); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+	req.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+
+	res, err := h.organization.Import(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Export 导出用户和部门，格式与导入一致
+func (h *Organization) Export(ctx *gin.Context) {
+	var req domain.ExportReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.organization.Export(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", res.Filename))
+	ctx.Data(http.StatusOK, res.ContentType, res.Data)
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"ai/internal/domain"
+	"ai/internal/logic"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/httpx"
+)
+
+type Role struct {
+	svcCtx *svc.ServiceContext
+	role   logic.Role
+}
+
+func NewRole(svcCtx *svc.ServiceContext, role logic.Role) *Role {
+	return &Role{
+		svcCtx: svcCtx,
+		role:   role,
+	}
+}
+
+func (h *Role) InitRegister(engine *gin.Engine) {
+	perm := h.svcCtx.Rbac.Permission
+
+	g := engine.Group("v1/role", h.svcCtx.Jwt.Handler)
+	g.GET("/list", perm(model.ResourceRole, model.ActionRead), h.List)
+	g.POST("", perm(model.ResourceRole, model.ActionCreate), h.Create)
+	g.PUT("", perm(model.ResourceRole, model.ActionUpdate), h.Edit)
+	g.DELETE("/:id", perm(model.ResourceRole, model.ActionDelete), h.Delete)
+	g.PUT("/2fa", perm(model.ResourceRole, model.ActionUpdate), h.SetTwoFactor)
+	g.GET("/user/:id", perm(model.ResourceRole, model.ActionRead), h.UserRoles)
+	g.POST("/user", perm(model.ResourceRole, model.ActionUpdate), h.Assign)
+	g.DELETE("/user/:id", perm(model.ResourceRole, model.ActionUpdate), h.Revoke)
+}
+
+// SetTwoFactor 设置角色是否要求二次验证
+func (h *Role) SetTwoFactor(ctx *gin.Context) {
+	var req domain.RoleTwoFactorReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.role.SetTwoFactor(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// List 角色列表
+func (h *Role) List(ctx *gin.Context) {
+	res, err := h.role.List(ctx.Request.Context())
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Create 创建角色
+func (h *Role) Create(ctx *gin.Context) {
+	var req domain.Role
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.role.Create(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Edit 修改角色
+func (h *Role) Edit(ctx *gin.Context) {
+	var req domain.Role
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.role.Edit(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// Delete 删除角色
+func (h *Role) Delete(ctx *gin.Context) {
+	var req domain.IdPathReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.role.Delete(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// UserRoles 获取用户的角色
+func (h *Role) UserRoles(ctx *gin.Context) {
+	var req domain.IdPathReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.role.UserRoles(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Assign 为用户分配角色
+func (h *Role) Assign(ctx *gin.Context) {
+	var req domain.UserRole
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.role.Assign(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Revoke 撤销用户的角色
+func (h *Role) Revoke(ctx *gin.Context) {
+	var req domain.IdPathReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.role.Revoke(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
@@ -10,27 +10,57 @@ func initHandler(svc *svc.ServiceContext) []Handler {
 	var (
 		departmentLogic = logic.NewDepartment(svc)
 		todoLogic       = logic.NewTodo(svc)
+		todoBoardLogic  = logic.NewTodoBoard(svc)
 		approvalLogic   = logic.NewApproval(svc)
 		chatLogic       = logic.NewChat(svc)
 		userLogic       = logic.NewUser(svc)
+		roleLogic       = logic.NewRole(svc)
+		orgLogic        = logic.NewOrganization(svc)
+		twoFactorLogic  = logic.NewTwoFactor(svc)
+		ssoLogic        = logic.NewSso(svc)
+		apiKeyLogic     = logic.NewApiKey(svc)
+		tenantLogic     = logic.NewTenant(svc)
+		auditLogic      = logic.NewAudit(svc)
+		groupLogic      = logic.NewGroup(svc)
+		convLogic       = logic.NewConversation(svc)
 	)
 
 	// new handlers
 	var (
 		todo       = NewTodo(svc, todoLogic)
+		todoBoard  = NewTodoBoard(svc, todoBoardLogic)
 		approval   = NewApproval(svc, approvalLogic)
 		chat       = NewChat(svc, chatLogic)
 		upload     = NewUpload(svc, chatLogic)
 		user       = NewUser(svc, userLogic)
 		department = NewDepartment(svc, departmentLogic)
+		role       = NewRole(svc, roleLogic)
+		org        = NewOrganization(svc, orgLogic)
+		twoFactor  = NewTwoFactor(svc, twoFactorLogic)
+		sso        = NewSso(svc, ssoLogic)
+		apiKey     = NewApiKey(svc, apiKeyLogic)
+		tenant     = NewTenant(svc, tenantLogic)
+		audit      = NewAudit(svc, auditLogic)
+		group      = NewGroup(svc, groupLogic)
+		conv       = NewConversation(svc, convLogic)
 	)
 
 	return []Handler{
 		todo,
+		todoBoard,
 		approval,
 		chat,
 		upload,
 		user,
 		department,
+		role,
+		org,
+		twoFactor,
+		sso,
+		apiKey,
+		tenant,
+		audit,
+		group,
+		conv,
 	}
 }
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"ai/internal/domain"
+	"ai/internal/logic"
+	"ai/internal/svc"
+	"ai/pkg/httpx"
+)
+
+// ssoCookie 发起单点登录的浏览器绑定值，回调时校验，与state的有效期一致
+const (
+	ssoCookie       = "sso_state"
+	ssoCookiePath   = "/v1/sso"
+	ssoCookieMaxAge = 10 * 60
+)
+
+type Sso struct {
+	svcCtx *svc.ServiceContext
+	sso    logic.Sso
+}
+
+func NewSso(svcCtx *svc.ServiceContext, sso logic.Sso) *Sso {
+	return &Sso{
+		svcCtx: svcCtx,
+		sso:    sso,
+	}
+}
+
+func (h *Sso) InitRegister(engine *gin.Engine) {
+	g := engine.Group("v1/sso")
+	g.GET("/authorize", h.Authorize)
+	g.POST("/callback", h.Callback)
+}
+
+// Authorize 获取身份提供方的登录地址
+func (h *Sso) Authorize(ctx *gin.Context) {
+	var req domain.SsoAuthorizeReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.sso.Authorize(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		setSsoCookie(ctx, res.Binding, ssoCookieMaxAge)
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Callback 使用身份提供方返回的授权码完成登录
+func (h *Sso) Callback(ctx *gin.Context) {
+	var req domain.SsoCallbackReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+	req.Ip = ctx.ClientIP()
+	req.UserAgent = ctx.Request.UserAgent()
+	req.Binding, _ = ctx.Cookie(ssoCookie)
+	// state只能使用一次，无论成功与否都清除Cookie
+	setSsoCookie(ctx, "", -1)
+
+	res, err := h.sso.Callback(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// setSsoCookie 写入HttpOnly的绑定值Cookie，maxAge小于0时清除
+func setSsoCookie(ctx *gin.Context, value string, maxAge int) {
+	secure := ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https"
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(ssoCookie, value, maxAge, ssoCookiePath, "", secure, true)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"ai/internal/domain"
+	"ai/internal/logic"
+	"ai/internal/svc"
+	"ai/pkg/httpx"
+)
+
+type Tenant struct {
+	svcCtx *svc.ServiceContext
+	tenant logic.Tenant
+}
+
+func NewTenant(svcCtx *svc.ServiceContext, tenant logic.Tenant) *Tenant {
+	return &Tenant{
+		svcCtx: svcCtx,
+		tenant: tenant,
+	}
+}
+
+func (h *Tenant) InitRegister(engine *gin.Engine) {
+	g := engine.Group("v1/tenant", h.svcCtx.Jwt.Handler)
+	g.POST("", h.Create)
+	g.PUT("", h.Edit)
+	g.GET("/list", h.List)
+	g.GET("/config", h.Config)
+	g.PUT("/config", h.EditConfig)
+	g.GET("/:id", h.Info)
+}
+
+// Create 开通租户
+func (h *Tenant) Create(ctx *gin.Context) {
+	var req domain.TenantCreateReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.tenant.Create(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Edit 修改租户名称及状态
+func (h *Tenant) Edit(ctx *gin.Context) {
+	var req domain.TenantEditReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.tenant.Edit(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// Info 租户详情
+func (h *Tenant) Info(ctx *gin.Context) {
+	var req domain.IdPathReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.tenant.Info(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// List 租户列表
+func (h *Tenant) List(ctx *gin.Context) {
+	var req domain.TenantListReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.tenant.List(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Config 当前租户的配置
+func (h *Tenant) Config(ctx *gin.Context) {
+	res, err := h.tenant.Config(ctx.Request.Context())
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// EditConfig 修改当前租户的配置
+func (h *Tenant) EditConfig(ctx *gin.Context) {
+	var req domain.TenantConfig
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.tenant.EditConfig(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
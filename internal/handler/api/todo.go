@@ -5,6 +5,7 @@ import (
 
 	"ai/internal/domain"
 	"ai/internal/logic"
+	"ai/internal/model"
 	"ai/internal/svc"
 	"ai/pkg/httpx"
 )
@@ -22,14 +23,16 @@ func NewTodo(svcCtx *svc.ServiceContext, todo logic.Todo) *Todo {
 }
 
 func (h *Todo) InitRegister(engine *gin.Engine) {
+	perm := h.svcCtx.Rbac.Permission
+
 	g := engine.Group("v1/todo", h.svcCtx.Jwt.Handler)
-	g.GET("/:id", h.Info)
-	g.POST("", h.Create)
-	g.PUT("", h.Edit)
-	g.DELETE("/:id", h.Delete)
-	g.POST("/finish", h.Finish)
-	g.POST("/record", h.CreateRecord)
-	g.GET("/list", h.List)
+	g.GET("/:id", perm(model.ResourceTodo, model.ActionRead), h.Info)
+	g.POST("", perm(model.ResourceTodo, model.ActionCreate), h.Create)
+	g.PUT("", perm(model.ResourceTodo, model.ActionUpdate), h.Edit)
+	g.DELETE("/:id", perm(model.ResourceTodo, model.ActionDelete), h.Delete)
+	g.POST("/finish", perm(model.ResourceTodo, model.ActionUpdate), h.Finish)
+	g.POST("/record", perm(model.ResourceTodo, model.ActionUpdate), h.CreateRecord)
+	g.GET("/list", perm(model.ResourceTodo, model.ActionRead), h.List)
 }
 
 func (h *Todo) Info(ctx *gin.Context) {
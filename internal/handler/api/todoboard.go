@@ -0,0 +1,128 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"ai/internal/domain"
+	"ai/internal/logic"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/httpx"
+)
+
+type TodoBoard struct {
+	svcCtx    *svc.ServiceContext
+	todoBoard logic.TodoBoard
+}
+
+func NewTodoBoard(svcCtx *svc.ServiceContext, todoBoard logic.TodoBoard) *TodoBoard {
+	return &TodoBoard{
+		svcCtx:    svcCtx,
+		todoBoard: todoBoard,
+	}
+}
+
+func (h *TodoBoard) InitRegister(engine *gin.Engine) {
+	perm := h.svcCtx.Rbac.Permission
+
+	g := engine.Group("v1/todo/board", h.svcCtx.Jwt.Handler)
+	g.GET("", perm(model.ResourceTodo, model.ActionRead), h.Board)
+	g.POST("/move", perm(model.ResourceTodo, model.ActionUpdate), h.Move)
+	g.GET("/column", perm(model.ResourceTodo, model.ActionRead), h.ColumnList)
+	g.POST("/column", perm(model.ResourceTodo, model.ActionUpdate), h.CreateColumn)
+	g.PUT("/column", perm(model.ResourceTodo, model.ActionUpdate), h.EditColumn)
+	g.DELETE("/column/:id", perm(model.ResourceTodo, model.ActionUpdate), h.DeleteColumn)
+}
+
+// Board 待办看板
+func (h *TodoBoard) Board(ctx *gin.Context) {
+	var req domain.TodoBoardReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.todoBoard.Board(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Move 移动待办到看板的列
+func (h *TodoBoard) Move(ctx *gin.Context) {
+	var req domain.MoveTodoReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.todoBoard.Move(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// ColumnList 看板列列表
+func (h *TodoBoard) ColumnList(ctx *gin.Context) {
+	var req domain.TodoColumnListReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.todoBoard.ColumnList(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+func (h *TodoBoard) CreateColumn(ctx *gin.Context) {
+	var req domain.TodoColumn
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.todoBoard.CreateColumn(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+func (h *TodoBoard) EditColumn(ctx *gin.Context) {
+	var req domain.TodoColumn
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.todoBoard.EditColumn(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+func (h *TodoBoard) DeleteColumn(ctx *gin.Context) {
+	var req domain.IdPathReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.todoBoard.DeleteColumn(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"ai/internal/domain"
+	"ai/internal/logic"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/httpx"
+)
+
+type TwoFactor struct {
+	svcCtx    *svc.ServiceContext
+	twoFactor logic.TwoFactor
+}
+
+func NewTwoFactor(svcCtx *svc.ServiceContext, twoFactor logic.TwoFactor) *TwoFactor {
+	return &TwoFactor{
+		svcCtx:    svcCtx,
+		twoFactor: twoFactor,
+	}
+}
+
+func (h *TwoFactor) InitRegister(engine *gin.Engine) {
+	// 角色要求二次验证的用户登录后，在完成二次验证之前只能开启二次验证
+	enroll := engine.Group("v1/user/2fa", h.svcCtx.Jwt.EnrollHandler)
+	enroll.POST("/enroll", h.Enroll)
+	enroll.POST("/activate", h.Activate)
+
+	g := engine.Group("v1/user/2fa", h.svcCtx.Jwt.SessionHandler)
+	g.POST("/disable", h.Disable)
+	g.POST("/recovery", h.RecoveryCodes)
+	g.POST("/reset/:id", h.svcCtx.Rbac.Permission(model.ResourceUser, model.ActionUpdate), h.Reset)
+}
+
+// Enroll 获取二次验证密钥及二维码地址
+func (h *TwoFactor) Enroll(ctx *gin.Context) {
+	res, err := h.twoFactor.Enroll(ctx.Request.Context())
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Activate 校验验证码并开启二次验证
+func (h *TwoFactor) Activate(ctx *gin.Context) {
+	var req domain.TwoFactorCodeReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.twoFactor.Activate(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Disable 关闭二次验证
+func (h *TwoFactor) Disable(ctx *gin.Context) {
+	var req domain.TwoFactorCodeReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.twoFactor.Disable(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
+// RecoveryCodes 重新生成恢复码
+func (h *TwoFactor) RecoveryCodes(ctx *gin.Context) {
+	var req domain.TwoFactorCodeReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.twoFactor.RecoveryCodes(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+// Reset 重置用户的二次验证
+func (h *TwoFactor) Reset(ctx *gin.Context) {
+	var req domain.IdPathReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	err := h.twoFactor.Reset(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
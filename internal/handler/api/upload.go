@@ -12,6 +12,7 @@ import (
 	"github.com/segmentio/ksuid"
 
 	"ai/internal/logic"
+	"ai/internal/model"
 	"ai/internal/svc"
 	"ai/pkg/httpx"
 )
@@ -29,7 +30,7 @@ func NewUpload(svcCtx *svc.ServiceContext, chat logic.Chat) *Upload {
 }
 
 func (h *Upload) InitRegister(engine *gin.Engine) {
-	g := engine.Group("v1/upload", h.svcCtx.Jwt.Handler)
+	g := engine.Group("v1/upload", h.svcCtx.Jwt.Handler, h.svcCtx.Rbac.Permission(model.ResourceChat, model.ActionCreate))
 	g.POST("/file", h.File)
 	g.POST("/multiplefiles", h.Multiplefiles)
 }
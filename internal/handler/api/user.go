@@ -3,6 +3,7 @@ package api
 import (
 	"ai/internal/domain"
 	"ai/internal/logic"
+	"ai/internal/model"
 	"ai/internal/svc"
 	"ai/pkg/httpx"
 
@@ -24,14 +25,25 @@ func NewUser(svcCtx *svc.ServiceContext, user logic.User) *User {
 func (h *User) InitRegister(engine *gin.Engine) {
 	g0 := engine.Group("v1/user")
 	g0.POST("/login", h.Login)
+	g0.POST("/login/password", h.LoginPassword)
+	g0.POST("/login/2fa", h.LoginTwoFactor)
+	g0.POST("/refresh", h.Refresh)
+
+	perm := h.svcCtx.Rbac.Permission
 
 	g1 := engine.Group("/v1/user", h.svcCtx.Jwt.Handler)
-	g1.GET("/:id", h.Info)
-	g1.POST("", h.Create)
-	g1.PUT("", h.Edit)
-	g1.DELETE("/:id", h.Delete)
-	g1.GET("/list", h.List)
-	g1.POST("/password", h.UpPassword)
+	g1.GET("/:id", perm(model.ResourceUser, model.ActionRead), h.Info)
+	g1.POST("", perm(model.ResourceUser, model.ActionCreate), h.Create)
+	g1.PUT("", perm(model.ResourceUser, model.ActionUpdate), h.Edit)
+	g1.DELETE("/:id", perm(model.ResourceUser, model.ActionDelete), h.Delete)
+	g1.GET("/list", perm(model.ResourceUser, model.ActionRead), h.List)
+	g1.GET("/login/events", perm(model.ResourceUser, model.ActionRead), h.LoginEvents)
+
+	// 修改密码和退出登录只能使用登录令牌，未完成二次验证时也可以退出登录
+	g2 := engine.Group("/v1/user", h.svcCtx.Jwt.SessionHandler)
+	g2.POST("/password", h.UpPassword)
+	g3 := engine.Group("/v1/user", h.svcCtx.Jwt.EnrollHandler)
+	g3.POST("/logout", h.Logout)
 }
 
 func (h *User) Login(ctx *gin.Context) {
@@ -40,6 +52,8 @@ func (h *User) Login(ctx *gin.Context) {
 		httpx.FailWithErr(ctx, err)
 		return
 	}
+	req.Ip = ctx.ClientIP()
+	req.UserAgent = ctx.Request.UserAgent()
 
 	res, err := h.user.Login(ctx.Request.Context(), &req)
 	if err != nil {
@@ -49,6 +63,79 @@ func (h *User) Login(ctx *gin.Context) {
 	}
 }
 
+func (h *User) LoginPassword(ctx *gin.Context) {
+	var req domain.LoginPasswordReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+	req.Ip = ctx.ClientIP()
+	req.UserAgent = ctx.Request.UserAgent()
+
+	res, err := h.user.LoginPassword(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+func (h *User) LoginTwoFactor(ctx *gin.Context) {
+	var req domain.TwoFactorLoginReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+	req.Ip = ctx.ClientIP()
+	req.UserAgent = ctx.Request.UserAgent()
+
+	res, err := h.user.LoginTwoFactor(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+func (h *User) LoginEvents(ctx *gin.Context) {
+	var req domain.LoginEventListReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.user.LoginEvents(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+func (h *User) Refresh(ctx *gin.Context) {
+	var req domain.RefreshReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	res, err := h.user.Refresh(ctx.Request.Context(), &req)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.OkWithData(ctx, res)
+	}
+}
+
+func (h *User) Logout(ctx *gin.Context) {
+	err := h.user.Logout(ctx.Request.Context())
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+	} else {
+		httpx.Ok(ctx)
+	}
+}
+
 func (h *User) Info(ctx *gin.Context) {
 	var req domain.IdPathReq
 	if err := httpx.BindAndValidate(ctx, &req); err != nil {
@@ -0,0 +1,54 @@
+package ws
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"context"
+	"fmt"
+
+	"gitee.com/dn-jinmin/tlog"
+)
+
+// aiChat 流式对话，对话的时间较长，在单独的协程中向该连接推送 aiStream，不阻塞连接的读循环。
+// 回复的 ack 只表示已开始处理，失败时推送 error；连接关闭后停止对话
+func (s *Ws) aiChat(ctx context.Context, c *conn, req *domain.Message) error {
+	if err := s.svc.Authorize(ctx, model.ResourceChat, model.ActionCreate, ""); err != nil {
+		return err
+	}
+
+	msg := *req
+	go func() {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		err := s.aiStream(ctx, c, &msg, cancel)
+		if err != nil {
+			tlog.ErrorfCtx(ctx, "aiChat", "fail %v, reqId %v", err.Error(), msg.ReqId)
+			s.replyError(ctx, c, &msg, domain.WsErrHandle, err)
+		}
+	}()
+	return nil
+}
+
+// aiStream 将对话的事件推送给连接，推送失败时取消对话
+func (s *Ws) aiStream(ctx context.Context, c *conn, req *domain.Message, cancel context.CancelFunc) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%v", e)
+		}
+	}()
+
+	return s.chat.AIChatStream(ctx, &domain.ChatReq{
+		Prompts:        req.Content,
+		ConversationId: req.ConversationId,
+	}, func(ev *domain.ChatStream) {
+		if err := s.reply(ctx, c, &domain.Message{
+			Event:          domain.EventAIStream,
+			ReqId:          req.ReqId,
+			ConversationId: req.ConversationId,
+			Stream:         ev,
+		}); err != nil {
+			cancel()
+		}
+	})
+}
@@ -0,0 +1,297 @@
+package ws
+
+import (
+	"ai/internal/domain"
+	"ai/internal/middleware"
+	"ai/internal/model"
+	"ai/pkg/encrypt"
+	"ai/token"
+	"context"
+	"encoding/json"
+	"time"
+
+	"gitee.com/dn-jinmin/tlog"
+)
+
+const (
+	// keepAliveInterval 实例刷新存活标记的间隔，超过3个间隔未刷新的实例视为已退出
+	keepAliveInterval = 10 * time.Second
+	// sessionsTimeout 查询其他实例上的连接时等待回复的时长，超时未回复的实例不计入
+	sessionsTimeout = 2 * time.Second
+	// clusterQueue 等待处理的其他实例转发的消息数，队列已满时丢弃并记录日志，未送达的消息在用户重新连接时补发
+	clusterQueue = 4096
+
+	broadcastChannel  = "ws:broadcast"
+	nodeChannelPrefix = "ws:deliver:"
+)
+
+// 实例之间转发的消息类型
+const (
+	frameDeliver       = "deliver"       // 推送给本实例上的用户，uids 为空时推送给本实例上同一租户的所有用户
+	framePresence      = "presence"      // 用户的在线状态变化，推送给本实例上的订阅者
+	frameClose         = "close"         // 同一设备在其他实例上重新连接，关闭本实例上的连接
+	frameSessions      = "sessions"      // 查询本实例上用户的连接，回复给发起查询的实例
+	frameSessionsReply = "sessionsReply" // 查询连接的回复
+)
+
+// clusterFrame 实例之间通过消息总线转发的消息
+type clusterFrame struct {
+	Type     string          `json:"type"`
+	From     string          `json:"from"` // 发布消息的实例
+	Tid      string          `json:"tid"`
+	Uids     []string        `json:"uids,omitempty"`
+	DeviceId string          `json:"deviceId,omitempty"`
+	ReqId    string          `json:"reqId,omitempty"` // 查询连接的请求ID，回复中原样带回
+	Msg      json.RawMessage `json:"msg,omitempty"`
+	// Record 为true时，接收的实例在消息进入发送队列后记录送达，Receipt 为true时同时给发送者发送送达回执
+	Record  bool `json:"record,omitempty"`
+	Receipt bool `json:"receipt,omitempty"`
+}
+
+func nodeChannel(node string) string {
+	return nodeChannelPrefix + node
+}
+
+// runCluster 定时刷新实例的存活标记，并处理其他实例转发的消息，订阅断开后重新订阅
+func (s *Ws) runCluster() {
+	ctx := context.Background()
+
+	go func() {
+		ticker := time.NewTicker(keepAliveInterval)
+		defer ticker.Stop()
+		for {
+			if err := s.bus.KeepAlive(ctx, s.node, 3*keepAliveInterval); err != nil {
+				tlog.Errorf("runCluster", "keep alive fail %v, node %v", err.Error(), s.node)
+			}
+			<-ticker.C
+		}
+	}()
+
+	go s.process()
+
+	for {
+		err := s.bus.Subscribe(ctx, s.receive, nodeChannel(s.node), broadcastChannel)
+		tlog.Errorf("runCluster", "subscribe fail %v, node %v", err, s.node)
+		time.Sleep(time.Second)
+	}
+}
+
+// receive 接收其他实例转发的消息，放入队列由 process 处理。
+// 回调在消息总线的连接上执行，不能访问数据库或消息总线，否则会阻塞该连接上的所有命令
+func (s *Ws) receive(channel string, payload []byte) {
+	f := new(clusterFrame)
+	if err := json.Unmarshal(payload, f); err != nil {
+		tlog.Errorf("cluster.receive", "json.Unmarshal fail %v, channel %v", err.Error(), channel)
+		return
+	}
+	if f.From == s.node {
+		return
+	}
+	// 查询连接的回复只转交给等待的请求，不排在其他消息之后
+	if f.Type == frameSessionsReply {
+		s.sessionsReply(f)
+		return
+	}
+
+	select {
+	case s.frames <- f:
+	default:
+		tlog.Errorf("cluster.receive", "queue full, drop %v frame from %v", f.Type, f.From)
+	}
+}
+
+// process 依次处理队列中其他实例转发的消息
+func (s *Ws) process() {
+	for f := range s.frames {
+		s.handleFrame(f)
+	}
+}
+
+// handleFrame 处理其他实例转发的一条消息
+func (s *Ws) handleFrame(f *clusterFrame) {
+	ctx := middleware.TraceStart(token.WithTenantId(context.Background(), f.Tid))
+	defer func() {
+		if e := recover(); e != nil {
+			tlog.ErrorfCtx(ctx, "cluster.handleFrame", "panic %v, type %v", e, f.Type)
+		}
+	}()
+
+	switch f.Type {
+	case frameDeliver:
+		delivered := s.deliverLocal(ctx, f.Tid, f.Msg, f.Uids...)
+		if !f.Record {
+			return
+		}
+		var msg domain.Message
+		if err := json.Unmarshal(f.Msg, &msg); err != nil {
+			tlog.ErrorfCtx(ctx, "cluster.handleFrame", "deliver fail %v", err.Error())
+			return
+		}
+		if err := s.delivered(ctx, &msg, f.Receipt, delivered); err != nil {
+			tlog.ErrorfCtx(ctx, "cluster.handleFrame", "delivered fail %v, msg %v", err.Error(), msg.Id)
+		}
+	case framePresence:
+		var p domain.Presence
+		if err := json.Unmarshal(f.Msg, &p); err != nil {
+			tlog.ErrorfCtx(ctx, "cluster.handleFrame", "presence fail %v", err.Error())
+			return
+		}
+		s.publishLocal(ctx, &p)
+	case frameClose:
+		for _, uid := range f.Uids {
+			s.RWMutex.RLock()
+			c, ok := s.uidToConns[uid][f.DeviceId]
+			s.RWMutex.RUnlock()
+			if ok && c.tid == f.Tid {
+				c.Close()
+			}
+		}
+	case frameSessions:
+		var list []*domain.WsSession
+		for _, uid := range f.Uids {
+			list = append(list, s.localSessions(f.Tid, uid)...)
+		}
+		b, err := json.Marshal(list)
+		if err != nil {
+			tlog.ErrorfCtx(ctx, "cluster.handleFrame", "sessions fail %v", err.Error())
+			return
+		}
+		s.forward(ctx, f.From, &clusterFrame{Type: frameSessionsReply, Tid: f.Tid, ReqId: f.ReqId, Msg: b})
+	}
+}
+
+// sessionsReply 将其他实例对连接查询的回复转交给等待的请求
+func (s *Ws) sessionsReply(f *clusterFrame) {
+	var list []*domain.WsSession
+	if err := json.Unmarshal(f.Msg, &list); err != nil {
+		tlog.Errorf("cluster.sessionsReply", "json.Unmarshal fail %v, from %v", err.Error(), f.From)
+		return
+	}
+	s.pendingMu.Lock()
+	ch, ok := s.pending[f.ReqId]
+	s.pendingMu.Unlock()
+	if ok {
+		// 缓冲与查询的实例数相同，不会阻塞
+		select {
+		case ch <- list:
+		default:
+		}
+	}
+}
+
+// forward 向其他实例发布消息，node 为空时发布给所有实例
+func (s *Ws) forward(ctx context.Context, node string, f *clusterFrame) error {
+	f.From = s.node
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	channel := broadcastChannel
+	if len(node) > 0 {
+		channel = nodeChannel(node)
+	}
+	if err = s.bus.Publish(ctx, channel, b); err != nil {
+		tlog.ErrorfCtx(ctx, "cluster.forward", "publish fail %v, channel %v", err.Error(), channel)
+	}
+	return err
+}
+
+// remoteSessions 查询用户在其他实例上的连接，等待各实例回复或超时
+func (s *Ws) remoteSessions(ctx context.Context, uid string) []*domain.WsSession {
+	nodes := s.remoteNodes(ctx, uid)
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	reqId, err := encrypt.RandomToken(8)
+	if err != nil {
+		tlog.ErrorfCtx(ctx, "cluster.remoteSessions", "req id fail %v", err.Error())
+		return nil
+	}
+	ch := make(chan []*domain.WsSession, len(nodes))
+	s.pendingMu.Lock()
+	s.pending[reqId] = ch
+	s.pendingMu.Unlock()
+	defer func() {
+		s.pendingMu.Lock()
+		delete(s.pending, reqId)
+		s.pendingMu.Unlock()
+	}()
+
+	wait := 0
+	for node := range nodes {
+		err = s.forward(ctx, node, &clusterFrame{
+			Type:  frameSessions,
+			Tid:   token.GetTenantId(ctx),
+			Uids:  []string{uid},
+			ReqId: reqId,
+		})
+		if err == nil {
+			wait++
+		}
+	}
+
+	var res []*domain.WsSession
+	timeout := time.NewTimer(sessionsTimeout)
+	defer timeout.Stop()
+	for ; wait > 0; wait-- {
+		select {
+		case list := <-ch:
+			res = append(res, list...)
+		case <-timeout.C:
+			tlog.ErrorfCtx(ctx, "cluster.remoteSessions", "%v nodes not reply, uid %v", wait, uid)
+			return res
+		case <-ctx.Done():
+			return res
+		}
+	}
+	return res
+}
+
+// remoteNodes 用户在其他实例上的连接，实例ID到用户ID
+func (s *Ws) remoteNodes(ctx context.Context, uids ...string) map[string][]string {
+	users, err := s.bus.Users(ctx, uids...)
+	if err != nil {
+		tlog.ErrorfCtx(ctx, "cluster.remoteNodes", "users fail %v", err.Error())
+		return nil
+	}
+
+	res := make(map[string][]string)
+	for _, uid := range uids {
+		for node := range users[uid] {
+			if node != s.node {
+				res[node] = append(res[node], uid)
+			}
+		}
+	}
+	return res
+}
+
+// clusterStatus 记录用户在本实例上的状态，返回用户在所有实例上的综合状态
+func (s *Ws) clusterStatus(ctx context.Context, uid string, local model.PresenceStatus) model.PresenceStatus {
+	status := string(local)
+	if local == model.PresenceOffline {
+		status = ""
+	}
+	if err := s.bus.SetUser(ctx, uid, s.node, status); err != nil {
+		tlog.ErrorfCtx(ctx, "cluster.status", "set user fail %v, uid %v", err.Error(), uid)
+		return local
+	}
+
+	users, err := s.bus.Users(ctx, uid)
+	if err != nil {
+		tlog.ErrorfCtx(ctx, "cluster.status", "users fail %v, uid %v", err.Error(), uid)
+		return local
+	}
+	res := model.PresenceOffline
+	for _, v := range users[uid] {
+		switch model.PresenceStatus(v) {
+		case model.PresenceOnline:
+			return model.PresenceOnline
+		case model.PresenceAway:
+			res = model.PresenceAway
+		}
+	}
+	return res
+}
@@ -2,23 +2,149 @@ package ws
 
 import (
 	"ai/internal/domain"
+	"ai/internal/logic"
+	"ai/internal/model"
 	"context"
+	"slices"
 
-	"github.com/gorilla/websocket"
+	"gitee.com/dn-jinmin/tlog"
 )
 
-func (s *Ws) privateChat(ctx context.Context, conn *websocket.Conn, req *domain.Message) error {
+func (s *Ws) privateChat(ctx context.Context, c *conn, req *domain.Message) error {
 	if err := s.chat.PrivateChat(ctx, req); err != nil {
 		return err
 	}
 
-	return s.sendByUids(ctx, req, req.RecvId)
+	// 同时同步到发送者的其他设备
+	s.sendToOthers(ctx, c, req)
+	go s.assistant(ctx, c, *req)
+	if req.RecvId == req.SendId {
+		return nil
+	}
+	return s.sendMessage(ctx, req, true, req.RecvId)
+}
+
+func (s *Ws) groupChat(ctx context.Context, c *conn, req *domain.Message) error {
+	uids, err := s.chat.GroupChat(ctx, req)
+	if err != nil {
+		return err
+	}
+	if len(uids) == 0 {
+		return nil
+	}
+	go s.assistant(ctx, c, *req)
+	return s.sendMessage(ctx, req, true, uids...)
+}
+
+// read 用户在一个设备上读过会话后，同步到其他设备，并给消息的发送者发送已读回执
+func (s *Ws) read(ctx context.Context, c *conn, req *domain.Message) error {
+	s.sendToOthers(ctx, c, req)
+
+	sendIds, err := s.chat.Read(ctx, req)
+	if err != nil {
+		return err
+	}
+	for _, sendId := range sendIds {
+		if sendId == c.uid {
+			continue
+		}
+		s.sendByUids(ctx, &domain.Message{
+			Event:          domain.EventReadReceipt,
+			Id:             req.Id,
+			ConversationId: req.ConversationId,
+			RecvId:         c.uid,
+			SendId:         sendId,
+		}, sendId)
+	}
+	return nil
+}
+
+// modify 撤回、编辑或删除消息后，将修改后的消息推送给需要通知的用户的所有设备
+func (s *Ws) modify(ctx context.Context, req *domain.Message,
+	handle func(ctx context.Context, req *domain.Message) ([]string, error)) error {
+	uids, err := handle(ctx, req)
+	if err != nil || len(uids) == 0 {
+		return err
+	}
+	s.sendByUids(ctx, req, uids...)
+	return nil
+}
+
+// assistant 消息中提到助手时，将助手的回复推送给会话的参与者。助手的处理时间较长，不阻塞连接的读循环。
+// 使用助手与 /v1/chat 一样需要对话的权限，没有权限时向发送者回复错误
+func (s *Ws) assistant(ctx context.Context, c *conn, req domain.Message) {
+	// 在独立的协程中运行，处理过程中的panic不能影响整个服务
+	defer func() {
+		if e := recover(); e != nil {
+			tlog.ErrorfCtx(ctx, "assistant", "panic %v, msg %v", e, req.Id)
+			s.replyError(ctx, c, &req, domain.WsErrHandle, ErrAssistant)
+		}
+	}()
+
+	if !logic.MentionAssistant(&req) {
+		return
+	}
+	if err := s.svc.Authorize(ctx, model.ResourceChat, model.ActionCreate, ""); err != nil {
+		s.replyError(ctx, c, &req, domain.WsErrForbidden, err)
+		return
+	}
+
+	reply, uids, err := s.chat.Assistant(ctx, &req)
+	if err != nil {
+		tlog.ErrorfCtx(ctx, "assistant", "fail %v, msg %v", err.Error(), req.Id)
+		return
+	}
+	if reply == nil || len(uids) == 0 {
+		return
+	}
+
+	if err = s.sendMessage(ctx, reply, false, uids...); err != nil {
+		tlog.ErrorfCtx(ctx, "assistant", "delivered fail %v, msg %v", err.Error(), reply.Id)
+	}
 }
 
-func (s *Ws) groupChat(ctx context.Context, conn *websocket.Conn, req *domain.Message) error {
-	if _, err := s.chat.GroupChat(ctx, req); err != nil {
+// delivered 记录消息已送达的接收者，发送者自己的设备不计入，receipt 为true时给发送者发送送达回执
+func (s *Ws) delivered(ctx context.Context, msg *domain.Message, receipt bool, uids []string) error {
+	uids = slices.DeleteFunc(uids, func(uid string) bool {
+		return uid == msg.SendId
+	})
+	if len(uids) == 0 {
+		return nil
+	}
+	if err := s.chat.Delivered(ctx, msg.Id, uids); err != nil {
 		return err
 	}
+	if !receipt {
+		return nil
+	}
+
+	for _, uid := range uids {
+		s.sendByUids(ctx, &domain.Message{
+			Event:          domain.EventDelivered,
+			Id:             msg.Id,
+			ConversationId: msg.ConversationId,
+			RecvId:         uid,
+			SendId:         msg.SendId,
+		}, msg.SendId)
+	}
+	return nil
+}
+
+// offline 用户上线后推送离线期间未送达的消息
+func (s *Ws) offline(ctx context.Context, c *conn) {
+	msgs, err := s.chat.Offline(ctx)
+	if err != nil {
+		tlog.ErrorfCtx(ctx, "offline", "list fail %v, uid %v", err.Error(), c.uid)
+		return
+	}
 
-	return s.sendByUids(ctx, req)
+	for _, msg := range msgs {
+		if err = s.reply(ctx, c, msg); err != nil {
+			tlog.ErrorfCtx(ctx, "offline", "send fail %v, uid %v", err.Error(), c.uid)
+			return
+		}
+		if err = s.delivered(ctx, msg, true, []string{c.uid}); err != nil {
+			tlog.ErrorfCtx(ctx, "offline", "delivered fail %v, uid %v", err.Error(), c.uid)
+		}
+	}
 }
@@ -0,0 +1,153 @@
+package ws
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"gitee.com/dn-jinmin/tlog"
+)
+
+var ErrPresenceStatus = errors.New("只能设置为 online 或 away")
+
+// status 用户当前的在线状态，有任一设备在线时为在线，所有设备都离开时为离开，调用方需要持有锁
+func (s *Ws) status(uid string) model.PresenceStatus {
+	conns := s.uidToConns[uid]
+	if len(conns) == 0 {
+		return model.PresenceOffline
+	}
+	for _, c := range conns {
+		if !c.away {
+			return model.PresenceOnline
+		}
+	}
+	return model.PresenceAway
+}
+
+// updatePresence 用户的连接或设备状态变化后，综合用户在各实例上的状态保存在线状态，并推送给订阅者
+func (s *Ws) updatePresence(ctx context.Context, c *conn) {
+	s.RWMutex.RLock()
+	local := s.status(c.uid)
+	s.RWMutex.RUnlock()
+
+	p, err := s.presence.Set(ctx, s.clusterStatus(ctx, c.uid, local))
+	if err != nil {
+		tlog.ErrorfCtx(ctx, "updatePresence", "set fail %v, uid %v", err.Error(), c.uid)
+		return
+	}
+	s.publish(ctx, p)
+}
+
+// publish 向本实例和其他实例上订阅了该用户的连接推送在线状态
+func (s *Ws) publish(ctx context.Context, p *domain.Presence) {
+	s.publishLocal(ctx, p)
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		tlog.ErrorCtx(ctx, "publish", err.Error())
+		return
+	}
+	s.forward(ctx, "", &clusterFrame{Type: framePresence, Msg: b})
+}
+
+// publishLocal 向本实例上订阅了该用户的连接推送在线状态
+func (s *Ws) publishLocal(ctx context.Context, p *domain.Presence) {
+	s.RWMutex.RLock()
+	defer s.RWMutex.RUnlock()
+
+	msg := &domain.Message{
+		Event:    domain.EventPresence,
+		SendId:   p.UserId,
+		Presence: p,
+	}
+	for c := range s.subscribers[p.UserId] {
+		if err := s.send(ctx, c, msg); err != nil {
+			tlog.ErrorfCtx(ctx, "publish.send", "err %v, uid %v, device %v", err.Error(), c.uid, c.deviceId)
+		}
+	}
+}
+
+// setPresence 客户端设置当前设备为在线或离开
+func (s *Ws) setPresence(ctx context.Context, c *conn, req *domain.Message) error {
+	if req.Presence == nil {
+		return ErrPresenceStatus
+	}
+
+	var away bool
+	switch model.PresenceStatus(req.Presence.Status) {
+	case model.PresenceOnline:
+	case model.PresenceAway:
+		away = true
+	default:
+		return ErrPresenceStatus
+	}
+
+	s.RWMutex.Lock()
+	c.away = away
+	s.RWMutex.Unlock()
+
+	s.updatePresence(ctx, c)
+	return nil
+}
+
+// subscribe 替换该连接订阅的用户，并推送这些用户当前的在线状态
+func (s *Ws) subscribe(ctx context.Context, c *conn, req *domain.Message) error {
+	presences, err := s.presence.Subscribe(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	s.RWMutex.Lock()
+	s.unsubscribe(c)
+	for _, p := range presences {
+		subs, ok := s.subscribers[p.UserId]
+		if !ok {
+			subs = make(map[*conn]struct{})
+			s.subscribers[p.UserId] = subs
+		}
+		subs[c] = struct{}{}
+		c.subscriptions = append(c.subscriptions, p.UserId)
+	}
+	s.RWMutex.Unlock()
+
+	for _, p := range presences {
+		if err = s.reply(ctx, c, &domain.Message{
+			Event:    domain.EventPresence,
+			SendId:   p.UserId,
+			Presence: p,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unsubscribe 取消该连接的所有订阅，调用方需要持有锁
+func (s *Ws) unsubscribe(c *conn) {
+	for _, uid := range c.subscriptions {
+		subs := s.subscribers[uid]
+		delete(subs, c)
+		if len(subs) == 0 {
+			delete(s.subscribers, uid)
+		}
+	}
+	c.subscriptions = nil
+}
+
+// typing 将正在输入转发给会话的其他参与者，不保存
+func (s *Ws) typing(ctx context.Context, req *domain.Message) error {
+	uids, err := s.presence.Typing(ctx, req)
+	if err != nil || len(uids) == 0 {
+		return err
+	}
+
+	s.sendByUids(ctx, &domain.Message{
+		Event:          domain.EventTyping,
+		ConversationId: req.ConversationId,
+		ChatType:       req.ChatType,
+		SendId:         req.SendId,
+	}, uids...)
+	return nil
+}
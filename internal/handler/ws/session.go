@@ -0,0 +1,117 @@
+package ws
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/pkg/httpx"
+	"ai/token"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+var ErrWsSessionNotFound = errors.New("该设备没有连接")
+
+// initRegister 注册连接管理的接口，用户可以查看和关闭自己在各设备上的连接，管理其他用户的连接需要用户管理权限
+func (s *Ws) initRegister(engine *gin.Engine) {
+	g := engine.Group("v1/ws", s.svc.Jwt.Handler)
+	g.GET("/sessions", s.Sessions)
+	g.DELETE("/sessions/:deviceId", s.CloseSession)
+}
+
+// Sessions 用户当前的websocket连接，集群模式下同时查询用户有连接的其他实例
+func (s *Ws) Sessions(ctx *gin.Context) {
+	var req domain.WsSessionListReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	uid, err := s.sessionUser(ctx.Request.Context(), req.UserId, model.ActionRead)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	list := s.localSessions(token.GetTenantId(ctx.Request.Context()), uid)
+	list = append(list, s.remoteSessions(ctx.Request.Context(), uid)...)
+	httpx.OkWithData(ctx, &domain.WsSessionListResp{
+		List: list,
+	})
+}
+
+// localSessions 用户在本实例上的连接
+func (s *Ws) localSessions(tid, uid string) []*domain.WsSession {
+	s.RWMutex.RLock()
+	defer s.RWMutex.RUnlock()
+
+	list := make([]*domain.WsSession, 0, len(s.uidToConns[uid]))
+	for _, c := range s.uidToConns[uid] {
+		if c.tid == tid {
+			list = append(list, c.toDomain())
+		}
+	}
+	return list
+}
+
+// CloseSession 关闭用户在指定设备上的连接
+func (s *Ws) CloseSession(ctx *gin.Context) {
+	var req domain.WsSessionCloseReq
+	if err := httpx.BindAndValidate(ctx, &req); err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	uid, err := s.sessionUser(ctx.Request.Context(), req.UserId, model.ActionUpdate)
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+		return
+	}
+
+	s.RWMutex.RLock()
+	c, ok := s.uidToConns[uid][req.DeviceId]
+	s.RWMutex.RUnlock()
+	if !ok || c.tid != token.GetTenantId(ctx.Request.Context()) {
+		// 连接可能在其他实例上
+		if !s.closeOnNodes(ctx.Request.Context(), uid, req.DeviceId) {
+			httpx.FailWithErr(ctx, ErrWsSessionNotFound)
+			return
+		}
+		httpx.Ok(ctx)
+		return
+	}
+
+	// 通知客户端连接被关闭的原因，连接的读循环随即结束并移除连接
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "连接已被关闭")
+	c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	c.Close()
+	httpx.Ok(ctx)
+}
+
+// closeOnNodes 通知用户有连接的其他实例关闭该设备的连接，用户在其他实例上没有连接时返回false
+func (s *Ws) closeOnNodes(ctx context.Context, uid, deviceId string) bool {
+	nodes := s.remoteNodes(ctx, uid)
+	for node := range nodes {
+		s.forward(ctx, node, &clusterFrame{
+			Type:     frameClose,
+			Tid:      token.GetTenantId(ctx),
+			Uids:     []string{uid},
+			DeviceId: deviceId,
+		})
+	}
+	return len(nodes) > 0
+}
+
+// sessionUser 查询或关闭其他用户的连接需要用户管理权限
+func (s *Ws) sessionUser(ctx context.Context, uid, action string) (string, error) {
+	if len(uid) == 0 || uid == token.GetUId(ctx) {
+		return token.GetUId(ctx), nil
+	}
+	if err := s.svc.Authorize(ctx, model.ResourceUser, action, ""); err != nil {
+		return "", err
+	}
+	return uid, nil
+}
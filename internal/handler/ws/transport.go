@@ -0,0 +1,123 @@
+package ws
+
+import (
+	"errors"
+	"time"
+
+	"gitee.com/dn-jinmin/tlog"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// writeWait 写入一条消息的超时时间
+	writeWait = 10 * time.Second
+	// maxMessageSize 客户端发送的单条消息的最大字节数
+	maxMessageSize = 64 * 1024
+
+	defaultPongWait  = 60 * time.Second
+	defaultSendQueue = 256
+)
+
+var (
+	ErrConnClosed   = errors.New("连接已关闭")
+	ErrSlowConsumer = errors.New("连接的发送队列已满")
+	ErrBadFrame     = errors.New("消息格式错误")
+	ErrUnknownEvent = errors.New("不支持的消息类型")
+	ErrAssistant    = errors.New("助手处理失败")
+)
+
+// pongWait 等待客户端响应心跳的时长，超时未收到任何消息时断开连接
+func (s *Ws) pongWait() time.Duration {
+	if s.svc.Config.Ws.PongWait > 0 {
+		return time.Duration(s.svc.Config.Ws.PongWait) * time.Second
+	}
+	return defaultPongWait
+}
+
+// newConnQueue 创建连接的发送队列
+func (s *Ws) newConnQueue(c *conn) {
+	size := s.svc.Config.Ws.SendQueue
+	if size <= 0 {
+		size = defaultSendQueue
+	}
+	c.queue = make(chan []byte, size)
+	c.done = make(chan struct{})
+}
+
+// readDeadline 设置读超时，收到客户端的心跳响应或任何消息后延长
+func (s *Ws) readDeadline(c *conn) {
+	wait := s.pongWait()
+	c.SetReadLimit(maxMessageSize)
+	c.SetReadDeadline(time.Now().Add(wait))
+	c.SetPongHandler(func(string) error {
+		return c.SetReadDeadline(time.Now().Add(wait))
+	})
+}
+
+// writeLoop 连接唯一的写入协程，发送队列中的消息并定时发送心跳，写入失败时关闭连接。
+// 每次心跳时重新校验连接的会话
+func (s *Ws) writeLoop(c *conn) {
+	ticker := time.NewTicker(s.pongWait() * 9 / 10)
+	defer func() {
+		ticker.Stop()
+		c.Close()
+	}()
+
+	for {
+		select {
+		case b := <-c.queue:
+			c.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.WriteMessage(websocket.TextMessage, b); err != nil {
+				tlog.Errorf("writeLoop", "write fail %v, uid %v, device %v", err.Error(), c.uid, c.deviceId)
+				return
+			}
+		case <-ticker.C:
+			if err := c.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				tlog.Errorf("writeLoop", "ping fail %v, uid %v, device %v", err.Error(), c.uid, c.deviceId)
+				return
+			}
+			// 会话可能在连接建立后被撤销，查询不阻塞写入
+			go s.checkSession(c)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// enqueue 将消息放入发送队列，wait 为0时队列满立即断开连接，否则最多等待 wait；
+// 慢速连接断开后由读循环移除
+func (c *conn) enqueue(b []byte, wait time.Duration) error {
+	select {
+	case <-c.done:
+		return ErrConnClosed
+	case c.queue <- b:
+		return nil
+	default:
+	}
+
+	if wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-c.done:
+			return ErrConnClosed
+		case c.queue <- b:
+			return nil
+		case <-timer.C:
+		}
+	}
+
+	tlog.Errorf("conn.enqueue", "slow consumer, uid %v, device %v", c.uid, c.deviceId)
+	c.Close()
+	return ErrSlowConsumer
+}
+
+// Close 关闭连接并结束写入协程，可以重复调用
+func (c *conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = c.Conn.Close()
+	})
+	return err
+}
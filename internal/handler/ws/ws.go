@@ -2,18 +2,26 @@ package ws
 
 import (
 	"ai/internal/domain"
+	"ai/internal/handler"
 	"ai/internal/logic"
+	"ai/internal/middleware"
 	"ai/internal/model"
 	"ai/internal/svc"
+	"ai/pkg/encrypt"
+	"ai/pkg/httpx"
+	"ai/pkg/wsbus"
 	"ai/token"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"gitee.com/dn-jinmin/tlog"
+	"github.com/gin-gonic/gin"
 
 	"github.com/gorilla/websocket"
 )
@@ -25,13 +33,52 @@ type Ws struct {
 	svc         *svc.ServiceContext // 服务上下文，包含配置等共享资源
 	tokenparser *token.Parse        // 令牌解析器，用于验证用户身份
 	chat        logic.Chat          // 聊天业务逻辑处理组件
+	presence    logic.Presence      // 在线状态与正在输入
 
-	uidToConn map[string]*websocket.Conn // 用户ID到WebSocket连接的映射
-	ConnToUid map[*websocket.Conn]string // WebSocket连接到用户ID的映射
+	bus  wsbus.Bus // 记录用户的连接所在的实例并在实例之间转发消息，单实例部署时为进程内的实现
+	node string    // 本实例的ID
+
+	uidToConns  map[string]map[string]*conn   // 用户ID到各设备连接的映射，按设备ID区分
+	subscribers map[string]map[*conn]struct{} // 被订阅的用户ID到订阅其在线状态的连接
+
+	pendingMu sync.Mutex
+	pending   map[string]chan []*domain.WsSession // 等待其他实例回复的连接查询，请求ID到回复
+	frames    chan *clusterFrame                  // 等待处理的其他实例转发的消息
+}
+
+// conn 用户在一个设备上的WebSocket连接，同一用户可以在多个设备上同时连接
+type conn struct {
+	*websocket.Conn
+
+	uid       string // 用户ID
+	tid       string // 租户ID，消息只发送给同一租户的用户
+	sid       string // 登录会话ID
+	token     string
+	deviceId  string // 设备ID，由客户端在连接时传递，同一设备重复连接时关闭之前的连接
+	ip        string
+	userAgent string
+	connectAt int64
+
+	away          bool     // 客户端设置该设备处于离开状态
+	subscriptions []string // 该连接订阅了在线状态的用户
+
+	queue     chan []byte   // 待发送的消息，由写入协程依次发送
+	done      chan struct{} // 连接关闭后结束写入协程
+	closeOnce sync.Once
+}
+
+func (c *conn) toDomain() *domain.WsSession {
+	return &domain.WsSession{
+		UserId:    c.uid,
+		DeviceId:  c.deviceId,
+		Ip:        c.ip,
+		UserAgent: c.userAgent,
+		ConnectAt: c.connectAt,
+	}
 }
 
-// NewWs 创建一个新的WebSocket服务实例
-func NewWs(svc *svc.ServiceContext) *Ws {
+// NewWs 创建一个新的WebSocket服务实例，集群模式下多个实例通过Redis记录用户的连接并转发消息
+func NewWs(svc *svc.ServiceContext) (*Ws, error) {
 	// 初始化日志配置
 	tlog.Init(
 		tlog.WithLoggerWriter(tlog.NewLoggerWriter()),
@@ -39,6 +86,26 @@ func NewWs(svc *svc.ServiceContext) *Ws {
 		tlog.WithMode(svc.Config.Tlog.Mode),
 	)
 
+	bus := wsbus.NewMemoryBus()
+	if svc.Config.Ws.Cluster {
+		var err error
+		if bus, err = wsbus.NewRedisBus(svc.Config.Redis.Addr); err != nil {
+			return nil, err
+		}
+	}
+
+	node := svc.Config.Ws.NodeId
+	if len(node) == 0 {
+		var err error
+		if node, err = encrypt.RandomToken(8); err != nil {
+			return nil, err
+		}
+	}
+	return NewWsWithBus(svc, bus, node), nil
+}
+
+// NewWsWithBus 使用指定的消息总线创建实例，同一进程中的多个实例共用进程内的消息总线即可模拟集群
+func NewWsWithBus(svc *svc.ServiceContext, bus wsbus.Bus, node string) *Ws {
 	return &Ws{
 		// 初始化WebSocket升级器，允许所有来源的连接
 		Upgrader: websocket.Upgrader{
@@ -47,24 +114,39 @@ func NewWs(svc *svc.ServiceContext) *Ws {
 			}, // 允许跨域请求
 		},
 		svc:         svc,
-		chat:        logic.NewChat(svc),                         // 初始化聊天业务逻辑
+		chat:        logic.NewChat(svc), // 初始化聊天业务逻辑
+		presence:    logic.NewPresence(svc),
 		tokenparser: token.NewTokenParse(svc.Config.Jwt.Secret), // 初始化令牌解析器
 
-		uidToConn: make(map[string]*websocket.Conn), // 初始化用户ID到连接的映射
-		ConnToUid: make(map[*websocket.Conn]string), // 初始化连接到用户ID的映射
+		uidToConns:  make(map[string]map[string]*conn), // 初始化用户ID到连接的映射
+		subscribers: make(map[string]map[*conn]struct{}),
+		pending:     make(map[string]chan []*domain.WsSession),
+		frames:      make(chan *clusterFrame, clusterQueue),
+
+		bus:  bus,
+		node: node,
 	}
 }
 
 // Run 启动WebSocket服务
 func (s *Ws) Run() {
+	engine := gin.Default()
+	engine.Use(middleware.NewLog().Handler)
+	httpx.SetErrorHandler(handler.ErrorHandler)
+
 	// 注册WebSocket处理函数，路径为"/ws"
-	http.HandleFunc("/ws", s.ServerWs)
+	engine.GET("/ws", gin.WrapF(s.ServerWs))
+	// 连接管理的接口
+	s.initRegister(engine)
+	// 与其他实例同步连接和消息
+	go s.runCluster()
+
 	// 打印启动信息并开始监听指定地址
 	fmt.Println("启动websocket服务", s.svc.Config.Ws.Addr)
-	http.ListenAndServe(s.svc.Config.Ws.Addr, nil)
+	engine.Run(s.svc.Config.Ws.Addr)
 }
 
-// ServerWs 处理WebSocket连接请求
+// ServerWs 处理WebSocket连接请求，客户端通过 deviceId 参数区分设备
 func (s *Ws) ServerWs(w http.ResponseWriter, r *http.Request) {
 	defer func() {
 		if e := recover(); e != nil {
@@ -72,157 +154,366 @@ func (s *Ws) ServerWs(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	// 对连接进行鉴权，获取用户ID和令牌
-	uid, token, err := s.auth(r)
+	// 对连接进行鉴权，获取用户ID、租户ID和令牌
+	c, err := s.auth(r)
 	if err != nil {
 		tlog.ErrorfCtx(r.Context(), "serverWs", "auth fail %v", err.Error())
 		return
 	}
 
+	c.deviceId = r.URL.Query().Get("deviceId")
+	if len(c.deviceId) == 0 {
+		if c.deviceId, err = encrypt.RandomToken(8); err != nil {
+			tlog.ErrorfCtx(r.Context(), "serverWs", "device id fail %v", err.Error())
+			return
+		}
+	}
+	c.ip = r.RemoteAddr
+	if ip := r.Header.Get("X-Forwarded-For"); len(ip) > 0 {
+		c.ip = strings.TrimSpace(strings.Split(ip, ",")[0])
+	}
+	c.userAgent = r.UserAgent()
+	c.connectAt = time.Now().Unix()
+
 	// 请求升级，设置WebSocket响应头，包含协议信息
 	respHeader := http.Header{
-		"sec-websocket-protocol": []string{token},
+		"sec-websocket-protocol": []string{c.token},
 	}
 
 	// 将HTTP连接升级为WebSocket连接
-	c, err := s.Upgrade(w, r, respHeader)
+	c.Conn, err = s.Upgrade(w, r, respHeader)
 	if err != nil {
 		tlog.ErrorfCtx(r.Context(), "serverWs", "Upgrade fail %v", err.Error())
 		return
 	}
 
+	// 每个连接只由一个协程写入
+	s.newConnQueue(c)
+	go s.writeLoop(c)
+
 	// 记录新建立的连接
-	s.addConn(c, uid)
+	s.addConn(c)
+	s.closeRemote(s.context(c), c)
+	s.updatePresence(s.context(c), c)
 
 	// 启动goroutine处理该连接的消息
-	go s.handlerConn(c, uid, token)
+	go s.handlerConn(c)
 }
 
-// handlerConn 处理单个WebSocket连接的消息循环
-func (s *Ws) handlerConn(conn *websocket.Conn, uid, tok string) {
+// handlerConn 处理单个WebSocket连接的消息循环，开始前先推送离线期间未送达的消息。
+// 单条消息处理失败时回复错误帧，不影响之后的消息
+func (s *Ws) handlerConn(c *conn) {
+	s.readDeadline(c)
+	s.offline(s.context(c), c)
+
 	for {
 		// 读取客户端发送的消息
-		_, msg, err := conn.ReadMessage()
+		_, msg, err := c.ReadMessage()
 		if err != nil {
-			tlog.Errorf("serverWs", "conn.ReadMessage fail %v, uid %v", err.Error(), uid)
-			s.closeConn(conn)
+			tlog.Errorf("serverWs", "conn.ReadMessage fail %v, uid %v, device %v", err.Error(), c.uid, c.deviceId)
+			s.closeConn(c)
+			s.updatePresence(s.context(c), c)
 			return
 		}
 
+		// 收到任何消息都说明连接正常
+		c.SetReadDeadline(time.Now().Add(s.pongWait()))
+
 		// 创建包含用户信息的上下文
-		ctx := s.context(uid, tok)
+		ctx := s.context(c)
 
 		// 解析消息为Message结构体
 		var req domain.Message
 		if err := json.Unmarshal(msg, &req); err != nil {
 			tlog.ErrorfCtx(ctx, "handlerConn", "json.Unmarshal fail %v", err.Error())
-			return
-		}
-		req.SendId = uid
-
-		// 根据消息类型分发处理
-		switch model.ChatType(req.ChatType) {
-		case model.SingleChatType:
-			err = s.privateChat(ctx, conn, &req)
-		case model.GroupChatType:
-			err = s.groupChat(ctx, conn, &req)
+			s.replyError(ctx, c, &req, domain.WsErrBadFrame, ErrBadFrame)
+			continue
 		}
+		req.SendId = c.uid
 
-		if err != nil {
+		if err := s.handle(ctx, c, &req); err != nil {
 			tlog.ErrorfCtx(ctx, "handlerConn", "message handle fail %v, msg %v", err.Error(), req)
-			return
+			code := domain.WsErrHandle
+			if errors.Is(err, ErrUnknownEvent) {
+				code = domain.WsErrBadFrame
+			}
+			s.replyError(ctx, c, &req, code, err)
+			continue
+		}
+		if len(req.ReqId) > 0 {
+			s.reply(ctx, c, &domain.Message{
+				Event:          domain.EventAck,
+				ReqId:          req.ReqId,
+				Id:             req.Id,
+				ConversationId: req.ConversationId,
+				SendTime:       req.SendTime,
+			})
 		}
 	}
 }
 
-// context 创建包含用户身份信息和日志追踪的上下文
-func (s *Ws) context(uid, tok string) context.Context {
-	ctx := context.WithValue(context.Background(), token.Identify, uid)
-	ctx = context.WithValue(ctx, token.Authorization, tok)
+// handle 根据消息类型分发处理，处理过程中的panic作为处理失败返回
+func (s *Ws) handle(ctx context.Context, c *conn, req *domain.Message) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("%v", e)
+		}
+	}()
 
-	return tlog.TraceStart(ctx)
+	switch {
+	case req.Event == domain.EventRead:
+		return s.read(ctx, c, req)
+	case req.Event == domain.EventRecall:
+		return s.modify(ctx, req, s.chat.Recall)
+	case req.Event == domain.EventEdit:
+		return s.modify(ctx, req, s.chat.Edit)
+	case req.Event == domain.EventDelete:
+		return s.modify(ctx, req, s.chat.DeleteMessage)
+	case req.Event == domain.EventPresence:
+		return s.setPresence(ctx, c, req)
+	case req.Event == domain.EventSubscribe:
+		return s.subscribe(ctx, c, req)
+	case req.Event == domain.EventTyping:
+		return s.typing(ctx, req)
+	case req.Event == domain.EventAIChat:
+		return s.aiChat(ctx, c, req)
+	case len(req.Event) > 0:
+		return ErrUnknownEvent
+	case model.ChatType(req.ChatType) == model.SingleChatType:
+		return s.privateChat(ctx, c, req)
+	case model.ChatType(req.ChatType) == model.GroupChatType:
+		return s.groupChat(ctx, c, req)
+	default:
+		return ErrUnknownEvent
+	}
 }
 
-// addConn 将新连接添加到映射中，线程安全
-func (s *Ws) addConn(conn *websocket.Conn, uid string) {
+// replyError 回复错误帧
+func (s *Ws) replyError(ctx context.Context, c *conn, req *domain.Message, code int, err error) {
+	s.reply(ctx, c, &domain.Message{
+		Event:          domain.EventError,
+		ReqId:          req.ReqId,
+		Id:             req.Id,
+		ConversationId: req.ConversationId,
+		Code:           code,
+		Error:          err.Error(),
+	})
+}
+
+// context 创建包含用户身份信息、所属租户和日志追踪的上下文
+func (s *Ws) context(c *conn) context.Context {
+	ctx := context.WithValue(context.Background(), token.Identify, c.uid)
+	ctx = context.WithValue(ctx, token.SessionIdentify, c.sid)
+	ctx = token.WithTenantId(ctx, c.tid)
+	ctx = context.WithValue(ctx, token.Authorization, c.token)
+
+	return middleware.TraceStart(ctx)
+}
+
+// addConn 将新连接添加到映射中，同一设备重复连接时关闭之前的连接，线程安全
+func (s *Ws) addConn(c *conn) {
 	s.RWMutex.Lock()
 	defer s.RWMutex.Unlock()
 
-	if c := s.uidToConn[uid]; c != nil {
-		c.Close()
+	conns, ok := s.uidToConns[c.uid]
+	if !ok {
+		conns = make(map[string]*conn)
+		s.uidToConns[c.uid] = conns
 	}
-
-	s.uidToConn[uid] = conn
-	s.ConnToUid[conn] = uid
+	if old := conns[c.deviceId]; old != nil {
+		old.Close()
+	}
+	conns[c.deviceId] = c
 }
 
 // closeConn 关闭连接并从映射中移除，线程安全
-func (s *Ws) closeConn(conn *websocket.Conn) {
+func (s *Ws) closeConn(c *conn) {
 	s.RWMutex.Lock()
 	defer s.RWMutex.Unlock()
 
-	uid := s.ConnToUid[conn]
-	if uid == "" {
+	c.Close()
+	s.unsubscribe(c)
+
+	// 同一设备重新连接后，旧连接已经被替换
+	conns := s.uidToConns[c.uid]
+	if conns[c.deviceId] != c {
 		return
 	}
 
-	fmt.Printf("关闭 %s 连接\n", uid)
-
-	delete(s.ConnToUid, conn)
-	delete(s.uidToConn, uid)
+	fmt.Printf("关闭 %s 设备 %s 连接\n", c.uid, c.deviceId)
 
-	conn.Close()
+	delete(conns, c.deviceId)
+	if len(conns) == 0 {
+		delete(s.uidToConns, c.uid)
+	}
 }
 
-// send 向指定连接发送消息
-func (s *Ws) send(ctx context.Context, conn *websocket.Conn, v interface{}) error {
+// send 向指定连接发送消息，连接的发送队列已满时断开该连接，不阻塞向其他连接发送
+func (s *Ws) send(ctx context.Context, c *conn, v interface{}) error {
 	b, err := json.Marshal(v)
 	if err != nil {
 		tlog.ErrorCtx(ctx, "conn.send", err.Error())
 		return err
 	}
 
-	return conn.WriteMessage(websocket.TextMessage, b)
+	return c.enqueue(b, 0)
 }
 
-// sendByUids 向指定用户列表发送消息，支持广播（uids为空时）
-func (s *Ws) sendByUids(ctx context.Context, msg interface{}, uids ...string) error {
+// reply 在连接自己的读循环中向该连接发送消息，发送队列已满时等待写入
+func (s *Ws) reply(ctx context.Context, c *conn, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		tlog.ErrorCtx(ctx, "conn.reply", err.Error())
+		return err
+	}
+
+	if err = c.enqueue(b, writeWait); err != nil {
+		tlog.ErrorfCtx(ctx, "conn.reply", "err %v, uid %v, device %v", err.Error(), c.uid, c.deviceId)
+	}
+	return err
+}
+
+// sendByUids 向指定用户的所有设备发送消息，支持广播（uids为空时），只发送给与当前用户同一租户的用户。
+// 用户在其他实例上的连接通过消息总线转发。某个设备发送失败不影响其他设备
+func (s *Ws) sendByUids(ctx context.Context, msg interface{}, uids ...string) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		tlog.ErrorCtx(ctx, "sendByUids", err.Error())
+		return
+	}
+
+	tid := token.GetTenantId(ctx)
+	s.deliverLocal(ctx, tid, b, uids...)
+	if len(uids) == 0 {
+		s.forward(ctx, "", &clusterFrame{Type: frameDeliver, Tid: tid, Msg: b})
+		return
+	}
+	for node, nodeUids := range s.remoteNodes(ctx, uids...) {
+		s.forward(ctx, node, &clusterFrame{Type: frameDeliver, Tid: tid, Uids: nodeUids, Msg: b})
+	}
+}
+
+// sendMessage 向指定用户的所有设备发送聊天消息并记录送达，receipt 为true时同时给发送者发送送达回执。
+// 本实例上的用户在消息进入发送队列后记录送达，其他实例上的用户由该实例在进入发送队列后记录
+func (s *Ws) sendMessage(ctx context.Context, msg *domain.Message, receipt bool, uids ...string) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		tlog.ErrorCtx(ctx, "sendMessage", err.Error())
+		return err
+	}
+
+	tid := token.GetTenantId(ctx)
+	delivered := s.deliverLocal(ctx, tid, b, uids...)
+	for node, nodeUids := range s.remoteNodes(ctx, uids...) {
+		s.forward(ctx, node, &clusterFrame{Type: frameDeliver, Tid: tid, Uids: nodeUids, Msg: b, Record: true, Receipt: receipt})
+	}
+	return s.delivered(ctx, msg, receipt, delivered)
+}
+
+// deliverLocal 向本实例上指定用户的所有设备发送消息，uids为空时发送给本实例上同一租户的所有用户
+func (s *Ws) deliverLocal(ctx context.Context, tid string, b []byte, uids ...string) (delivered []string) {
 	s.RWMutex.RLock()
 	defer s.RWMutex.RUnlock()
 
 	if len(uids) == 0 {
-		for i, _ := range s.uidToConn {
-			if err := s.send(ctx, s.uidToConn[i], msg); err != nil {
-				tlog.ErrorCtx(ctx, "sendByUids.all.send", err.Error())
-				return err
-			}
+		for uid := range s.uidToConns {
+			uids = append(uids, uid)
 		}
 	}
 
 	for _, uid := range uids {
-		c, ok := s.uidToConn[uid]
-		if !ok {
+		ok := false
+		for _, c := range s.uidToConns[uid] {
+			if c.tid != tid {
+				continue
+			}
+			if err := c.enqueue(b, 0); err != nil {
+				tlog.ErrorfCtx(ctx, "deliverLocal", "err %v, uid %v, device %v", err.Error(), uid, c.deviceId)
+				continue
+			}
+			ok = true
+		}
+		if ok {
+			delivered = append(delivered, uid)
+		}
+	}
+	return delivered
+}
+
+// sendToOthers 向用户的其他设备发送消息，用于多个设备之间同步
+func (s *Ws) sendToOthers(ctx context.Context, from *conn, msg interface{}) {
+	s.RWMutex.RLock()
+	for _, c := range s.uidToConns[from.uid] {
+		if c == from {
 			continue
 		}
 		if err := s.send(ctx, c, msg); err != nil {
-			tlog.ErrorfCtx(ctx, "sendByUids.one.send err %v, uid %v", err.Error(), uid)
-			return err
+			tlog.ErrorfCtx(ctx, "sendToOthers.send", "err %v, uid %v, device %v", err.Error(), c.uid, c.deviceId)
 		}
 	}
-	return nil
+	s.RWMutex.RUnlock()
+
+	// 其他实例上只有该用户的其他设备
+	nodes := s.remoteNodes(ctx, from.uid)
+	if len(nodes) == 0 {
+		return
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		tlog.ErrorCtx(ctx, "sendToOthers", err.Error())
+		return
+	}
+	for node := range nodes {
+		s.forward(ctx, node, &clusterFrame{Type: frameDeliver, Tid: from.tid, Uids: []string{from.uid}, Msg: b})
+	}
+}
+
+// closeRemote 同一设备在其他实例上的连接已经被替换，通知其他实例关闭
+func (s *Ws) closeRemote(ctx context.Context, c *conn) {
+	for node := range s.remoteNodes(ctx, c.uid) {
+		s.forward(ctx, node, &clusterFrame{Type: frameClose, Tid: c.tid, Uids: []string{c.uid}, DeviceId: c.deviceId})
+	}
 }
 
 // auth 验证WebSocket连接的身份
-func (s *Ws) auth(r *http.Request) (uid string, tokenStr string, err error) {
+func (s *Ws) auth(r *http.Request) (*conn, error) {
 	tok := r.Header.Get("sec-websocket-protocol")
 	if tok == "" {
-		return "", "", errors.New("没有登入，不存在访问权限")
+		return nil, errors.New("没有登入，不存在访问权限")
 	}
 
 	claims, tokenStr, err := s.tokenparser.ParseToken(tok)
 	if err != nil {
-		return "", "", err
+		return nil, err
+	}
+
+	// 校验令牌所属的会话未被撤销，角色要求二次验证时会话需要已完成二次验证
+	c := &conn{token: tokenStr}
+	c.uid, _ = claims[token.Identify].(string)
+	c.sid, _ = claims[token.SessionIdentify].(string)
+	c.tid, _ = claims[token.TenantIdentify].(string)
+	if err = s.svc.CheckTwoFactor(token.WithTenantId(r.Context(), c.tid), c.uid, c.sid); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// checkSession 重新校验连接所属的会话，会话已撤销、租户已停用或需要二次验证时关闭连接，
+// 退出登录、修改密码、禁用用户等撤销会话后最迟在下一次心跳时断开。查询失败时保留连接，等待下一次校验
+func (s *Ws) checkSession(c *conn) {
+	ctx := s.context(c)
+	err := s.svc.CheckTwoFactor(ctx, c.uid, c.sid)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, svc.ErrSessionInvalid) && !errors.Is(err, svc.ErrTwoFactorRequired) && !errors.Is(err, svc.ErrTenantDisabled) {
+		tlog.ErrorfCtx(ctx, "checkSession", "err %v, uid %v, device %v", err.Error(), c.uid, c.deviceId)
+		return
 	}
 
-	return claims[token.Identify].(string), tokenStr, nil
+	// 通知客户端连接被关闭的原因，连接的读循环随即结束并移除连接
+	msg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, err.Error())
+	c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	c.Close()
 }
@@ -0,0 +1,160 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/encrypt"
+	"ai/token"
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// apiKeyBytes API密钥的随机字节数
+	apiKeyBytes = 32
+	// apiKeyPrefixLen 列表中展示的密钥前缀长度
+	apiKeyPrefixLen = 12
+)
+
+// ApiKey 用户及服务账号的API密钥，用于脚本和其他系统调用接口
+type ApiKey interface {
+	Create(ctx context.Context, req *domain.ApiKeyCreateReq) (resp *domain.ApiKeyCreateResp, err error)
+	List(ctx context.Context, req *domain.ApiKeyListReq) (resp *domain.ApiKeyListResp, err error)
+	All(ctx context.Context, req *domain.ApiKeyListReq) (resp *domain.ApiKeyListResp, err error)
+	Revoke(ctx context.Context, req *domain.IdPathReq) (err error)
+}
+
+type apiKey struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewApiKey(svcCtx *svc.ServiceContext) ApiKey {
+	return &apiKey{
+		svcCtx: svcCtx,
+	}
+}
+
+// Create 创建API密钥，管理员可以为服务账号创建密钥
+func (l *apiKey) Create(ctx context.Context, req *domain.ApiKeyCreateReq) (resp *domain.ApiKeyCreateResp, err error) {
+	uid := token.GetUId(ctx)
+	// 未完成二次验证的会话不能通过创建密钥绕过二次验证
+	if err = l.svcCtx.CheckTwoFactor(ctx, uid, token.GetSessionId(ctx)); err != nil {
+		return nil, err
+	}
+	if len(req.UserId) > 0 && req.UserId != uid {
+		if err = l.svcCtx.Authorize(ctx, model.ResourceUser, model.ActionUpdate, ""); err != nil {
+			return nil, err
+		}
+		u, err := l.svcCtx.UserModel.FindOne(ctx, req.UserId)
+		if err != nil {
+			return nil, err
+		}
+		if !u.ServiceAccount {
+			return nil, errors.New("只能为服务账号创建API密钥")
+		}
+		uid = req.UserId
+	}
+
+	if len(req.Name) == 0 {
+		return nil, errors.New("请填写密钥名称")
+	}
+	if len(req.Scopes) == 0 {
+		return nil, errors.New("请选择密钥的授权范围")
+	}
+	if req.ExpireAt > 0 && req.ExpireAt <= time.Now().Unix() {
+		return nil, errors.New("过期时间不能早于当前时间")
+	}
+
+	scopes := make([]*model.Permission, 0, len(req.Scopes))
+	for _, p := range req.Scopes {
+		if len(p.Resource) == 0 || len(p.Action) == 0 {
+			return nil, errors.New("授权范围不完整")
+		}
+		scopes = append(scopes, &model.Permission{
+			Resource: p.Resource,
+			Action:   p.Action,
+		})
+	}
+
+	random, err := encrypt.RandomToken(apiKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	key := token.ApiKeyPrefix + random
+
+	data := &model.ApiKey{
+		UserId:    uid,
+		CreatorId: token.GetUId(ctx),
+		Name:      req.Name,
+		Prefix:    key[:apiKeyPrefixLen],
+		Hash:      encrypt.Sha256(key),
+		Scopes:    scopes,
+		ExpireAt:  req.ExpireAt,
+	}
+	if err = l.svcCtx.ApiKeyModel.Insert(ctx, data); err != nil {
+		return nil, err
+	}
+
+	return &domain.ApiKeyCreateResp{
+		Id:  data.ID.Hex(),
+		Key: key,
+	}, nil
+}
+
+// List 当前用户的API密钥
+func (l *apiKey) List(ctx context.Context, req *domain.ApiKeyListReq) (resp *domain.ApiKeyListResp, err error) {
+	req.UserId = token.GetUId(ctx)
+	return l.list(ctx, req)
+}
+
+// All 管理员查询所有用户的API密钥，可按用户筛选
+func (l *apiKey) All(ctx context.Context, req *domain.ApiKeyListReq) (resp *domain.ApiKeyListResp, err error) {
+	return l.list(ctx, req)
+}
+
+// Revoke 撤销API密钥，撤销其他用户的密钥需要用户管理权限
+func (l *apiKey) Revoke(ctx context.Context, req *domain.IdPathReq) (err error) {
+	data, err := l.svcCtx.ApiKeyModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+	if data.UserId != token.GetUId(ctx) {
+		if err = l.svcCtx.Authorize(ctx, model.ResourceUser, model.ActionUpdate, ""); err != nil {
+			return err
+		}
+	}
+	return l.svcCtx.ApiKeyModel.Revoke(ctx, req.Id)
+}
+
+func (l *apiKey) list(ctx context.Context, req *domain.ApiKeyListReq) (*domain.ApiKeyListResp, error) {
+	keys, count, err := l.svcCtx.ApiKeyModel.List(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]*model.User)
+	if len(keys) > 0 {
+		uids := make([]string, 0, len(keys))
+		for i := range keys {
+			uids = append(uids, keys[i].UserId)
+		}
+		if users, err = l.svcCtx.UserModel.ListToMaps(ctx, &domain.UserListReq{Ids: uids}); err != nil {
+			return nil, err
+		}
+	}
+
+	list := make([]*domain.ApiKey, 0, len(keys))
+	for i := range keys {
+		item := keys[i].ToDomain()
+		if u, ok := users[keys[i].UserId]; ok {
+			item.UserName = u.Name
+		}
+		list = append(list, item)
+	}
+	return &domain.ApiKeyListResp{
+		Count: count,
+		List:  list,
+	}, nil
+}
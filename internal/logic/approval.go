@@ -9,12 +9,15 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"ai/internal/domain"
 	"ai/internal/svc"
+	"ai/pkg/mongox"
 )
 
 type Approval interface {
@@ -25,12 +28,14 @@ type Approval interface {
 }
 
 type approval struct {
-	svcCtx *svc.ServiceContext
+	svcCtx      *svc.ServiceContext
+	offboarding Offboarding
 }
 
 func NewApproval(svcCtx *svc.ServiceContext) Approval {
 	return &approval{
-		svcCtx: svcCtx,
+		svcCtx:      svcCtx,
+		offboarding: NewOffboarding(svcCtx),
 	}
 }
 
@@ -63,12 +68,37 @@ func (l *approval) Info(ctx context.Context, req *domain.IdPathReq) (resp *domai
 		})
 	}
 
+	// 处理中的审批返回当前审批人的在线状态，便于催办
+	if approval.Status != model.Processed {
+		return
+	}
+	presences, err := l.svcCtx.PresenceModel.ListToMaps(ctx, []string{approval.ApprovalId})
+	if err != nil {
+		return nil, err
+	}
+	resp.Approver.Presence = string(presences[approval.ApprovalId].Status)
+	resp.Approver.LastSeen = presences[approval.ApprovalId].LastSeen
+
 	return
 }
 
 func (l *approval) Create(ctx context.Context, req *domain.Approval) (resp *domain.IdResp, err error) {
 	uid := token.GetUId(ctx)
 	req.UserId = uid
+
+	// 租户可以停用某类审批，或在部门主管之后追加固定的审批人
+	t, err := l.svcCtx.CurrentTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tpl *model.ApprovalTemplate
+	if t != nil {
+		tpl = t.Config.Template(model.ApprovalType(req.Type))
+	}
+	if tpl != nil && tpl.Disabled {
+		return nil, fmt.Errorf("%s审批已停用", model.ApprovalType(req.Type).ToString())
+	}
+
 	approval := l.newApproval(req)
 
 	var abstract string
@@ -101,6 +131,20 @@ func (l *approval) Create(ctx context.Context, req *domain.Approval) (resp *doma
 		}
 		abstract = fmt.Sprintf("【%s】【%s】", timex.Format(req.MakeCard.Date), req.MakeCard.Reason)
 		approval.Reason = req.MakeCard.Reason
+	case model.DimissionApproval:
+		if req.Dimission == nil {
+			return nil, errors.New("请填写离职信息")
+		}
+		lastDay := req.Dimission.LastDay
+		if lastDay == 0 {
+			lastDay = time.Now().Unix()
+		}
+		approval.Dimission = &model.Dimission{
+			LastDay: lastDay,
+			Reason:  req.Dimission.Reason,
+		}
+		abstract = fmt.Sprintf("最后工作日【%s】", timex.Format(lastDay))
+		approval.Reason = req.Dimission.Reason
 	default:
 		// ...
 	}
@@ -112,8 +156,8 @@ func (l *approval) Create(ctx context.Context, req *domain.Approval) (resp *doma
 	approval.Title = fmt.Sprintf("%s 提交的 %s", user.Name, model.ApprovalType(req.Type).ToString())
 	approval.Abstract = abstract
 
-	// 审批人
-	depUser, err := l.svcCtx.DepartmentUserModel.FindByUserId(ctx, user.ID.Hex())
+	// 审批人：用户属于多个部门时可以选择提交审批的部门，默认使用主部门
+	depUser, err := l.fileDepartment(ctx, user.ID.Hex(), req.DepId)
 	if err != nil {
 		return
 	}
@@ -146,15 +190,28 @@ func (l *approval) Create(ctx context.Context, req *domain.Approval) (resp *doma
 		})
 		participations = append(participations, pdeps[parentIds[i]].LeaderId)
 	}
+	if tpl != nil {
+		for _, approver := range tpl.Approvers {
+			if slices.Contains(participations, approver) {
+				continue
+			}
+			approvals = append(approvals, &model.Approver{
+				UserId: approver,
+			})
+			participations = append(participations, approver)
+		}
+	}
 
 	approval.Approvers = approvals
 	approval.Participation = participations
 	approval.ApprovalId = dep.LeaderId
 	approval.UserId = uid
+	approval.DepId = depUser.DepId
 
 	if err = l.svcCtx.ApprovalModel.Insert(ctx, approval); err != nil {
 		return
 	}
+	l.svcCtx.Audit(ctx, model.ActionCreate, model.ResourceApproval, approval.ID.Hex(), nil, approval)
 
 	return &domain.IdResp{
 		Id: approval.ID.Hex(),
@@ -167,6 +224,7 @@ func (l *approval) Dispose(ctx context.Context, req *domain.DisposeReq) (err err
 		return err
 	}
 	uid := token.GetUId(ctx)
+	before := approvalState(approval)
 	// 撤销
 	if model.ApprovalStatus(req.Status) == model.Cancel {
 		if req.ApprovalId != approval.UserId {
@@ -175,7 +233,11 @@ func (l *approval) Dispose(ctx context.Context, req *domain.DisposeReq) (err err
 
 		approval.Status = model.Cancel
 
-		return l.svcCtx.ApprovalModel.Update(ctx, approval)
+		if err = l.svcCtx.ApprovalModel.Update(ctx, approval); err != nil {
+			return err
+		}
+		l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceApproval, req.ApprovalId, before, approvalState(approval))
+		return nil
 	}
 
 	// 通过或拒绝
@@ -216,7 +278,32 @@ func (l *approval) Dispose(ctx context.Context, req *domain.DisposeReq) (err err
 		}
 	}
 
-	return l.svcCtx.ApprovalModel.Update(ctx, approval)
+	// 离职审批通过后登记离职交接，与审批结果在同一事务中提交，避免审批已通过却没有交接记录
+	err = mongox.Transaction(ctx, l.svcCtx.Mongo, func(ctx context.Context) error {
+		if err := l.svcCtx.ApprovalModel.Update(ctx, approval); err != nil {
+			return err
+		}
+		if approval.Status == model.Pass && approval.Type == model.DimissionApproval {
+			return l.offboarding.Start(ctx, approval)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	after := approvalState(approval)
+	after["reason"] = req.Reason
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceApproval, req.ApprovalId, before, after)
+	return nil
+}
+
+// approvalState 审批处理时记录审计日志的字段，审批人列表在处理时原地修改，只记录审批进度
+func approvalState(a *model.Approval) bson.M {
+	return bson.M{
+		"status":      a.Status,
+		"approvalId":  a.ApprovalId,
+		"approvalIdx": a.ApprovalIdx,
+	}
 }
 
 func (l *approval) List(ctx context.Context, req *domain.ApprovalListReq) (resp *domain.ApprovalListResp, err error) {
@@ -230,6 +317,9 @@ func (l *approval) List(ctx context.Context, req *domain.ApprovalListReq) (resp
 	for i, _ := range data {
 		list = append(list, data[i].ToDomainApprovalList())
 	}
+	if err = l.approvers(ctx, data, list); err != nil {
+		return nil, err
+	}
 
 	return &domain.ApprovalListResp{
 		List:  list,
@@ -237,6 +327,63 @@ func (l *approval) List(ctx context.Context, req *domain.ApprovalListReq) (resp
 	}, nil
 }
 
+// approvers 处理中的审批填写当前审批人及其在线状态
+func (l *approval) approvers(ctx context.Context, data []*model.Approval, list []*domain.ApprovalList) error {
+	var uids []string
+	for i := range data {
+		if data[i].Status == model.Processed && len(data[i].ApprovalId) > 0 {
+			uids = append(uids, data[i].ApprovalId)
+		}
+	}
+	if len(uids) == 0 {
+		return nil
+	}
+
+	users, err := l.svcCtx.UserModel.ListToMaps(ctx, &domain.UserListReq{Ids: uids})
+	if err != nil {
+		return err
+	}
+	presences, err := l.svcCtx.PresenceModel.ListToMaps(ctx, uids)
+	if err != nil {
+		return err
+	}
+	for i := range data {
+		u, ok := users[data[i].ApprovalId]
+		if data[i].Status != model.Processed || !ok {
+			continue
+		}
+		list[i].Approver = &domain.Approver{
+			UserId:   data[i].ApprovalId,
+			UserName: u.Name,
+			Presence: string(presences[data[i].ApprovalId].Status),
+			LastSeen: presences[data[i].ApprovalId].LastSeen,
+		}
+	}
+	return nil
+}
+
+// fileDepartment 确定审批提交的部门，未指定部门时使用用户的主部门
+func (l *approval) fileDepartment(ctx context.Context, uid, depId string) (*model.DepartmentUser, error) {
+	depUsers, err := l.svcCtx.DepartmentUserModel.ListByUserId(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if len(depUsers) == 0 {
+		return nil, errors.New("用户不属于任何部门，无法提交审批")
+	}
+
+	if len(depId) == 0 {
+		return depUsers[0], nil
+	}
+
+	for i := range depUsers {
+		if depUsers[i].DepId == depId {
+			return depUsers[i], nil
+		}
+	}
+	return nil, errors.New("用户不属于该部门，无法以该部门提交审批")
+}
+
 func (l *approval) newApproval(req *domain.Approval) *model.Approval {
 	return &model.Approval{
 		ID:     primitive.NewObjectID(),
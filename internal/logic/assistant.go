@@ -0,0 +1,166 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitee.com/dn-jinmin/tlog"
+)
+
+// mentionReg 消息中提到助手的方式
+var mentionReg = regexp.MustCompile(`(?i)@assistant|@助手`)
+
+// recentReg 提问中的最近一段时间，如“最近1小时”“last hour”，用于确定读取的聊天记录的范围
+var recentReg = regexp.MustCompile(`(?i)(?:last|past)\s*(\d*)\s*(minute|hour|day)s?|(?:最近|过去)\s*(\d*)\s*个?(分钟|小时|天)`)
+
+// Assistant 消息中提到助手时，以所在会话为上下文调用助手，助手的回复作为消息保存到会话中。
+// 返回助手的回复和需要接收回复的会话参与者，没有提到助手时回复为空
+func (l *chat) Assistant(ctx context.Context, req *domain.Message) (reply *domain.Message, uids []string, err error) {
+	prompts, ok := assistantPrompts(req)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	chatType, members, err := conversationMembers(ctx, l.svc, req.ConversationId)
+	if err != nil {
+		return nil, nil, err
+	}
+	background, err := l.assistantContext(ctx, req, chatType, members)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var content string
+	resp, err := l.AIChat(ctx, &domain.ChatReq{
+		Prompts:        background + prompts,
+		ConversationId: req.ConversationId,
+		StartTime:      recentStart(prompts, time.Now()),
+	})
+	if err != nil {
+		// 回复发送到会话中所有成员可见，不暴露内部错误
+		tlog.ErrorfCtx(ctx, "assistant", "ai chat fail %v, conversation %v", err.Error(), req.ConversationId)
+		content = "抱歉，处理失败，请稍后重试"
+	} else if content = assistantContent(resp); len(content) == 0 {
+		content = "抱歉，没有找到相关的内容"
+	}
+
+	data := &model.Chatlog{
+		ConversationId: req.ConversationId,
+		SendId:         model.AssistantId,
+		RecvId:         req.RecvId,
+		ChatType:       chatType,
+		ContentType:    model.TextContent,
+		MsgContent:     content,
+		SendTime:       time.Now().Unix(),
+	}
+	if err = l.svc.ChatlogModel.Insert(ctx, data); err != nil {
+		return nil, nil, err
+	}
+	if err = l.dispatch(ctx, data, members); err != nil {
+		return nil, nil, err
+	}
+	return data.ToDomain(), members, nil
+}
+
+// assistantContext 会话的背景信息，便于助手确定会话的参与者，如为会话中的所有人创建待办
+func (l *chat) assistantContext(ctx context.Context, req *domain.Message, chatType model.ChatType,
+	members []string) (string, error) {
+	users, err := l.svc.UserModel.ListToMaps(ctx, &domain.UserListReq{Ids: members})
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(members))
+	for _, uid := range members {
+		if u, ok := users[uid]; ok {
+			names = append(names, fmt.Sprintf("%s(%s)", u.Name, uid))
+		}
+	}
+
+	var where string
+	if chatType == model.GroupChatType {
+		g, err := findGroup(ctx, l.svc, req.ConversationId)
+		if err != nil {
+			return "", err
+		}
+		where = fmt.Sprintf("群聊「%s」", g.Name)
+	} else {
+		where = "私聊"
+	}
+
+	var sender string
+	if u, ok := users[req.SendId]; ok {
+		sender = fmt.Sprintf("%s(%s)", u.Name, req.SendId)
+	}
+	return fmt.Sprintf("当前在%s中，会话的参与者有：%s；提问的是%s。\n", where, strings.Join(names, "、"), sender), nil
+}
+
+// MentionAssistant 消息是否为提到助手的提问
+func MentionAssistant(req *domain.Message) bool {
+	_, ok := assistantPrompts(req)
+	return ok
+}
+
+// assistantPrompts 文本消息中提到助手时，去掉提到助手的部分作为提问
+func assistantPrompts(req *domain.Message) (string, bool) {
+	if model.ContentType(req.ContentType) != model.TextContent || len(req.Event) > 0 {
+		return "", false
+	}
+
+	if !mentionReg.MatchString(req.Content) {
+		return "", false
+	}
+	content := strings.TrimSpace(mentionReg.ReplaceAllString(req.Content, ""))
+	return content, len(content) > 0
+}
+
+// recentStart 根据提问中的最近一段时间确定开始时间，没有时为0
+func recentStart(prompts string, now time.Time) int64 {
+	m := recentReg.FindStringSubmatch(prompts)
+	if m == nil {
+		return 0
+	}
+
+	num, unit := m[1], m[2]
+	if len(unit) == 0 {
+		num, unit = m[3], m[4]
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil || n <= 0 {
+		n = 1
+	}
+
+	var d time.Duration
+	switch strings.ToLower(unit) {
+	case "minute", "分钟":
+		d = time.Minute
+	case "hour", "小时":
+		d = time.Hour
+	default:
+		d = 24 * time.Hour
+	}
+	return now.Add(-time.Duration(n) * d).Unix()
+}
+
+// assistantContent 助手的回复作为文本消息的内容，结构化的结果转为JSON
+func assistantContent(resp *domain.ChatResp) string {
+	if resp == nil {
+		return ""
+	}
+	if s, ok := resp.Data.(string); ok {
+		return s
+	}
+
+	b, err := json.Marshal(resp.Data)
+	if err != nil {
+		return fmt.Sprint(resp.Data)
+	}
+	return string(b)
+}
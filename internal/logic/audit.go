@@ -0,0 +1,101 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"context"
+	"time"
+
+	"gitee.com/dn-jinmin/tlog"
+)
+
+const (
+	// auditCleanInterval 清理过期审计日志的间隔
+	auditCleanInterval = 24 * time.Hour
+	// defaultAuditRetentionDays 未配置时审计日志的保留天数
+	defaultAuditRetentionDays = 180
+)
+
+// Audit 审计日志的查询与按保留期限清理
+type Audit interface {
+	List(ctx context.Context, req *domain.AuditListReq) (resp *domain.AuditListResp, err error)
+	// Run 定时清理超过保留期限的审计日志，直到ctx结束
+	Run(ctx context.Context)
+}
+
+type audit struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewAudit(svcCtx *svc.ServiceContext) Audit {
+	return &audit{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *audit) List(ctx context.Context, req *domain.AuditListReq) (resp *domain.AuditListResp, err error) {
+	logs, count, err := l.svcCtx.AuditLogModel.List(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]*model.User)
+	uids := make([]string, 0, len(logs))
+	for i := range logs {
+		if len(logs[i].ActorId) > 0 {
+			uids = append(uids, logs[i].ActorId)
+		}
+	}
+	if len(uids) > 0 {
+		if users, err = l.svcCtx.UserModel.ListToMaps(ctx, &domain.UserListReq{Ids: uids}); err != nil {
+			return nil, err
+		}
+	}
+
+	list := make([]*domain.AuditLog, 0, len(logs))
+	for i := range logs {
+		item := logs[i].ToDomain()
+		if u, ok := users[logs[i].ActorId]; ok {
+			item.ActorName = u.Name
+		}
+		list = append(list, item)
+	}
+	return &domain.AuditListResp{
+		Count: count,
+		List:  list,
+	}, nil
+}
+
+func (l *audit) Run(ctx context.Context) {
+	ticker := time.NewTicker(auditCleanInterval)
+	defer ticker.Stop()
+
+	for {
+		l.clean(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// clean 删除超过保留期限的审计日志
+func (l *audit) clean(ctx context.Context) {
+	days := l.svcCtx.Config.Audit.RetentionDays
+	if days <= 0 {
+		days = defaultAuditRetentionDays
+	}
+
+	before := time.Now().AddDate(0, 0, -days).Unix()
+	n, err := l.svcCtx.AuditLogModel.DeleteBefore(ctx, before)
+	if err != nil {
+		tlog.ErrorfCtx(ctx, "audit", "clean fail %v", err)
+		return
+	}
+	if n > 0 {
+		tlog.InfoCtx(ctx, "audit clean", n)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"ai/internal/logic/chatinternal"
 	"ai/internal/model"
 	"ai/pkg/langchain"
+	"ai/pkg/langchain/callbackx"
 	"ai/pkg/langchain/memoryx"
 	"ai/pkg/langchain/router"
 	"ai/pkg/langchain/voice"
@@ -12,10 +13,15 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/tmc/langchaingo/schema"
 
@@ -30,23 +36,55 @@ import (
 type Chat interface {
 	PrivateChat(ctx context.Context, req *domain.Message) error
 	GroupChat(ctx context.Context, req *domain.Message) (uids []string, err error)
+	// Delivered 消息已送达接收者的设备
+	Delivered(ctx context.Context, msgId string, uids []string) error
+	// Offline 当前用户离线期间未送达的消息
+	Offline(ctx context.Context) ([]*domain.Message, error)
+	// Read 当前用户读到会话中的消息，返回需要发送已读回执的发送者
+	Read(ctx context.Context, req *domain.Message) (sendIds []string, err error)
+	// Assistant 消息中提到助手时调用助手，返回助手在会话中的回复和需要接收回复的参与者
+	Assistant(ctx context.Context, req *domain.Message) (reply *domain.Message, uids []string, err error)
+	// Recall 撤回消息，返回需要通知的会话参与者
+	Recall(ctx context.Context, req *domain.Message) (uids []string, err error)
+	// Edit 编辑文本消息，返回需要通知的会话参与者
+	Edit(ctx context.Context, req *domain.Message) (uids []string, err error)
+	// DeleteMessage 删除消息，为所有人删除时通知会话参与者，否则只通知自己的其它设备
+	DeleteMessage(ctx context.Context, req *domain.Message) (uids []string, err error)
 	AIChat(ctx context.Context, req *domain.ChatReq) (resp *domain.ChatResp, err error)
+	// AIChatStream 流式对话，依次推送处理进度和回复的部分文本，最后推送完整的回复
+	AIChatStream(ctx context.Context, req *domain.ChatReq, emit func(ev *domain.ChatStream)) error
 	File(ctx context.Context, req []*domain.FileResp) (err error)
 }
 
 type chat struct {
-	svc    *svc.ServiceContext
-	memory schema.Memory
-	router *router.Router
-	voice  *voice.Voice
+	svc *svc.ServiceContext
+
+	mu      sync.Mutex
+	engines map[string]*chatEngine // 按租户ID区分，租户使用各自的大模型和知识库
+}
+
+// chatEngine 租户的AI对话，租户配置修改后重新创建
+type chatEngine struct {
+	updateAt int64
+	memory   schema.Memory
+	router   *router.Router
+	voice    *voice.Voice
 }
 
 func NewChat(svc *svc.ServiceContext) Chat {
+	return &chat{
+		svc:     svc,
+		engines: make(map[string]*chatEngine),
+	}
+}
+
+func newChatEngine(svc *svc.ServiceContext) *chatEngine {
 	handlers := []router.Handler{
 		chatinternal.NewTodoHandle(svc),
 		chatinternal.NewKnowledge(svc),
 		chatinternal.NewApprovalHandle(svc),
 		chatinternal.NewChatLogHandle(svc),
+		chatinternal.NewUserHandle(svc),
 	}
 
 	memory := memoryx.NewMemoryx(func() schema.Memory {
@@ -56,8 +94,7 @@ func NewChat(svc *svc.ServiceContext) Chat {
 		m.InputKey = langchain.Input
 		return m
 	})
-	return &chat{
-		svc:    svc,
+	return &chatEngine{
 		memory: memory,
 		voice:  voice.NewVoice(svc.OpenaiClient),
 		router: router.NewRouter(svc.LLMs, handlers,
@@ -68,20 +105,317 @@ func NewChat(svc *svc.ServiceContext) Chat {
 	}
 }
 
+// engine 获取当前租户的AI对话
+func (l *chat) engine(ctx context.Context) (*chatEngine, error) {
+	t, err := l.svc.CurrentTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var updateAt int64
+	if t != nil {
+		updateAt = t.UpdateAt
+	}
+
+	tid := token.GetTenantId(ctx)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.engines[tid]; ok && e.updateAt == updateAt {
+		return e, nil
+	}
+
+	svcCtx, err := l.svc.WithTenant(t)
+	if err != nil {
+		return nil, err
+	}
+	e := newChatEngine(svcCtx)
+	e.updateAt = updateAt
+	l.engines[tid] = e
+	return e, nil
+}
+
+// PrivateChat 私聊的会话ID由双方的用户ID生成，客户端传递其他会话ID时拒绝，接收者需要是同一租户的用户
 func (l *chat) PrivateChat(ctx context.Context, req *domain.Message) error {
-	return l.chatlog(ctx, req)
+	conversationId := GenerateUniqueID(req.SendId, req.RecvId)
+	if len(req.ConversationId) > 0 && req.ConversationId != conversationId {
+		return ErrConversationMismatch
+	}
+	req.ConversationId = conversationId
+
+	if _, err := l.svc.UserModel.FindOne(ctx, req.RecvId); err != nil {
+		if errors.Is(err, model.ErrNotFound) || errors.Is(err, model.ErrInvalidObjectId) {
+			return ErrRecvNotFound
+		}
+		return err
+	}
+
+	data, err := l.chatlog(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	uids := []string{req.SendId}
+	if req.RecvId != req.SendId {
+		uids = append(uids, req.RecvId)
+	}
+	return l.dispatch(ctx, data, uids)
 }
 
+// GroupChat 群聊消息以群ID作为会话ID保存，返回需要接收消息的群成员
 func (l *chat) GroupChat(ctx context.Context, req *domain.Message) (uids []string, err error) {
-	req.ConversationId = "all"
-	if err := l.chatlog(ctx, req); err != nil {
+	gid := req.ConversationId
+	if len(gid) == 0 {
+		gid = req.RecvId
+	}
+
+	g, err := findGroup(ctx, l.svc, gid)
+	if err != nil {
 		return nil, err
 	}
+	if !g.IsMember(req.SendId) {
+		return nil, ErrNotGroupMember
+	}
 
-	return nil, err
+	req.ConversationId = g.ID.Hex()
+	req.RecvId = g.ID.Hex()
+	data, err := l.chatlog(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err = l.dispatch(ctx, data, g.MemberIds); err != nil {
+		return nil, err
+	}
+
+	return g.MemberIds, nil
+}
+
+func (l *chat) Delivered(ctx context.Context, msgId string, uids []string) error {
+	for _, uid := range uids {
+		if err := l.svc.DeliveryModel.SetDelivered(ctx, uid, []string{msgId}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *chat) Offline(ctx context.Context) ([]*domain.Message, error) {
+	deliveries, err := l.svc.DeliveryModel.ListPending(ctx, token.GetUId(ctx))
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(deliveries))
+	for i := range deliveries {
+		ids = append(ids, deliveries[i].MsgId)
+	}
+	chatlogs, err := l.svc.ChatlogModel.ListByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*domain.Message, 0, len(chatlogs))
+	for i := range chatlogs {
+		res = append(res, chatlogs[i].ToDomain())
+	}
+	return res, nil
+}
+
+// Read 将会话中不晚于该消息的未读消息标记为已读，并清空会话的未读数
+func (l *chat) Read(ctx context.Context, req *domain.Message) (sendIds []string, err error) {
+	data, err := l.svc.ChatlogModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if data.ConversationId != req.ConversationId {
+		return nil, errors.New("消息不属于该会话")
+	}
+
+	uid := token.GetUId(ctx)
+	unread, err := l.svc.DeliveryModel.ListUnread(ctx, uid, data.ConversationId, data.SendTime)
+	if err != nil {
+		return nil, err
+	}
+	if err = l.svc.DeliveryModel.SetRead(ctx, uid, data.ConversationId, data.SendTime); err != nil {
+		return nil, err
+	}
+	if err = l.svc.ConversationModel.Read(ctx, uid, data.ConversationId, req.Id); err != nil {
+		return nil, err
+	}
+
+	for i := range unread {
+		if !slices.Contains(sendIds, unread[i].SendId) {
+			sendIds = append(sendIds, unread[i].SendId)
+		}
+	}
+	return sendIds, nil
+}
+
+// defaultRecallWindow 未配置时消息发送后允许撤回的时长（秒）
+const defaultRecallWindow = 120
+
+var (
+	ErrNotMessageSender = errors.New("只能操作自己发送的消息")
+	ErrRecallExpired    = errors.New("消息已超过可撤回的时间")
+	ErrMessageWithdrawn = errors.New("消息已撤回或已删除")
+	ErrEditNotText      = errors.New("只能编辑文本消息")
+
+	ErrConversationMismatch = errors.New("会话与接收者不符")
+	ErrRecvNotFound         = errors.New("接收者不存在")
+)
+
+// Recall 发送者在时限内撤回消息，撤回后消息内容清除
+func (l *chat) Recall(ctx context.Context, req *domain.Message) (uids []string, err error) {
+	data, err := l.ownMessage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	window := l.svc.Config.Chat.RecallWindow
+	if window <= 0 {
+		window = defaultRecallWindow
+	}
+	if time.Now().Unix()-data.SendTime > window {
+		return nil, ErrRecallExpired
+	}
+
+	if err = l.svc.ChatlogModel.Recall(ctx, req.Id); err != nil {
+		return nil, err
+	}
+	return l.notify(ctx, req)
+}
+
+// Edit 发送者编辑文本消息，保留编辑前的内容
+func (l *chat) Edit(ctx context.Context, req *domain.Message) (uids []string, err error) {
+	data, err := l.ownMessage(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if data.ContentType != model.TextContent {
+		return nil, ErrEditNotText
+	}
+	if len(strings.TrimSpace(req.Content)) == 0 {
+		return nil, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(req.Content) > maxTextLength {
+		return nil, ErrMessageTooLong
+	}
+
+	prev := &model.ChatlogEdit{
+		Content: data.MsgContent,
+		EditAt:  data.EditAt,
+	}
+	if prev.EditAt == 0 {
+		prev.EditAt = data.SendTime
+	}
+	err = l.svc.ChatlogModel.Edit(ctx, req.Id, req.Content, prev)
+	if errors.Is(err, model.ErrChatlogWithdrawn) {
+		return nil, ErrMessageWithdrawn
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l.notify(ctx, req)
+}
+
+// DeleteMessage forEveryone 为true时由发送者为所有人删除，否则会话参与者只为自己删除
+func (l *chat) DeleteMessage(ctx context.Context, req *domain.Message) (uids []string, err error) {
+	if req.ForEveryone {
+		if _, err = l.ownMessage(ctx, req); err != nil {
+			return nil, err
+		}
+		if err = l.svc.ChatlogModel.DeleteForAll(ctx, req.Id); err != nil {
+			return nil, err
+		}
+		return l.notify(ctx, req)
+	}
+
+	uid := token.GetUId(ctx)
+	data, err := l.svc.ChatlogModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, members, err := conversationMembers(ctx, l.svc, data.ConversationId)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(members, uid) {
+		return nil, ErrNotConversationMember
+	}
+	if err = l.svc.ChatlogModel.DeleteFor(ctx, req.Id, uid); err != nil {
+		return nil, err
+	}
+
+	req.ConversationId = data.ConversationId
+	return []string{uid}, nil
+}
+
+// ownMessage 获取当前用户发送的、未撤回或删除的消息
+func (l *chat) ownMessage(ctx context.Context, req *domain.Message) (*model.Chatlog, error) {
+	data, err := l.svc.ChatlogModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if data.SendId != token.GetUId(ctx) {
+		return nil, ErrNotMessageSender
+	}
+	if data.Withdrawn() {
+		return nil, ErrMessageWithdrawn
+	}
+	return data, nil
 }
 
-func (l *chat) chatlog(ctx context.Context, req *domain.Message) error {
+// notify 以修改后的消息回填请求，返回会话的所有参与者
+func (l *chat) notify(ctx context.Context, req *domain.Message) ([]string, error) {
+	data, err := l.svc.ChatlogModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, uids, err := conversationMembers(ctx, l.svc, data.ConversationId)
+	if err != nil {
+		return nil, err
+	}
+
+	event, forEveryone := req.Event, req.ForEveryone
+	*req = *data.ToDomain()
+	req.Event = event
+	req.ForEveryone = forEveryone
+	return uids, nil
+}
+
+// dispatch 更新会话参与者的会话列表，并为发送者以外的参与者记录待送达的消息
+func (l *chat) dispatch(ctx context.Context, data *model.Chatlog, uids []string) error {
+	deliveries := make([]*model.Delivery, 0, len(uids))
+	for _, uid := range uids {
+		// 私聊的会话对方为另一个参与者，群聊为群；助手的回复不改变私聊的对方
+		peerId := data.RecvId
+		if data.ChatType == model.SingleChatType {
+			peerId = uid
+			for _, id := range uids {
+				if id != uid {
+					peerId = id
+				}
+			}
+		}
+
+		unread := uid != data.SendId
+		if err := l.svc.ConversationModel.Touch(ctx, uid, data, peerId, unread); err != nil {
+			return err
+		}
+		if unread {
+			deliveries = append(deliveries, &model.Delivery{
+				MsgId:          data.ID.Hex(),
+				ConversationId: data.ConversationId,
+				SendId:         data.SendId,
+				UserId:         uid,
+				SendTime:       data.SendTime,
+				Status:         model.DeliveryPending,
+			})
+		}
+	}
+	return l.svc.DeliveryModel.Inserts(ctx, deliveries)
+}
+
+// chatlog 保存消息，并回填消息ID和发送时间
+func (l *chat) chatlog(ctx context.Context, req *domain.Message) (*model.Chatlog, error) {
 	sendId := req.SendId
 
 	chatlog := model.Chatlog{
@@ -89,27 +423,55 @@ func (l *chat) chatlog(ctx context.Context, req *domain.Message) error {
 		SendId:         sendId,
 		RecvId:         req.RecvId,
 		ChatType:       model.ChatType(req.ChatType),
-		MsgContent:     req.Content,
 		SendTime:       time.Now().Unix(),
 	}
+	if err := messageContent(ctx, l.svc, req, &chatlog); err != nil {
+		return nil, err
+	}
 
 	if chatlog.ConversationId == "" {
 		chatlog.ConversationId = GenerateUniqueID(sendId, req.RecvId)
 	}
 
-	return l.svc.ChatlogModel.Insert(ctx, &chatlog)
+	if err := l.svc.ChatlogModel.Insert(ctx, &chatlog); err != nil {
+		return nil, err
+	}
+	// 推送给接收者的消息使用保存后的内容，如生成的卡片
+	msg := chatlog.ToDomain()
+	req.Id = msg.Id
+	req.ConversationId = msg.ConversationId
+	req.SendTime = msg.SendTime
+	req.Attachment = msg.Attachment
+	req.Card = msg.Card
+	return &chatlog, nil
 }
 
 func (l *chat) AIChat(ctx context.Context, req *domain.ChatReq) (resp *domain.ChatResp, err error) {
-	uid := token.GetUId(ctx)
-	ctx = context.WithValue(ctx, langchain.ChatId, uid)
+	// 在会话中使用时，对话的上下文按会话区分
+	chatId := token.GetUId(ctx)
+	if len(req.ConversationId) > 0 {
+		_, members, err := conversationMembers(ctx, l.svc, req.ConversationId)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(members, chatId) {
+			return nil, ErrNotConversationMember
+		}
+		chatId = req.ConversationId
+	}
+	ctx = context.WithValue(ctx, langchain.ChatId, chatId)
 
 	if req.ChatType > 0 {
 		return l.basicService(ctx, req)
 	}
 
+	e, err := l.engine(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(req.Prompts) == 0 && len(req.Voice) > 0 {
-		prompts, err := l.voice.Transcriptions(ctx, req.Voice, "")
+		prompts, err := e.voice.Transcriptions(ctx, req.Voice, "")
 		if err != nil {
 			return nil, err
 		}
@@ -117,13 +479,37 @@ func (l *chat) AIChat(ctx context.Context, req *domain.ChatReq) (resp *domain.Ch
 		req.Prompts = prompts
 	}
 
-	return l.aiService(ctx, req)
+	return l.aiService(ctx, e, req)
 }
 
-func (l *chat) aiService(ctx context.Context, req *domain.ChatReq) (resp *domain.ChatResp, err error) {
-	v, err := chains.Call(ctx, l.router, map[string]any{
+// AIChatStream 路由和工具调用的进度由回调推送，默认处理器和知识库问答生成回复时推送部分文本。
+// 部分文本只用于展示，结束时的 done 事件以完整的回复为准
+func (l *chat) AIChatStream(ctx context.Context, req *domain.ChatReq, emit func(ev *domain.ChatStream)) error {
+	ctx = callbackx.WithStreamer(ctx, func(_ context.Context, ev callbackx.StreamEvent) {
+		emit(&domain.ChatStream{
+			Type: ev.Type,
+			Step: ev.Step,
+			Name: ev.Name,
+			Text: ev.Text,
+		})
+	})
+
+	resp, err := l.AIChat(ctx, req)
+	if err != nil {
+		return err
+	}
+	emit(&domain.ChatStream{Type: domain.ChatStreamDone, Resp: resp})
+	return nil
+}
+
+func (l *chat) aiService(ctx context.Context, e *chatEngine, req *domain.ChatReq) (resp *domain.ChatResp, err error) {
+	var relationId any = req.RelationId
+	if len(req.ConversationId) > 0 {
+		relationId = req.ConversationId
+	}
+	v, err := chains.Call(ctx, e.router, map[string]any{
 		langchain.Input: req.Prompts,
-		"relationId":    req.RelationId,
+		"relationId":    relationId,
 		"startTime":     req.StartTime,
 		"endTime":       req.EndTime,
 	}, chains.WithCallback(l.svc.Callbacks))
@@ -211,14 +597,18 @@ func (l *chat) File(ctx context.Context, files []*domain.FileResp) (err error) {
 		return err
 	}
 
-	err = l.memory.SaveContext(ctx, map[string]any{
+	e, err := l.engine(ctx)
+	if err != nil {
+		return err
+	}
+	err = e.memory.SaveContext(ctx, map[string]any{
 		langchain.Input: string(b),
 	}, map[string]any{
 		langchain.Input: "uploaded files",
 	})
 
 	// test
-	memoryContent, err := l.memory.LoadMemoryVariables(ctx, map[string]any{})
+	memoryContent, err := e.memory.LoadMemoryVariables(ctx, map[string]any{})
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package chatinternal
 
 import (
+	"ai/internal/model"
 	"ai/internal/svc"
 	"ai/pkg/langchain"
 	"context"
@@ -18,12 +19,42 @@ type baseChat struct {
 }
 
 func NewBaseChat(svc *svc.ServiceContext, tools []tools.Tool) *baseChat {
+	// 工具调用都记录审计日志
+	for i := range tools {
+		tools[i] = &auditTool{Tool: tools[i], svc: svc}
+	}
+
 	return &baseChat{
 		// 创建代理执行链：
 		// 1. 使用一次性代理(OneShotAgent)
 		// 2. 传入LLM服务、可用工具和默认提示前缀
-		agentsChain: agents.NewExecutor(agents.NewOneShotAgent(svc.LLMs, tools, agents.WithPromptPrefix(_defaultMrklPrefix))),
+		// 3. 执行器的回调推送调用工具的进度
+		agentsChain: agents.NewExecutor(agents.NewOneShotAgent(svc.LLMs, tools, agents.WithPromptPrefix(_defaultMrklPrefix)),
+			agents.WithCallbacksHandler(svc.Callbacks)),
+	}
+}
+
+// auditTool 记录AI对话中工具调用的审计日志
+type auditTool struct {
+	tools.Tool
+	svc *svc.ServiceContext
+}
+
+// toolInvocation 审计日志中记录的工具调用
+type toolInvocation struct {
+	Input string `bson:"input"`
+	Error string `bson:"error,omitempty"`
+}
+
+func (t *auditTool) Call(ctx context.Context, input string) (string, error) {
+	res, err := t.Tool.Call(ctx, input)
+
+	invocation := &toolInvocation{Input: input}
+	if err != nil {
+		invocation.Error = err.Error()
 	}
+	t.svc.Audit(ctx, model.ActionInvoke, model.ResourceChat, t.Name(), nil, invocation)
+	return res, err
 }
 
 // Chains 返回当前处理器的链式处理器
@@ -129,6 +129,10 @@ func (c *ChatLogHandle) chatLog(ctx context.Context, cid string, startTime, endT
 	// 4. 遍历聊天记录，拼接文本并缓存用户信息
 	for i, _ := range list {
 		var u *model.User
+		if list[i].SendId == model.AssistantId {
+			res.Write([]byte(fmt.Sprintf(chatStr, "助手", model.AssistantId, list[i].Summary())))
+			continue
+		}
 		if v, ok := record[list[i].SendId]; ok {
 			u = v
 		} else {
@@ -141,8 +145,8 @@ func (c *ChatLogHandle) chatLog(ctx context.Context, cid string, startTime, endT
 			record[list[i].SendId] = t
 		}
 
-		// 5. 按模板拼接当前聊天记录，写入字符串构建器
-		res.Write([]byte(fmt.Sprintf(chatStr, u.Name, u.ID.Hex(), list[i].MsgContent)))
+		// 5. 按模板拼接当前聊天记录，写入字符串构建器；图片、文件、语音和卡片使用文本描述
+		res.Write([]byte(fmt.Sprintf(chatStr, u.Name, u.ID.Hex(), list[i].Summary())))
 	}
 
 	// 6. 返回拼接完成的聊天记录文本
@@ -157,16 +161,16 @@ func setTimeRange(input map[string]any) (int64, int64) {
 	cuurentTime := time.Now().Unix()
 
 	// 处理startTime：若输入有合法的startTime（int类型且>0），则使用；否则默认“24小时前”
-	if start, ok := input["startTime"].(int); ok && start > 0 {
-		startTime = int64(start)
+	if start := timeInput(input["startTime"]); start > 0 {
+		startTime = start
 	} else {
 		// 获取前一天的消息
 		startTime = cuurentTime - 24*3600
 	}
 
 	// 处理endTime：若输入有合法的endTime（int类型且>0），则使用；否则默认“当前时间”
-	if end, ok := input["endTime"].(int); ok && end > 0 {
-		endTime = int64(end)
+	if end := timeInput(input["endTime"]); end > 0 {
+		endTime = end
 	} else {
 		// 获取前一天的消息
 		endTime = cuurentTime
@@ -174,3 +178,14 @@ func setTimeRange(input map[string]any) (int64, int64) {
 
 	return startTime, endTime
 }
+
+// timeInput 输入的时间戳，对话接口传入的为int64
+func timeInput(v any) int64 {
+	switch t := v.(type) {
+	case int:
+		return int64(t)
+	case int64:
+		return t
+	}
+	return 0
+}
@@ -4,6 +4,8 @@ import (
 	"ai/internal/domain"
 	"ai/internal/svc"
 	"ai/pkg/langchain"
+	"ai/pkg/langchain/callbackx"
+	"context"
 	"fmt"
 
 	"github.com/tmc/langchaingo/chains"
@@ -51,5 +53,11 @@ func (d *DefaultHandler) Description() string {
 
 // Chains 返回默认处理器关联的LLM链，供路由系统调用以处理用户问题
 func (d *DefaultHandler) Chains() chains.Chain {
-	return d.c
+	return chains.NewTransform(d.transform, nil, nil)
+}
+
+// transform 调用LLM链，流式对话时推送回复中 data 字段的文本
+func (d *DefaultHandler) transform(ctx context.Context, inputs map[string]any,
+	opts ...chains.ChainCallOption) (map[string]any, error) {
+	return chains.Call(ctx, d.c, inputs, append(opts, callbackx.StreamOptions(ctx, true)...)...)
 }
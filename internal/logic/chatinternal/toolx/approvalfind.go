@@ -47,7 +47,8 @@ func (a *ApprovalFind) Name() string {
 func (a *ApprovalFind) Description() string {
 	return `
 	a approval find interface.
-	use when you need to find a approval.
+	use when you need to find a approval, or whether its current approver is online
+	(approver.presence: online, away, offline).
 	If the condition is null, return {}
  	keep Chinese output.` + a.outputparser.GetFormatInstructions()
 }
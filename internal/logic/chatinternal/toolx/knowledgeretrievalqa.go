@@ -1,7 +1,9 @@
 package toolx
 
 import (
+	"ai/internal/model"
 	"ai/internal/svc"
+	"ai/pkg/langchain/callbackx"
 	"context"
 
 	"github.com/tmc/langchaingo/vectorstores"
@@ -37,6 +39,11 @@ keep Chinese output.`
 // input: 用户的查询字符串（问题）
 // 返回值: 检索到的答案字符串和可能的错误
 func (k *KnowledgeRetrievalQA) Call(ctx context.Context, input string) (string, error) {
+	// 权限验证：检查当前用户是否有权限查询知识库
+	if err := k.svc.Authorize(ctx, model.ResourceKnowledge, model.ActionRead, ""); err != nil {
+		return "", err
+	}
+
 	var err error
 	// 初始化问答链（如果尚未初始化）
 	if k.qa == nil {
@@ -53,10 +60,10 @@ func (k *KnowledgeRetrievalQA) Call(ctx context.Context, input string) (string,
 	}
 
 	// 调用问答链处理用户查询
-	// 传入查询参数（"query"字段对应用户输入）
+	// 传入查询参数（"query"字段对应用户输入），流式对话时推送生成的回答
 	res, err := chains.Predict(ctx, k.qa, map[string]any{
 		"query": input,
-	})
+	}, callbackx.StreamOptions(ctx, false)...)
 	if err != nil {
 		return "", err
 	}
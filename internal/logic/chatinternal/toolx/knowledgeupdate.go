@@ -1,6 +1,7 @@
 package toolx
 
 import (
+	"ai/internal/model"
 	"ai/internal/svc"
 	"ai/pkg/langchain/outputparserx"
 	"context"
@@ -58,7 +59,7 @@ your output should be in the following json format.
 // Call 执行知识库更新操作
 func (k *KnowledgeUpdate) Call(ctx context.Context, input string) (string, error) {
 	// 权限验证：检查当前上下文是否有权限执行知识库更新操作
-	if err := k.svc.Auth(ctx); err != nil {
+	if err := k.svc.Authorize(ctx, model.ResourceKnowledge, model.ActionUpdate, ""); err != nil {
 		return "", err
 	}
 
@@ -114,6 +115,11 @@ func (k *KnowledgeUpdate) Call(ctx context.Context, input string) (string, error
 	if err != nil {
 		return "", err
 	}
+	k.svc.Audit(ctx, model.ActionUpdate, model.ResourceKnowledge, k.svc.KnowledgeIndex(), nil, map[string]any{
+		"name":   file["name"],
+		"path":   file["path"],
+		"chunks": len(chunkedDocuments),
+	})
 
 	return Success, nil
 }
@@ -129,8 +135,8 @@ func getKnowledgeStore(ctx context.Context, svc *svc.ServiceContext) (*redisvect
 	// 创建并返回Redis向量存储实例：
 	// - 使用前面创建的嵌入器
 	// - 连接到配置中指定的Redis服务
-	// - 指定索引名称为"knowledge"，并设置为可创建（不存在则创建）
+	// - 指定索引名称为租户的知识库索引，并设置为可创建（不存在则创建）
 	return redisvector.New(ctx, redisvector.WithEmbedder(embedder), redisvector.WithConnectionURL("redis://"+svc.Config.
 		Redis.
-		Addr), redisvector.WithIndexName("knowledge", true))
+		Addr), redisvector.WithIndexName(svc.KnowledgeIndex(), true))
 }
@@ -0,0 +1,83 @@
+package toolx
+
+import (
+	"ai/internal/domain"
+	"ai/internal/svc"
+	"ai/pkg/curl"
+	"ai/pkg/langchain/outputparserx"
+	"ai/token"
+	"context"
+
+	"github.com/tmc/langchaingo/callbacks"
+)
+
+// UserFind 人员通讯录查询工具，可查询员工的部门、职位、工作地点以及直属上级
+type UserFind struct {
+	svc          *svc.ServiceContext
+	callback     callbacks.Handler
+	outputparser outputparserx.Structured
+}
+
+func NewUserFind(svc *svc.ServiceContext) *UserFind {
+	return &UserFind{
+		svc:      svc,
+		callback: svc.Callbacks,
+		outputparser: outputparserx.NewStructured([]outputparserx.ResponseSchema{
+			{
+				Name:        "keyword",
+				Description: "person name, employee number, email, phone or job title, such as 张三 or IT support. none is empty",
+				Type:        "string",
+			},
+			{
+				Name:        "depName",
+				Description: "department name, such as IT. none is empty",
+				Type:        "string",
+			},
+			{
+				Name:        "workLocation",
+				Description: "work location, such as 上海. none is empty",
+				Type:        "string",
+			},
+		}),
+	}
+}
+
+func (u *UserFind) Name() string {
+	return "user_find"
+}
+
+func (u *UserFind) Description() string {
+	return `
+	a people directory interface.
+	use when you need to find a colleague, their department, position, job title, work location, manager,
+	or whether they are online (presence: online, away, offline; lastSeen is the last online time).
+	such as "who is 张三's manager", "who handles IT in 上海" or "is 张三 online".
+	If the condition is null, return {}
+	keep Chinese output.` + u.outputparser.GetFormatInstructions()
+}
+
+// Call 执行人员查询操作
+func (u *UserFind) Call(ctx context.Context, input string) (string, error) {
+	if u.callback != nil {
+		u.callback.HandleText(ctx, "user find start : "+input)
+	}
+
+	out, err := u.outputparser.Parse(input)
+	if err != nil {
+		return "", err
+	}
+
+	params := make(map[string]any)
+	if data, ok := out.(map[string]any); ok {
+		for _, key := range []string{"keyword", "depName", "workLocation"} {
+			if v, ok := data[key].(string); ok && len(v) > 0 {
+				params[key] = v
+			}
+		}
+	}
+	params["count"] = 10
+
+	res, err := curl.GetRequest(token.GetTokenStr(ctx), u.svc.Config.Host+"/v1/user/list", params)
+
+	return ResParser(res, domain.UserFind, err)
+}
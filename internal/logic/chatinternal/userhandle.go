@@ -0,0 +1,28 @@
+package chatinternal
+
+import (
+	"ai/internal/logic/chatinternal/toolx"
+	"ai/internal/svc"
+
+	"github.com/tmc/langchaingo/tools"
+)
+
+type UserHandle struct {
+	*baseChat
+}
+
+func NewUserHandle(svc *svc.ServiceContext) *UserHandle {
+	return &UserHandle{
+		baseChat: NewBaseChat(svc, []tools.Tool{
+			toolx.NewUserFind(svc),
+		}),
+	}
+}
+
+func (t *UserHandle) Name() string {
+	return "user"
+}
+
+func (t *UserHandle) Description() string {
+	return "suitable for the company people directory, such as finding colleagues, their department, job title, work location, who their manager is and whether they are online"
+}
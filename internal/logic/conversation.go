@@ -0,0 +1,212 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/token"
+	"context"
+	"errors"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultMessageCount = 20
+	maxMessageCount     = 100
+)
+
+var (
+	ErrConversationNotFound  = errors.New("不存在该会话")
+	ErrNotConversationMember = errors.New("不是该会话的参与者")
+)
+
+// Conversation 当前用户的会话列表及会话中的历史消息
+type Conversation interface {
+	List(ctx context.Context, req *domain.ConversationListReq) (resp *domain.ConversationListResp, err error)
+	Messages(ctx context.Context, req *domain.ConversationMessagesReq) (resp *domain.ConversationMessagesResp, err error)
+}
+
+type conversation struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewConversation(svcCtx *svc.ServiceContext) Conversation {
+	return &conversation{
+		svcCtx: svcCtx,
+	}
+}
+
+// List 会话列表，带有最后一条消息、对方用户或群的名称以及未读数
+func (l *conversation) List(ctx context.Context, req *domain.ConversationListReq) (resp *domain.ConversationListResp, err error) {
+	convs, count, err := l.svcCtx.ConversationModel.ListByUser(ctx, token.GetUId(ctx), req.Page, req.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	var msgIds, uids, gids []string
+	for _, c := range convs {
+		if len(c.LastMsgId) > 0 {
+			msgIds = append(msgIds, c.LastMsgId)
+		}
+		if c.ChatType == model.GroupChatType {
+			gids = append(gids, c.PeerId)
+		} else {
+			uids = append(uids, c.PeerId)
+		}
+	}
+
+	msgs := make(map[string]*model.Chatlog, len(msgIds))
+	if len(msgIds) > 0 {
+		list, err := l.svcCtx.ChatlogModel.ListByIds(ctx, msgIds)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			msgs[list[i].ID.Hex()] = list[i]
+		}
+	}
+	names, err := l.peerNames(ctx, uids, gids)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*domain.Conversation, 0, len(convs))
+	for _, c := range convs {
+		item := &domain.Conversation{
+			Id:        c.ConversationId,
+			ChatType:  int(c.ChatType),
+			PeerId:    c.PeerId,
+			PeerName:  names[c.PeerId],
+			Unread:    c.Unread,
+			ReadMsgId: c.ReadMsgId,
+		}
+		if msg, ok := msgs[c.LastMsgId]; ok {
+			item.LastMessage = msg.ToDomain()
+		}
+		list = append(list, item)
+	}
+	return &domain.ConversationListResp{
+		Count: count,
+		List:  list,
+	}, nil
+}
+
+// Messages 会话中的历史消息，只有会话的参与者可以查询
+func (l *conversation) Messages(ctx context.Context, req *domain.ConversationMessagesReq) (resp *domain.ConversationMessagesResp, err error) {
+	uid := token.GetUId(ctx)
+	_, members, err := conversationMembers(ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(members, uid) {
+		return nil, ErrNotConversationMember
+	}
+
+	count := req.Count
+	if count <= 0 {
+		count = defaultMessageCount
+	}
+	count = min(count, maxMessageCount)
+
+	// 多查询一条判断是否还有更多消息
+	chatlogs, err := l.svcCtx.ChatlogModel.ListByCursor(ctx, uid, req.Id, req.Before, req.After, count+1)
+	if err != nil {
+		return nil, err
+	}
+	hasMore := len(chatlogs) > count
+	if hasMore {
+		if len(req.After) > 0 {
+			chatlogs = chatlogs[:count]
+		} else {
+			chatlogs = chatlogs[1:]
+		}
+	}
+
+	list := make([]*domain.Message, 0, len(chatlogs))
+	for i := range chatlogs {
+		list = append(list, chatlogs[i].ToDomain())
+	}
+	return &domain.ConversationMessagesResp{
+		List:    list,
+		HasMore: hasMore,
+	}, nil
+}
+
+// peerNames 会话对方的用户名称或群名称
+func (l *conversation) peerNames(ctx context.Context, uids, gids []string) (map[string]string, error) {
+	res := make(map[string]string, len(uids)+len(gids))
+	if len(uids) > 0 {
+		users, err := l.svcCtx.UserModel.ListToMaps(ctx, &domain.UserListReq{Ids: uids})
+		if err != nil {
+			return nil, err
+		}
+		for id, u := range users {
+			res[id] = u.Name
+		}
+	}
+	if len(gids) > 0 {
+		groups, err := l.svcCtx.GroupModel.ListByIds(ctx, gids)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			if g.IsDepartment() {
+				if dep, err := l.svcCtx.DepartmentModel.FindOne(ctx, g.DepId); err == nil {
+					g.Name = dep.Name
+				}
+			}
+			res[g.ID.Hex()] = g.Name
+		}
+	}
+	return res, nil
+}
+
+// conversationMembers 解析会话ID对应的参与者：群聊的会话ID为群ID，
+// 私聊的会话ID由 GenerateUniqueID 根据双方的用户ID生成，从会话记录或聊天记录中找到双方后校验
+func conversationMembers(ctx context.Context, svcCtx *svc.ServiceContext, cid string) (model.ChatType, []string, error) {
+	if primitive.IsValidObjectID(cid) {
+		g, err := findGroup(ctx, svcCtx, cid)
+		if err == nil {
+			return model.GroupChatType, g.MemberIds, nil
+		}
+		if !errors.Is(err, model.ErrGroupNotFound) {
+			return 0, nil, err
+		}
+	}
+
+	var candidates []string
+	convs, err := svcCtx.ConversationModel.ListByConversationId(ctx, cid)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, c := range convs {
+		candidates = append(candidates, c.UserId, c.PeerId)
+	}
+	// 早于会话记录的聊天只能从聊天记录中找到双方
+	if len(candidates) == 0 {
+		latest, err := svcCtx.ChatlogModel.FindLatest(ctx, cid)
+		if errors.Is(err, model.ErrNotFound) {
+			return 0, nil, ErrConversationNotFound
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		candidates = append(candidates, latest.SendId, latest.RecvId)
+	}
+
+	slices.Sort(candidates)
+	candidates = slices.Compact(candidates)
+	for i := range candidates {
+		for j := i; j < len(candidates); j++ {
+			if GenerateUniqueID(candidates[i], candidates[j]) == cid {
+				if i == j {
+					return model.SingleChatType, candidates[i : i+1], nil
+				}
+				return model.SingleChatType, []string{candidates[i], candidates[j]}, nil
+			}
+		}
+	}
+	return 0, nil, ErrConversationNotFound
+}
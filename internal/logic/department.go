@@ -2,10 +2,13 @@ package logic
 
 import (
 	"ai/internal/model"
+	"ai/pkg/mongox"
 	"context"
 	"errors"
+	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"ai/internal/domain"
@@ -16,11 +19,16 @@ import (
 
 type Department interface {
 	Soa(ctx context.Context) (resp *domain.DepartmentSoaResp, err error)
+	OrgChart(ctx context.Context) (resp *domain.OrgChartResp, err error)
 	Info(ctx context.Context, req *domain.IdPathReq) (resp *domain.Department, err error)
 	Create(ctx context.Context, req *domain.Department) (err error)
 	Edit(ctx context.Context, req *domain.Department) (err error)
+	Move(ctx context.Context, req *domain.MoveDepartmentReq) (err error)
 	Delete(ctx context.Context, req *domain.IdPathReq) (err error)
 	SetDepUsers(ctx context.Context, req *domain.SetDepUser) (err error)
+	SetMember(ctx context.Context, req *domain.DepartmentMemberReq) (err error)
+	RemoveMember(ctx context.Context, req *domain.DepartmentMemberReq) (err error)
+	UserDeps(ctx context.Context, req *domain.IdPathReq) (resp *domain.DepartmentUserListResp, err error)
 	DepUserInfo(ctx context.Context, req *domain.IdPathReq) (resp *domain.Department, err error)
 }
 
@@ -74,6 +82,104 @@ func (l *department) buildTree(rootDep []*domain.Department, groupDep map[string
 	}
 }
 
+// OrgChart 组织架构图，在部门树的基础上补充每个部门的主管、成员以及成员的汇报对象
+func (l *department) OrgChart(ctx context.Context) (resp *domain.OrgChartResp, err error) {
+	deps, err := l.svcCtx.DepartmentModel.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	depById := make(map[string]*model.Department, len(deps))
+	for i := range deps {
+		depById[deps[i].ID.Hex()] = deps[i]
+	}
+
+	users, err := l.svcCtx.UserModel.AllToMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]string, 0, len(users))
+	for uid := range users {
+		uids = append(uids, uid)
+	}
+
+	depUsers, err := l.svcCtx.DepartmentUserModel.ListByUserIds(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	primaries := primaryDepartments(depUsers)
+
+	orgUser := func(uid string, depUser *model.DepartmentUser) *domain.OrgUser {
+		u, ok := users[uid]
+		if !ok {
+			return nil
+		}
+
+		res := &domain.OrgUser{
+			Id:        uid,
+			Name:      u.Name,
+			JobTitle:  u.JobTitle,
+			Avatar:    u.Avatar,
+			ManagerId: reportsTo(u, primaries[uid], depById),
+		}
+		if depUser != nil {
+			res.Position = depUser.Position
+			res.IsPrimary = depUser.IsPrimary
+		}
+		if manager, ok := users[res.ManagerId]; ok {
+			res.ManagerName = manager.Name
+		}
+		return res
+	}
+
+	nodes := make(map[string]*domain.OrgChartNode, len(deps))
+	for _, dep := range deps {
+		nodes[dep.ID.Hex()] = &domain.OrgChartNode{
+			Id:       dep.ID.Hex(),
+			Name:     dep.Name,
+			ParentId: dep.ParentId,
+			Level:    dep.Level,
+			Leader:   orgUser(dep.LeaderId, nil),
+			Members:  make([]*domain.OrgUser, 0),
+		}
+	}
+
+	for _, depUser := range depUsers {
+		node, ok := nodes[depUser.DepId]
+		if !ok {
+			continue
+		}
+		if node.Leader != nil && node.Leader.Id == depUser.UserId {
+			node.Leader.Position = depUser.Position
+			node.Leader.IsPrimary = depUser.IsPrimary
+			continue
+		}
+		if member := orgUser(depUser.UserId, depUser); member != nil {
+			node.Members = append(node.Members, member)
+		}
+	}
+
+	resp = &domain.OrgChartResp{
+		Child: make([]*domain.OrgChartNode, 0),
+	}
+	for _, dep := range deps {
+		node := nodes[dep.ID.Hex()]
+		parent, ok := nodes[dep.ParentId]
+		if !ok {
+			resp.Child = append(resp.Child, node)
+			continue
+		}
+		parent.Child = append(parent.Child, node)
+	}
+
+	for _, uid := range uids {
+		if _, ok := primaries[uid]; !ok {
+			resp.Unassigned = append(resp.Unassigned, orgUser(uid, nil))
+		}
+	}
+
+	return resp, nil
+}
+
 // Info 获取部门信息
 func (l *department) Info(ctx context.Context, req *domain.IdPathReq) (resp *domain.Department, err error) {
 	dep, err := l.svcCtx.DepartmentModel.FindOne(ctx, req.Id)
@@ -96,6 +202,11 @@ func (l *department) Info(ctx context.Context, req *domain.IdPathReq) (resp *dom
 
 // Create 创建部门
 func (l *department) Create(ctx context.Context, req *domain.Department) (err error) {
+	// 创建顶级部门需要全局权限，创建下级部门需要具有上级部门的权限
+	if err = l.svcCtx.Authorize(ctx, model.ResourceDepartment, model.ActionCreate, req.ParentId); err != nil {
+		return err
+	}
+
 	dep, err := l.svcCtx.DepartmentModel.FindByName(ctx, req.Name)
 	if err != nil && !errors.Is(err, model.ErrDepNotFound) {
 		return err
@@ -115,28 +226,42 @@ func (l *department) Create(ctx context.Context, req *domain.Department) (err er
 
 	depId := primitive.NewObjectID()
 
-	err = l.svcCtx.DepartmentModel.Insert(ctx, &model.Department{
+	data := &model.Department{
 		ID:         depId,
 		Name:       req.Name,
 		ParentId:   req.ParentId,
 		ParentPath: parentPath,
-		Level:      req.Level,
+		Level:      model.DepartmentLevel(parentPath),
 		LeaderId:   req.LeaderId,
+		Group:      req.Group,
 		CreateAt:   time.Now().Unix(),
-	})
-	if err != nil {
+	}
+	if err = l.svcCtx.DepartmentModel.Insert(ctx, data); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionCreate, model.ResourceDepartment, depId.Hex(), nil, data)
+	if err = setDepGroup(ctx, l.svcCtx, data); err != nil {
 		return err
 	}
 
 	// 将部门主管也添加到部门中
+	isPrimary, err := noDepartment(ctx, l.svcCtx, req.LeaderId)
+	if err != nil {
+		return err
+	}
 	return l.svcCtx.DepartmentUserModel.Insert(ctx, &model.DepartmentUser{
-		DepId:  depId.Hex(),
-		UserId: req.LeaderId,
+		DepId:     depId.Hex(),
+		UserId:    req.LeaderId,
+		IsPrimary: isPrimary,
 	})
 }
 
 // Edit 修改部门
 func (l *department) Edit(ctx context.Context, req *domain.Department) (err error) {
+	if err = l.svcCtx.Authorize(ctx, model.ResourceDepartment, model.ActionUpdate, req.Id); err != nil {
+		return err
+	}
+
 	dep, err := l.svcCtx.DepartmentModel.FindOne(ctx, req.Id)
 	if err != nil {
 		return err
@@ -150,13 +275,147 @@ func (l *department) Edit(ctx context.Context, req *domain.Department) (err erro
 		return errors.New("已存在该部门")
 	}
 
-	return l.svcCtx.DepartmentModel.Update(ctx, &model.Department{
-		ID:       dep.ID,
-		Name:     req.Name,
-		ParentId: req.ParentId,
-		Level:    req.Level,
-		LeaderId: req.LeaderId,
+	data := &model.Department{
+		ID:         dep.ID,
+		Name:       req.Name,
+		ParentId:   dep.ParentId,
+		ParentPath: dep.ParentPath,
+		Level:      dep.Level,
+		LeaderId:   req.LeaderId,
+		NeedLeader: dep.NeedLeader && len(req.LeaderId) == 0,
+		Group:      req.Group,
+		CreateAt:   dep.CreateAt,
+	}
+
+	// 上级部门发生变化，需要同步调整所有下级部门的路径，调整为顶级部门需要使用Move
+	moved := len(req.ParentId) > 0 && req.ParentId != dep.ParentId
+	if moved {
+		if err = l.svcCtx.Authorize(ctx, model.ResourceDepartment, model.ActionUpdate, req.ParentId); err != nil {
+			return err
+		}
+	}
+
+	// 部门信息与上级部门的调整在同一事务中提交
+	err = mongox.Transaction(ctx, l.svcCtx.Mongo, func(ctx context.Context) error {
+		if err := l.svcCtx.DepartmentModel.Update(ctx, data); err != nil {
+			return err
+		}
+		if err := setDepGroup(ctx, l.svcCtx, data); err != nil {
+			return err
+		}
+		if !moved {
+			return nil
+		}
+
+		parentPath, err := l.move(ctx, dep, req.ParentId)
+		if err != nil {
+			return err
+		}
+		data.ParentId = req.ParentId
+		data.ParentPath = parentPath
+		data.Level = model.DepartmentLevel(parentPath)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceDepartment, req.Id, dep, data)
+	return nil
+}
+
+// Move 调整部门的上级部门，在事务中重写该部门及其所有下级部门的父级路径和层级
+// 审批在创建时已经确定了审批人，调整部门结构不会影响进行中的审批
+func (l *department) Move(ctx context.Context, req *domain.MoveDepartmentReq) (err error) {
+	// 需要同时具有当前部门和新的上级部门的权限，移动为顶级部门需要全局权限
+	if err = l.svcCtx.Authorize(ctx, model.ResourceDepartment, model.ActionUpdate, req.Id); err != nil {
+		return err
+	}
+	if err = l.svcCtx.Authorize(ctx, model.ResourceDepartment, model.ActionUpdate, req.ParentId); err != nil {
+		return err
+	}
+
+	dep, err := l.svcCtx.DepartmentModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+	if dep.ParentId == req.ParentId {
+		return nil
+	}
+
+	parentPath, err := l.move(ctx, dep, req.ParentId)
+	if err != nil {
+		return err
+	}
+
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceDepartment, req.Id,
+		bson.M{"parentId": dep.ParentId, "parentPath": dep.ParentPath},
+		bson.M{"parentId": req.ParentId, "parentPath": parentPath})
+	return nil
+}
+
+// move 在事务中校验新的上级部门，并重写部门及其所有下级部门的父级路径和层级，返回部门新的父级路径。
+// ctx已经在事务中时加入该事务
+func (l *department) move(ctx context.Context, dep *model.Department, parentId string) (string, error) {
+	id := dep.ID.Hex()
+	if parentId == id {
+		return "", errors.New("不能将部门移动到自身之下")
+	}
+
+	var parentPath string
+	err := mongox.Transaction(ctx, l.svcCtx.Mongo, func(ctx context.Context) error {
+		// 部门和上级部门都在事务中重新读取，上级部门的路径可能已被并发的调整修改
+		cur, err := l.svcCtx.DepartmentModel.FindOne(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		parentPath = ""
+		if len(parentId) > 0 {
+			parent, err := l.svcCtx.DepartmentModel.FindOne(ctx, parentId)
+			if err != nil {
+				return err
+			}
+
+			// 新的上级部门不能是当前部门的下级部门，否则会形成环
+			for _, pid := range model.ParseParentPath(parent.ParentPath) {
+				if pid == id {
+					return errors.New("不能将部门移动到其下级部门之下")
+				}
+			}
+			parentPath = parent.Path()
+
+			// 写入上级部门，与同时移动上级部门的事务产生写冲突，避免两个部门互相移动到对方之下形成环
+			if err = l.svcCtx.DepartmentModel.Touch(ctx, parent.ID); err != nil {
+				return err
+			}
+		}
+
+		oldPath := cur.Path()
+		newPath := model.DepartmentParentPath(parentPath, id)
+
+		children, err := l.svcCtx.DepartmentModel.ListByParentPath(ctx, oldPath)
+		if err != nil {
+			return err
+		}
+
+		err = l.svcCtx.DepartmentModel.UpdateParent(ctx, dep.ID, parentId, parentPath,
+			model.DepartmentLevel(parentPath))
+		if err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			childPath := newPath + strings.TrimPrefix(child.ParentPath, oldPath)
+			err = l.svcCtx.DepartmentModel.UpdateParent(ctx, child.ID, child.ParentId, childPath,
+				model.DepartmentLevel(childPath))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
 	})
+	return parentPath, err
 }
 
 // Delete 删除部门
@@ -168,36 +427,229 @@ func (l *department) Delete(ctx context.Context, req *domain.IdPathReq) (err err
 		}
 		return err
 	}
+	if err = l.svcCtx.Authorize(ctx, model.ResourceDepartment, model.ActionDelete, req.Id); err != nil {
+		return err
+	}
 
 	depUser, err := l.svcCtx.DepartmentUserModel.List(ctx, &domain.DepartmentListReq{DepId: req.Id})
 	if err != nil {
 		return err
 	}
 
-	if len(depUser) == 0 {
-		return l.svcCtx.DepartmentModel.Delete(ctx, req.Id)
-	}
-
-	if len(depUser) > 1 || depUser[0].UserId != dep.LeaderId {
+	// 只剩部门主管时可以删除
+	if len(depUser) > 1 || len(depUser) == 1 && depUser[0].UserId != dep.LeaderId {
 		return errors.New("该部门下还存在用户，不能删除该部门")
 	}
 
-	return l.svcCtx.DepartmentModel.Delete(ctx, req.Id)
+	if err = l.svcCtx.DepartmentModel.Delete(ctx, req.Id); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionDelete, model.ResourceDepartment, req.Id, dep, nil)
+	return l.svcCtx.GroupModel.DeleteByDepId(ctx, req.Id)
 }
 
 // SetDepUsers 设置部门成员
 func (l *department) SetDepUsers(ctx context.Context, req *domain.SetDepUser) (err error) {
+	if err = l.svcCtx.Authorize(ctx, model.ResourceDepartment, model.ActionUpdate, req.DepId); err != nil {
+		return err
+	}
+
 	_, err = l.svcCtx.DepartmentModel.FindOne(ctx, req.DepId)
 	if err != nil {
 		return err
 	}
 
+	// 保留仍在部门中的成员的主部门标记和职位
+	olds, err := l.svcCtx.DepartmentUserModel.List(ctx, &domain.DepartmentListReq{DepId: req.DepId})
+	if err != nil {
+		return err
+	}
+	oldByUid := make(map[string]*model.DepartmentUser, len(olds))
+	oldUids := make([]string, 0, len(olds))
+	for i := range olds {
+		oldByUid[olds[i].UserId] = olds[i]
+		oldUids = append(oldUids, olds[i].UserId)
+	}
+
 	err = l.svcCtx.DepartmentUserModel.DeleteByDepId(ctx, req.DepId)
 	if err != nil {
 		return err
 	}
 
-	return l.svcCtx.DepartmentUserModel.Inserts(ctx, req.DepId, req.UserIds)
+	depUsers := make([]*model.DepartmentUser, 0, len(req.UserIds))
+	for _, uid := range req.UserIds {
+		depUser := &model.DepartmentUser{
+			DepId:  req.DepId,
+			UserId: uid,
+		}
+		if old, ok := oldByUid[uid]; ok {
+			depUser.IsPrimary = old.IsPrimary
+			depUser.Position = old.Position
+		} else if depUser.IsPrimary, err = noDepartment(ctx, l.svcCtx, uid); err != nil {
+			return err
+		}
+		depUsers = append(depUsers, depUser)
+	}
+
+	if err = l.svcCtx.DepartmentUserModel.Inserts(ctx, depUsers); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceDepartment, req.DepId,
+		bson.M{"userIds": oldUids}, bson.M{"userIds": req.UserIds})
+	return nil
+}
+
+// SetMember 添加或修改部门成员，设置成员的职位以及是否为主部门
+func (l *department) SetMember(ctx context.Context, req *domain.DepartmentMemberReq) (err error) {
+	if err = l.svcCtx.Authorize(ctx, model.ResourceDepartment, model.ActionUpdate, req.DepId); err != nil {
+		return err
+	}
+	if _, err = l.svcCtx.DepartmentModel.FindOne(ctx, req.DepId); err != nil {
+		return err
+	}
+	if _, err = l.svcCtx.UserModel.FindOne(ctx, req.UserId); err != nil {
+		return err
+	}
+
+	var before *model.DepartmentUser
+	depUser, err := l.svcCtx.DepartmentUserModel.FindByDepAndUser(ctx, req.DepId, req.UserId)
+	switch {
+	case err == nil:
+		old := *depUser
+		before = &old
+		depUser.Position = req.Position
+		if err = l.svcCtx.DepartmentUserModel.Update(ctx, depUser); err != nil {
+			return err
+		}
+	case errors.Is(err, model.ErrNotFound):
+		// 用户还不属于任何部门时，该部门即为主部门
+		isPrimary, err := noDepartment(ctx, l.svcCtx, req.UserId)
+		if err != nil {
+			return err
+		}
+		err = l.svcCtx.DepartmentUserModel.Insert(ctx, &model.DepartmentUser{
+			DepId:     req.DepId,
+			UserId:    req.UserId,
+			IsPrimary: isPrimary,
+			Position:  req.Position,
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	if req.IsPrimary {
+		if err = l.svcCtx.DepartmentUserModel.SetPrimary(ctx, req.DepId, req.UserId); err != nil {
+			return err
+		}
+	}
+
+	after, err := l.svcCtx.DepartmentUserModel.FindByDepAndUser(ctx, req.DepId, req.UserId)
+	if err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceDepartment, req.DepId, before, after)
+	return nil
+}
+
+// RemoveMember 将用户移出部门，移出的是主部门时由用户最早加入的其他部门作为主部门
+func (l *department) RemoveMember(ctx context.Context, req *domain.DepartmentMemberReq) (err error) {
+	if err = l.svcCtx.Authorize(ctx, model.ResourceDepartment, model.ActionUpdate, req.DepId); err != nil {
+		return err
+	}
+
+	before, err := l.svcCtx.DepartmentUserModel.FindByDepAndUser(ctx, req.DepId, req.UserId)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err = removeMember(ctx, l.svcCtx, req.DepId, req.UserId); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceDepartment, req.DepId, before, nil)
+	return nil
+}
+
+// UserDeps 获取用户所属的所有部门，主部门排在第一个
+func (l *department) UserDeps(ctx context.Context, req *domain.IdPathReq) (resp *domain.DepartmentUserListResp, err error) {
+	user, err := l.svcCtx.UserModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	depUsers, err := l.svcCtx.DepartmentUserModel.ListByUserId(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	depIds := make([]string, 0, len(depUsers))
+	for i := range depUsers {
+		depIds = append(depIds, depUsers[i].DepId)
+	}
+	deps := make(map[string]*model.Department)
+	if len(depIds) > 0 {
+		deps, err = l.svcCtx.DepartmentModel.ListToMap(ctx, &domain.DepartmentListReq{DepIds: depIds})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	list := make([]*domain.DepartmentUser, 0, len(depUsers))
+	for i := range depUsers {
+		var depName string
+		if dep, ok := deps[depUsers[i].DepId]; ok {
+			depName = dep.Name
+		}
+		list = append(list, depUsers[i].ToDomain(user.Name, depName))
+	}
+
+	return &domain.DepartmentUserListResp{
+		List: list,
+	}, nil
+}
+
+// removeMember 将用户移出部门，移出的是主部门时由用户最早加入的其他部门作为主部门
+func removeMember(ctx context.Context, svcCtx *svc.ServiceContext, depId, uid string) error {
+	depUser, err := svcCtx.DepartmentUserModel.FindByDepAndUser(ctx, depId, uid)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err = svcCtx.DepartmentUserModel.Delete(ctx, depUser.ID.Hex()); err != nil {
+		return err
+	}
+	if !depUser.IsPrimary {
+		return nil
+	}
+
+	next, err := svcCtx.DepartmentUserModel.FindByUserId(ctx, uid)
+	if err != nil {
+		if errors.Is(err, model.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	return svcCtx.DepartmentUserModel.SetPrimary(ctx, next.DepId, uid)
+}
+
+// noDepartment 判断用户是否还不属于任何部门
+func noDepartment(ctx context.Context, svcCtx *svc.ServiceContext, uid string) (bool, error) {
+	_, err := svcCtx.DepartmentUserModel.FindByUserId(ctx, uid)
+	switch {
+	case err == nil:
+		return false, nil
+	case errors.Is(err, model.ErrNotFound):
+		return true, nil
+	default:
+		return false, err
+	}
 }
 
 // DepUserInfo 获取部门成员信息
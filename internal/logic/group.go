@@ -0,0 +1,337 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/token"
+	"context"
+	"errors"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	ErrNotGroupMember  = errors.New("不是该群的成员")
+	ErrNotGroupAdmin   = errors.New("只有群主和管理员可以操作")
+	ErrNotGroupOwner   = errors.New("只有群主可以操作")
+	ErrDepGroupManaged = errors.New("部门群的成员跟随部门，不能单独修改")
+)
+
+// Group 群聊的创建与成员管理，群主和管理员可以修改群名称和成员，只有群主可以设置管理员和解散群聊。
+// 部门群在部门中开启，成员跟随部门成员
+type Group interface {
+	Create(ctx context.Context, req *domain.GroupCreateReq) (resp *domain.IdResp, err error)
+	Info(ctx context.Context, req *domain.IdPathReq) (resp *domain.Group, err error)
+	Edit(ctx context.Context, req *domain.GroupEditReq) (err error)
+	Delete(ctx context.Context, req *domain.IdPathReq) (err error)
+	List(ctx context.Context) (resp *domain.GroupListResp, err error)
+	AddMembers(ctx context.Context, req *domain.GroupMemberReq) (err error)
+	// RemoveMembers 移出成员，成员可以将自己移出即退出群聊
+	RemoveMembers(ctx context.Context, req *domain.GroupMemberReq) (err error)
+	SetAdmins(ctx context.Context, req *domain.GroupMemberReq) (err error)
+}
+
+type group struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewGroup(svcCtx *svc.ServiceContext) Group {
+	return &group{
+		svcCtx: svcCtx,
+	}
+}
+
+// Create 创建群聊，创建人为群主
+func (l *group) Create(ctx context.Context, req *domain.GroupCreateReq) (resp *domain.IdResp, err error) {
+	if len(req.Name) == 0 {
+		return nil, errors.New("请填写群名称")
+	}
+
+	uid := token.GetUId(ctx)
+	members, err := l.users(ctx, append([]string{uid}, req.MemberIds...))
+	if err != nil {
+		return nil, err
+	}
+
+	data := &model.Group{
+		Name:      req.Name,
+		OwnerId:   uid,
+		MemberIds: members,
+	}
+	if err = l.svcCtx.GroupModel.Insert(ctx, data); err != nil {
+		return nil, err
+	}
+	l.svcCtx.Audit(ctx, model.ActionCreate, model.ResourceGroup, data.ID.Hex(), nil, data)
+
+	return &domain.IdResp{
+		Id: data.ID.Hex(),
+	}, nil
+}
+
+func (l *group) Info(ctx context.Context, req *domain.IdPathReq) (resp *domain.Group, err error) {
+	data, err := findGroup(ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !data.IsMember(token.GetUId(ctx)) {
+		return nil, ErrNotGroupMember
+	}
+	return data.ToDomain(), nil
+}
+
+// Edit 修改群名称，部门群的名称跟随部门
+func (l *group) Edit(ctx context.Context, req *domain.GroupEditReq) (err error) {
+	if len(req.Name) == 0 {
+		return errors.New("请填写群名称")
+	}
+
+	data, err := l.manage(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+
+	before := bson.M{"name": data.Name}
+	if err = l.svcCtx.GroupModel.UpdateName(ctx, req.Id, req.Name); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceGroup, req.Id, before, bson.M{"name": req.Name})
+	return nil
+}
+
+// Delete 解散群聊，部门群在部门中关闭
+func (l *group) Delete(ctx context.Context, req *domain.IdPathReq) (err error) {
+	data, err := l.manage(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+	if data.OwnerId != token.GetUId(ctx) {
+		return ErrNotGroupOwner
+	}
+
+	if err = l.svcCtx.GroupModel.Delete(ctx, req.Id); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionDelete, model.ResourceGroup, req.Id, data, nil)
+	return nil
+}
+
+// List 当前用户加入的群聊，包括所在部门开启的部门群
+func (l *group) List(ctx context.Context) (resp *domain.GroupListResp, err error) {
+	uid := token.GetUId(ctx)
+
+	groups, err := l.svcCtx.GroupModel.ListByMember(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	depUsers, err := l.svcCtx.DepartmentUserModel.ListByUserId(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if len(depUsers) > 0 {
+		depIds := make([]string, 0, len(depUsers))
+		for i := range depUsers {
+			depIds = append(depIds, depUsers[i].DepId)
+		}
+		depGroups, err := l.svcCtx.GroupModel.ListByDepIds(ctx, depIds)
+		if err != nil {
+			return nil, err
+		}
+		for i := range depGroups {
+			if err = depGroup(ctx, l.svcCtx, depGroups[i]); err != nil {
+				return nil, err
+			}
+		}
+		groups = append(groups, depGroups...)
+	}
+
+	list := make([]*domain.Group, 0, len(groups))
+	for i := range groups {
+		list = append(list, groups[i].ToDomain())
+	}
+	return &domain.GroupListResp{
+		List: list,
+	}, nil
+}
+
+func (l *group) AddMembers(ctx context.Context, req *domain.GroupMemberReq) (err error) {
+	data, err := l.manage(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+
+	uids, err := l.users(ctx, req.UserIds)
+	if err != nil {
+		return err
+	}
+	after, err := l.svcCtx.GroupModel.AddMembers(ctx, req.Id, uids)
+	if err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceGroup, req.Id,
+		bson.M{"memberIds": data.MemberIds}, bson.M{"memberIds": after.MemberIds})
+	return nil
+}
+
+func (l *group) RemoveMembers(ctx context.Context, req *domain.GroupMemberReq) (err error) {
+	data, err := findGroup(ctx, l.svcCtx, req.Id)
+	if err != nil {
+		return err
+	}
+	if data.IsDepartment() {
+		return ErrDepGroupManaged
+	}
+
+	uid := token.GetUId(ctx)
+	quit := len(req.UserIds) == 1 && req.UserIds[0] == uid
+	if !quit && !data.IsAdmin(uid) {
+		return ErrNotGroupAdmin
+	}
+
+	for _, id := range req.UserIds {
+		if id == data.OwnerId {
+			return errors.New("群主不能退出群聊，请解散群聊")
+		}
+		// 管理员只能由群主移出
+		if !quit && slices.Contains(data.AdminIds, id) && uid != data.OwnerId {
+			return ErrNotGroupOwner
+		}
+	}
+
+	if len(req.UserIds) == 0 {
+		return nil
+	}
+	after, err := l.svcCtx.GroupModel.RemoveMembers(ctx, req.Id, req.UserIds)
+	if err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceGroup, req.Id,
+		bson.M{"memberIds": data.MemberIds}, bson.M{"memberIds": after.MemberIds})
+	return nil
+}
+
+// SetAdmins 设置群管理员，管理员需要是群成员
+func (l *group) SetAdmins(ctx context.Context, req *domain.GroupMemberReq) (err error) {
+	data, err := l.manage(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+	if data.OwnerId != token.GetUId(ctx) {
+		return ErrNotGroupOwner
+	}
+
+	admins := make([]string, 0, len(req.UserIds))
+	for _, id := range req.UserIds {
+		if !data.IsMember(id) {
+			return ErrNotGroupMember
+		}
+		if id != data.OwnerId && !slices.Contains(admins, id) {
+			admins = append(admins, id)
+		}
+	}
+
+	after, err := l.svcCtx.GroupModel.SetAdmins(ctx, req.Id, admins)
+	if errors.Is(err, model.ErrGroupNotFound) {
+		// 群聊刚查询过，更新不到说明有管理员在此期间被移出了群聊
+		return ErrNotGroupMember
+	}
+	if err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceGroup, req.Id,
+		bson.M{"adminIds": data.AdminIds}, bson.M{"adminIds": after.AdminIds})
+	return nil
+}
+
+// manage 获取当前用户可以管理的普通群聊
+func (l *group) manage(ctx context.Context, id string) (*model.Group, error) {
+	data, err := l.svcCtx.GroupModel.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if data.IsDepartment() {
+		return nil, ErrDepGroupManaged
+	}
+	if !data.IsAdmin(token.GetUId(ctx)) {
+		return nil, ErrNotGroupAdmin
+	}
+	return data, nil
+}
+
+// users 去重并校验用户都存在
+func (l *group) users(ctx context.Context, uids []string) ([]string, error) {
+	res := make([]string, 0, len(uids))
+	for _, uid := range uids {
+		if len(uid) > 0 && !slices.Contains(res, uid) {
+			res = append(res, uid)
+		}
+	}
+
+	users, err := l.svcCtx.UserModel.ListToMaps(ctx, &domain.UserListReq{Ids: res})
+	if err != nil {
+		return nil, err
+	}
+	for _, uid := range res {
+		if _, ok := users[uid]; !ok {
+			return nil, model.ErrNotUser
+		}
+	}
+	return res, nil
+}
+
+// findGroup 获取群聊，部门群的名称、群主和成员取自部门
+func findGroup(ctx context.Context, svcCtx *svc.ServiceContext, id string) (*model.Group, error) {
+	data, err := svcCtx.GroupModel.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if data.IsDepartment() {
+		if err = depGroup(ctx, svcCtx, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func depGroup(ctx context.Context, svcCtx *svc.ServiceContext, data *model.Group) error {
+	dep, err := svcCtx.DepartmentModel.FindOne(ctx, data.DepId)
+	if err != nil {
+		return err
+	}
+	depUsers, err := svcCtx.DepartmentUserModel.List(ctx, &domain.DepartmentListReq{DepId: data.DepId})
+	if err != nil {
+		return err
+	}
+
+	data.Name = dep.Name
+	data.OwnerId = dep.LeaderId
+	data.MemberIds = make([]string, 0, len(depUsers))
+	for i := range depUsers {
+		data.MemberIds = append(data.MemberIds, depUsers[i].UserId)
+	}
+	return nil
+}
+
+// setDepGroup 开启或关闭部门群
+func setDepGroup(ctx context.Context, svcCtx *svc.ServiceContext, dep *model.Department) error {
+	_, err := svcCtx.GroupModel.FindByDepId(ctx, dep.ID.Hex())
+	switch {
+	case err == nil:
+		if dep.Group {
+			return nil
+		}
+		return svcCtx.GroupModel.DeleteByDepId(ctx, dep.ID.Hex())
+	case errors.Is(err, model.ErrGroupNotFound):
+		if !dep.Group {
+			return nil
+		}
+		return svcCtx.GroupModel.Insert(ctx, &model.Group{
+			Name:  dep.Name,
+			DepId: dep.ID.Hex(),
+		})
+	default:
+		return err
+	}
+}
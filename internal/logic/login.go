@@ -0,0 +1,236 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/pkg/encrypt"
+	"ai/token"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gitee.com/dn-jinmin/tlog"
+)
+
+// 登录状态，对应 LoginResp.Status
+const (
+	LoginStatusOk              = iota // 登录成功，返回令牌
+	LoginStatusPasswordChange         // 密码需要修改，修改后才能完成登录
+	LoginStatusTwoFactor              // 需要使用返回的二次验证令牌和验证码完成登录
+	LoginStatusTwoFactorEnroll        // 登录成功，但角色要求开启二次验证，开启前不能使用websocket
+)
+
+// twoFactorTokenExpire 二次验证令牌的有效期（秒）
+const twoFactorTokenExpire = 5 * 60
+
+// 未配置登录锁定策略时的默认值
+const (
+	defaultLoginMaxFailures   = 5
+	defaultLoginIpMaxFailures = 20
+	defaultLoginFailureWindow = 15 * 60
+	defaultLoginLockDuration  = 15 * 60
+)
+
+var (
+	ErrPasswordNotChanged    = errors.New("新密码不能与旧密码相同")
+	ErrTwoFactorTokenInvalid = errors.New("二次验证已过期，请重新登录")
+)
+
+// loginAttempt 登录失败计数的Key以及允许的失败次数
+type loginAttempt struct {
+	key         string
+	maxFailures int
+}
+
+// authenticate 校验账号密码，失败次数过多时临时锁定账号和IP，登录结果记录在event中
+func (l *user) authenticate(ctx context.Context, name, password, ip string, event *model.LoginEvent) (*model.User, error) {
+	attempts := l.loginAttempts(name, ip)
+	if err := l.checkLocked(ctx, attempts); err != nil {
+		event.Result = model.LoginLocked
+		return nil, err
+	}
+
+	user, err := l.svcCtx.UserModel.FindByName(ctx, name)
+	if errors.Is(err, model.ErrNotUser) {
+		return nil, l.loginFailed(ctx, attempts, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	event.UserId = user.ID.Hex()
+
+	if !encrypt.ValidatePasswordHash(password, user.Password) {
+		return nil, l.loginFailed(ctx, attempts, errors.New("密码错误"))
+	}
+	if user.Status == model.UserDisabled {
+		return nil, errors.New("账号已停用")
+	}
+	if user.ServiceAccount {
+		return nil, errors.New("服务账号不能登录，请使用API密钥")
+	}
+
+	// 登录成功后清除账号的失败次数，IP的失败次数需要等统计窗口过期
+	if err = l.svcCtx.LoginAttemptModel.DeleteByKey(ctx, attempts[0].key); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (l *user) loginAttempts(name, ip string) []loginAttempt {
+	cfg := l.svcCtx.Config.Login
+	attempts := []loginAttempt{
+		{key: model.LoginAttemptUserKey(name), maxFailures: orDefault(cfg.MaxFailures, defaultLoginMaxFailures)},
+	}
+	if len(ip) > 0 {
+		attempts = append(attempts, loginAttempt{
+			key:         model.LoginAttemptIpKey(ip),
+			maxFailures: orDefault(cfg.IpMaxFailures, defaultLoginIpMaxFailures),
+		})
+	}
+	return attempts
+}
+
+func (l *user) checkLocked(ctx context.Context, attempts []loginAttempt) error {
+	now := time.Now().Unix()
+	for _, attempt := range attempts {
+		data, err := l.svcCtx.LoginAttemptModel.FindByKey(ctx, attempt.key)
+		if errors.Is(err, model.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if data.LockedUntil > now {
+			return fmt.Errorf("登录失败次数过多，请%d分钟后重试", (data.LockedUntil-now+59)/60)
+		}
+	}
+	return nil
+}
+
+// loginFailed 增加登录失败次数，达到上限时锁定，返回登录失败的原因
+func (l *user) loginFailed(ctx context.Context, attempts []loginAttempt, cause error) error {
+	var (
+		cfg    = l.svcCtx.Config.Login
+		window = orDefault(cfg.FailureWindow, defaultLoginFailureWindow)
+		lock   = orDefault(cfg.LockDuration, defaultLoginLockDuration)
+		now    = time.Now().Unix()
+	)
+
+	// 计数在一次原子更新中完成，并发的失败不会互相覆盖
+	for _, attempt := range attempts {
+		data, err := l.svcCtx.LoginAttemptModel.Fail(ctx, attempt.key, now, window)
+		if err != nil {
+			return err
+		}
+		if data.Failures < attempt.maxFailures {
+			continue
+		}
+		if err = l.svcCtx.LoginAttemptModel.Lock(ctx, attempt.key, attempt.maxFailures, now+lock); err != nil {
+			return err
+		}
+	}
+	return cause
+}
+
+// completeLogin 密码校验通过后完成登录。开启了二次验证的用户先返回二次验证令牌；
+// 角色要求二次验证但用户还未开启时，签发未完成二次验证的会话，用于开启二次验证
+func (l *user) completeLogin(ctx context.Context, u *model.User, event *model.LoginEvent) (*domain.LoginResp, error) {
+	uid := u.ID.Hex()
+	if u.TotpEnabled {
+		tok, err := token.GetTwoFactorToken(l.svcCtx.Config.Jwt.Secret, time.Now().Unix(), twoFactorTokenExpire, uid, token.GetTenantId(ctx))
+		if err != nil {
+			return nil, err
+		}
+		event.Result = model.LoginTwoFactor
+		return &domain.LoginResp{
+			Status:         LoginStatusTwoFactor,
+			Id:             uid,
+			Name:           u.Name,
+			TwoFactorToken: tok,
+		}, nil
+	}
+
+	required, err := l.svcCtx.RequireTwoFactor(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := newSession(ctx, l.svcCtx, uid, false)
+	if err != nil {
+		return nil, err
+	}
+	resp.Name = u.Name
+	if required {
+		resp.Status = LoginStatusTwoFactorEnroll
+	}
+	return resp, nil
+}
+
+// recordLogin 记录登录事件，记录失败不影响登录结果
+func (l *user) recordLogin(ctx context.Context, event *model.LoginEvent, err error) {
+	switch {
+	case err == nil && event.Result == 0:
+		event.Result = model.LoginSuccess
+	case err != nil:
+		if event.Result == 0 {
+			event.Result = model.LoginFailure
+		}
+		event.Reason = err.Error()
+	}
+
+	if err := l.svcCtx.LoginEventModel.Insert(ctx, event); err != nil {
+		tlog.ErrorfCtx(ctx, "recordLogin", "insert login event fail %v, name %v", err, event.Name)
+	}
+}
+
+// passwordExpired 判断用户是否需要修改密码：首次登录需要修改初始密码，或者密码已超过有效期
+func (l *user) passwordExpired(u *model.User) bool {
+	if u.MustChangePassword {
+		return true
+	}
+
+	days := l.svcCtx.Config.Password.ExpireDays
+	if days <= 0 {
+		return false
+	}
+	changedAt := u.PasswordAt
+	if changedAt == 0 {
+		changedAt = u.CreateAt
+	}
+	return time.Now().Unix() > changedAt+int64(days)*24*60*60
+}
+
+// setPassword 按密码策略校验新密码并修改，修改后撤销用户所有的会话
+func (l *user) setPassword(ctx context.Context, u *model.User, password string) error {
+	cfg := l.svcCtx.Config.Password
+	policy := &encrypt.PasswordPolicy{
+		MinLength:     cfg.MinLength,
+		RequireUpper:  cfg.RequireUpper,
+		RequireLower:  cfg.RequireLower,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+	}
+	if err := policy.Validate(password); err != nil {
+		return err
+	}
+	if encrypt.ValidatePasswordHash(password, u.Password) {
+		return ErrPasswordNotChanged
+	}
+
+	hash, err := encrypt.GenPasswordHash([]byte(password))
+	if err != nil {
+		return err
+	}
+	if err = l.svcCtx.UserModel.UpdatePassword(ctx, u.ID, string(hash)); err != nil {
+		return err
+	}
+	return l.svcCtx.SessionModel.RevokeByUserId(ctx, u.ID.Hex())
+}
+
+func orDefault[T int | int64](v, def T) T {
+	if v > 0 {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,173 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/token"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// maxTextLength 文本消息的最大长度
+	maxTextLength = 5000
+	// maxVoiceDuration 语音消息的最大时长，单位秒
+	maxVoiceDuration = 60
+)
+
+var (
+	imageExts = []string{".png", ".jpg", ".jpeg", ".gif", ".webp", ".bmp"}
+	voiceExts = []string{".mp3", ".wav", ".m4a", ".amr", ".ogg", ".webm", ".aac"}
+)
+
+var (
+	ErrEmptyMessage       = errors.New("消息内容不能为空")
+	ErrMessageTooLong     = errors.New("消息内容过长")
+	ErrContentType        = errors.New("不支持的消息类型")
+	ErrAttachment         = errors.New("请先上传文件，再发送上传后的文件")
+	ErrAttachmentType     = errors.New("文件格式与消息类型不符")
+	ErrVoiceDuration      = errors.New("语音时长需要在1-60秒之间")
+	ErrCardType           = errors.New("卡片只支持待办和审批")
+	ErrCardNotFound       = errors.New("卡片引用的待办或审批不存在")
+	ErrContentNotAllowed  = errors.New("消息内容与消息类型不符")
+	ErrAttachmentNotFound = errors.New("上传的文件不存在")
+)
+
+// messageContent 按消息类型校验消息内容，图片、文件和语音需要引用上传的文件，卡片根据引用的待办或审批生成
+func messageContent(ctx context.Context, svcCtx *svc.ServiceContext, req *domain.Message, data *model.Chatlog) error {
+	data.ContentType = model.ContentType(req.ContentType)
+	data.MsgContent = req.Content
+	if utf8.RuneCountInString(req.Content) > maxTextLength {
+		return ErrMessageTooLong
+	}
+
+	switch data.ContentType {
+	case model.TextContent:
+		if len(strings.TrimSpace(req.Content)) == 0 {
+			return ErrEmptyMessage
+		}
+		if req.Attachment != nil || req.Card != nil {
+			return ErrContentNotAllowed
+		}
+	case model.ImageContent, model.FileContent, model.VoiceContent:
+		if req.Card != nil {
+			return ErrContentNotAllowed
+		}
+		attachment, err := messageAttachment(svcCtx, data.ContentType, req.Attachment)
+		if err != nil {
+			return err
+		}
+		data.Attachment = attachment
+	case model.CardContent:
+		if req.Attachment != nil {
+			return ErrContentNotAllowed
+		}
+		card, err := messageCard(ctx, svcCtx, req.Card)
+		if err != nil {
+			return err
+		}
+		data.Card = card
+	default:
+		return ErrContentType
+	}
+	return nil
+}
+
+// messageAttachment 校验引用的文件是上传目录中的文件，文件大小以实际文件为准
+func messageAttachment(svcCtx *svc.ServiceContext, contentType model.ContentType,
+	req *domain.MessageAttachment) (*model.Attachment, error) {
+	if req == nil || len(req.File) == 0 {
+		return nil, ErrAttachment
+	}
+
+	dir := filepath.Clean(svcCtx.Config.Upload.SavePath)
+	file := filepath.Clean(req.File)
+	if rel, err := filepath.Rel(dir, file); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil, ErrAttachment
+	}
+
+	ext := strings.ToLower(filepath.Ext(file))
+	switch contentType {
+	case model.ImageContent:
+		if !slices.Contains(imageExts, ext) {
+			return nil, ErrAttachmentType
+		}
+	case model.VoiceContent:
+		if !slices.Contains(voiceExts, ext) {
+			return nil, ErrAttachmentType
+		}
+		if req.Duration <= 0 || req.Duration > maxVoiceDuration {
+			return nil, ErrVoiceDuration
+		}
+	}
+
+	info, err := os.Stat(file)
+	if err != nil || info.IsDir() {
+		return nil, ErrAttachmentNotFound
+	}
+
+	res := &model.Attachment{
+		File:     req.File,
+		Name:     req.Name,
+		Size:     info.Size(),
+		Duration: req.Duration,
+	}
+	if len(res.Name) == 0 {
+		res.Name = filepath.Base(file)
+	}
+	return res, nil
+}
+
+// messageCard 根据引用的待办或审批生成卡片，发送者需要是参与者或者具有查看的权限
+func messageCard(ctx context.Context, svcCtx *svc.ServiceContext, req *domain.MessageCard) (*model.Card, error) {
+	if req == nil || len(req.Id) == 0 {
+		return nil, ErrCardNotFound
+	}
+
+	switch req.Type {
+	case model.TodoCard:
+		todo, err := svcCtx.TodoModel.FindOne(ctx, req.Id)
+		if err != nil {
+			return nil, ErrCardNotFound
+		}
+		if !todo.IsParticipant(token.GetUId(ctx)) {
+			if err = svcCtx.Authorize(ctx, model.ResourceTodo, model.ActionRead, ""); err != nil {
+				return nil, err
+			}
+		}
+		return &model.Card{
+			Type:   model.TodoCard,
+			Id:     req.Id,
+			Title:  todo.Title,
+			Status: todo.CurrentStatus(time.Now().Unix()).ToString(),
+			Desc:   todo.Desc,
+		}, nil
+	case model.ApprovalCard:
+		approval, err := svcCtx.ApprovalModel.FindOne(ctx, req.Id)
+		if err != nil {
+			return nil, ErrCardNotFound
+		}
+		// 按审批提交时所在的部门校验，部门范围的权限只能查看本部门及下级部门的审批
+		if !approval.IsParticipant(token.GetUId(ctx)) {
+			if err = svcCtx.Authorize(ctx, model.ResourceApproval, model.ActionRead, approval.DepId); err != nil {
+				return nil, err
+			}
+		}
+		return &model.Card{
+			Type:   model.ApprovalCard,
+			Id:     req.Id,
+			Title:  approval.Title,
+			Status: approval.Status.ToString(),
+			Desc:   approval.Abstract,
+		}, nil
+	default:
+		return nil, ErrCardType
+	}
+}
@@ -0,0 +1,383 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/mongox"
+	"ai/pkg/timex"
+	"ai/token"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gitee.com/dn-jinmin/tlog"
+)
+
+const (
+	// offboardingInterval 检查到期离职交接的间隔
+	offboardingInterval = time.Hour
+	// handoverDeadline 交接待办的处理期限
+	handoverDeadline = 7 * 24 * time.Hour
+)
+
+// Offboarding 离职交接，离职审批通过后在最后工作日结束时停用账号并转交工作
+type Offboarding interface {
+	// Start 登记离职交接，最后工作日已经结束时立即执行
+	Start(ctx context.Context, approval *model.Approval) error
+	// Run 定时执行到期的离职交接，直到ctx结束
+	Run(ctx context.Context)
+}
+
+type offboarding struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewOffboarding(svcCtx *svc.ServiceContext) Offboarding {
+	return &offboarding{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *offboarding) Start(ctx context.Context, approval *model.Approval) error {
+	_, err := l.svcCtx.OffboardingModel.FindByApprovalId(ctx, approval.ID.Hex())
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		return err
+	}
+
+	now := time.Now()
+	lastDay := now.Unix()
+	if approval.Dimission != nil && approval.Dimission.LastDay > 0 {
+		lastDay = approval.Dimission.LastDay
+	}
+
+	// 最后工作日当天账号仍然可用，次日零点开始交接
+	y, m, d := time.Unix(lastDay, 0).Date()
+	executeAt := time.Date(y, m, d+1, 0, 0, 0, 0, time.Local).Unix()
+
+	data := &model.Offboarding{
+		UserId:     approval.UserId,
+		ApprovalId: approval.ID.Hex(),
+		LastDay:    lastDay,
+		ExecuteAt:  executeAt,
+		Status:     model.OffboardingPending,
+	}
+	if err = l.svcCtx.OffboardingModel.Insert(ctx, data); err != nil {
+		return err
+	}
+
+	if executeAt > now.Unix() {
+		return nil
+	}
+	return l.execute(ctx, data)
+}
+
+func (l *offboarding) Run(ctx context.Context) {
+	ticker := time.NewTicker(offboardingInterval)
+	defer ticker.Stop()
+
+	for {
+		l.executeDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// executeDue 依次执行默认租户及各租户到期的离职交接
+func (l *offboarding) executeDue(ctx context.Context) {
+	tids := []string{""}
+	tenants, _, err := l.svcCtx.TenantModel.List(ctx, &domain.TenantListReq{})
+	if err != nil {
+		tlog.ErrorfCtx(ctx, "offboarding", "list tenant fail %v", err)
+	}
+	for i := range tenants {
+		tids = append(tids, tenants[i].ID.Hex())
+	}
+
+	for _, tid := range tids {
+		l.executeTenantDue(token.WithTenantId(ctx, tid))
+	}
+}
+
+func (l *offboarding) executeTenantDue(ctx context.Context) {
+	list, err := l.svcCtx.OffboardingModel.ListDue(ctx, time.Now().Unix())
+	if err != nil {
+		tlog.ErrorfCtx(ctx, "offboarding", "list due fail %v", err)
+		return
+	}
+
+	for i := range list {
+		if err = l.execute(ctx, list[i]); err != nil {
+			tlog.ErrorfCtx(ctx, "offboarding", "execute fail %v, uid %v", err, list[i].UserId)
+		}
+	}
+}
+
+// execute 在事务中执行离职交接：转交待办、审批和直属下级，移出部门，标记需要重新指定主管的部门，
+// 停用账号，最后给交接人创建交接清单待办
+func (l *offboarding) execute(ctx context.Context, data *model.Offboarding) error {
+	return mongox.Transaction(ctx, l.svcCtx.Mongo, func(ctx context.Context) error {
+		user, err := l.svcCtx.UserModel.FindOne(ctx, data.UserId)
+		if err != nil {
+			return err
+		}
+
+		deps, err := l.svcCtx.DepartmentModel.AllToMap(ctx)
+		if err != nil {
+			return err
+		}
+		depUsers, err := l.svcCtx.DepartmentUserModel.ListByUserId(ctx, data.UserId)
+		if err != nil {
+			return err
+		}
+
+		// 交接人为离职用户的汇报对象，没有时交由系统管理员处理
+		manager, err := l.handoverTo(ctx, user, depUsers, deps)
+		if err != nil {
+			return err
+		}
+		data.ManagerId = manager.ID.Hex()
+
+		if data.TodoIds, err = l.transferTodos(ctx, user, manager); err != nil {
+			return err
+		}
+		if data.ApprovalIds, err = l.transferApprovals(ctx, user, manager); err != nil {
+			return err
+		}
+		if err = l.transferReports(ctx, user, manager); err != nil {
+			return err
+		}
+
+		// 移出所有部门
+		data.DepIds = data.DepIds[:0]
+		for i := range depUsers {
+			data.DepIds = append(data.DepIds, depUsers[i].DepId)
+		}
+		if err = l.svcCtx.DepartmentUserModel.DeleteByUserId(ctx, data.UserId); err != nil {
+			return err
+		}
+
+		// 担任主管的部门需要重新指定主管
+		leads, err := l.svcCtx.DepartmentModel.ListByLeaderId(ctx, data.UserId)
+		if err != nil {
+			return err
+		}
+		data.LeaderDepIds = data.LeaderDepIds[:0]
+		for i := range leads {
+			data.LeaderDepIds = append(data.LeaderDepIds, leads[i].ID.Hex())
+		}
+		if err = l.svcCtx.DepartmentModel.ClearLeader(ctx, data.UserId); err != nil {
+			return err
+		}
+
+		// 停用账号并撤销所有会话和API密钥
+		user.Status = model.UserDisabled
+		if err = l.svcCtx.UserModel.Update(ctx, user); err != nil {
+			return err
+		}
+		if err = l.svcCtx.SessionModel.RevokeByUserId(ctx, data.UserId); err != nil {
+			return err
+		}
+		if err = l.svcCtx.ApiKeyModel.RevokeByUserId(ctx, data.UserId); err != nil {
+			return err
+		}
+
+		if data.HandoverTodoId, err = l.handoverTodo(ctx, data, user, deps); err != nil {
+			return err
+		}
+
+		data.Status = model.OffboardingDone
+		data.FinishAt = time.Now().Unix()
+		return l.svcCtx.OffboardingModel.Update(ctx, data)
+	})
+}
+
+func (l *offboarding) handoverTo(ctx context.Context, user *model.User, depUsers []*model.DepartmentUser,
+	deps map[string]*model.Department) (*model.User, error) {
+	var primary *model.DepartmentUser
+	if len(depUsers) > 0 {
+		primary = depUsers[0]
+	}
+
+	if managerId := reportsTo(user, primary, deps); len(managerId) > 0 {
+		manager, err := l.svcCtx.UserModel.FindOne(ctx, managerId)
+		if err == nil && manager.Status != model.UserDisabled {
+			return manager, nil
+		}
+		if err != nil && !errors.Is(err, model.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	return l.svcCtx.UserModel.FindSysStemUser(ctx)
+}
+
+// transferTodos 将用户创建或执行中的待办转交给交接人
+func (l *offboarding) transferTodos(ctx context.Context, user, manager *model.User) ([]string, error) {
+	uid, mid := user.ID.Hex(), manager.ID.Hex()
+
+	todos, err := l.svcCtx.TodoModel.ListByUserIds(ctx, []string{uid})
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, todo := range todos {
+		if todo.TodoStatus != model.TodoInProgress {
+			continue
+		}
+
+		changed := todo.CreatorId == uid
+		if changed {
+			todo.CreatorId = mid
+		}
+
+		hasManager := slices.ContainsFunc(todo.Executes, func(e *model.UserTodo) bool {
+			return e.UserId == mid
+		})
+		executes := make([]*model.UserTodo, 0, len(todo.Executes))
+		for _, execute := range todo.Executes {
+			if execute.UserId != uid || execute.TodoStatus != model.TodoInProgress {
+				executes = append(executes, execute)
+				continue
+			}
+
+			changed = true
+			if hasManager {
+				continue
+			}
+			executes = append(executes, &model.UserTodo{
+				UserId:     mid,
+				TodoStatus: model.TodoInProgress,
+			})
+			hasManager = true
+		}
+		if !changed {
+			continue
+		}
+
+		todo.Executes = executes
+		todo.Records = append(todo.Records, &model.TodoRecord{
+			UserId:   uid,
+			UserName: user.Name,
+			Content:  fmt.Sprintf("%s 已离职，待办转交给 %s", user.Name, manager.Name),
+			CreateAt: time.Now().Unix(),
+		})
+		if err = l.svcCtx.TodoModel.Update(ctx, todo); err != nil {
+			return nil, err
+		}
+		ids = append(ids, todo.ID.Hex())
+	}
+
+	return ids, nil
+}
+
+// transferApprovals 将用户还未处理的审批节点转交给交接人
+func (l *offboarding) transferApprovals(ctx context.Context, user, manager *model.User) ([]string, error) {
+	uid, mid := user.ID.Hex(), manager.ID.Hex()
+
+	approvals, err := l.svcCtx.ApprovalModel.ListPendingByApprover(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, approval := range approvals {
+		changed := false
+		for i := approval.ApprovalIdx; i < len(approval.Approvers); i++ {
+			approver := approval.Approvers[i]
+			if approver.UserId != uid || approver.Status == model.Pass {
+				continue
+			}
+			approver.UserId = mid
+			approver.UserName = manager.Name
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+
+		if approval.ApprovalId == uid {
+			approval.ApprovalId = mid
+		}
+		if !slices.Contains(approval.Participation, mid) {
+			approval.Participation = append(approval.Participation, mid)
+		}
+		if err = l.svcCtx.ApprovalModel.Update(ctx, approval); err != nil {
+			return nil, err
+		}
+		ids = append(ids, approval.ID.Hex())
+	}
+
+	return ids, nil
+}
+
+// transferReports 直接汇报给离职用户的下级改为汇报给交接人
+func (l *offboarding) transferReports(ctx context.Context, user, manager *model.User) error {
+	users, _, err := l.svcCtx.UserModel.List(ctx, &domain.UserListReq{
+		ManagerId: user.ID.Hex(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		u.ManagerId = manager.ID.Hex()
+		if u.ID == manager.ID {
+			u.ManagerId = ""
+		}
+		if err = l.svcCtx.UserModel.Update(ctx, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handoverTodo 给交接人创建离职交接清单待办
+func (l *offboarding) handoverTodo(ctx context.Context, data *model.Offboarding, user *model.User,
+	deps map[string]*model.Department) (string, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s 已于 %s 离职，请完成以下交接：\n", user.Name, timex.Format(data.LastDay))
+
+	if len(data.TodoIds) > 0 {
+		fmt.Fprintf(&sb, "- 确认转交的 %d 个待办\n", len(data.TodoIds))
+	}
+	if len(data.ApprovalIds) > 0 {
+		fmt.Fprintf(&sb, "- 处理转交的 %d 个审批\n", len(data.ApprovalIds))
+	}
+	for _, id := range data.LeaderDepIds {
+		if dep, ok := deps[id]; ok {
+			fmt.Fprintf(&sb, "- 为【%s】指定新的部门主管\n", dep.Name)
+		}
+	}
+	sb.WriteString("- 回收办公设备与门禁\n")
+	sb.WriteString("- 交接工作文档与账号权限\n")
+
+	todo := &model.Todo{
+		CreatorId:  data.ManagerId,
+		Title:      fmt.Sprintf("%s 的离职交接", user.Name),
+		DeadlineAt: time.Now().Add(handoverDeadline).Unix(),
+		Desc:       sb.String(),
+		Executes: []*model.UserTodo{
+			{
+				UserId:     data.ManagerId,
+				TodoStatus: model.TodoInProgress,
+			},
+		},
+		TodoStatus: model.TodoInProgress,
+	}
+	if err := l.svcCtx.TodoModel.Insert(ctx, todo); err != nil {
+		return "", err
+	}
+	return todo.ID.Hex(), nil
+}
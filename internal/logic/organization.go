@@ -0,0 +1,768 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/encrypt"
+	"ai/pkg/mongox"
+	"ai/pkg/timex"
+	"ai/pkg/xlsx"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 导入导出文件的列，导入时按表头名称匹配列，列的顺序不限
+const (
+	colType         = "类型"
+	colName         = "名称"
+	colParent       = "上级部门"
+	colLeader       = "部门主管"
+	colEmployeeNo   = "工号"
+	colEmail        = "邮箱"
+	colPhone        = "电话"
+	colJobTitle     = "职位名称"
+	colHireDate     = "入职日期"
+	colManager      = "直属上级"
+	colWorkLocation = "工作地点"
+	colDeps         = "所属部门"
+	colPosition     = "部门职位"
+)
+
+const (
+	rowTypeDep  = "部门"
+	rowTypeUser = "用户"
+
+	formatXlsx = "xlsx"
+	formatCsv  = "csv"
+
+	// importDefaultPassword 导入新用户时的初始密码，与创建用户时一致
+	importDefaultPassword = "123456"
+)
+
+var orgColumns = []string{
+	colType, colName, colParent, colLeader, colEmployeeNo, colEmail, colPhone, colJobTitle, colHireDate,
+	colManager, colWorkLocation, colDeps, colPosition,
+}
+
+type Organization interface {
+	Import(ctx context.Context, req *domain.ImportReq) (resp *domain.ImportResp, err error)
+	Export(ctx context.Context, req *domain.ExportReq) (resp *domain.ExportResp, err error)
+}
+
+type organization struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewOrganization(svcCtx *svc.ServiceContext) Organization {
+	return &organization{
+		svcCtx: svcCtx,
+	}
+}
+
+type importDep struct {
+	row    int
+	name   string
+	parent string
+	leader string
+}
+
+type importUser struct {
+	row          int
+	name         string
+	employeeNo   string
+	email        string
+	phone        string
+	jobTitle     string
+	hireDate     int64
+	manager      string
+	workLocation string
+	deps         []string
+	position     string
+}
+
+// orgImport 一次导入解析出的数据以及校验的结果
+type orgImport struct {
+	columns map[string]int
+	deps    []*importDep
+	users   []*importUser
+
+	depByName  map[string]*importDep
+	userByName map[string]*importUser
+
+	dbDeps    map[string]*model.Department
+	dbDepById map[string]*model.Department
+	dbUsers   map[string]*model.User
+
+	errors []*domain.ImportRowError
+}
+
+// Import 导入用户和部门，已存在的用户和部门按名称更新
+// 所有行都校验通过后才会在一个事务中写入，dryRun 时只校验不写入
+func (l *organization) Import(ctx context.Context, req *domain.ImportReq) (resp *domain.ImportResp, err error) {
+	rows, err := readRows(req.Format, req.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	imp, err := l.parse(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+	imp.validate()
+
+	resp = &domain.ImportResp{
+		DryRun: req.DryRun,
+		Total:  len(imp.deps) + len(imp.users),
+		Errors: imp.errors,
+	}
+	for _, d := range imp.deps {
+		if _, ok := imp.dbDeps[d.name]; ok {
+			resp.UpdatedDeps++
+		} else {
+			resp.CreatedDeps++
+		}
+	}
+	for _, u := range imp.users {
+		if _, ok := imp.dbUsers[u.name]; ok {
+			resp.UpdatedUsers++
+		} else {
+			resp.CreatedUsers++
+		}
+	}
+
+	if req.DryRun || len(resp.Errors) > 0 {
+		return resp, nil
+	}
+
+	err = mongox.Transaction(ctx, l.svcCtx.Mongo, func(ctx context.Context) error {
+		return l.apply(ctx, imp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Export 按导入的格式导出所有部门和用户
+func (l *organization) Export(ctx context.Context, req *domain.ExportReq) (resp *domain.ExportResp, err error) {
+	deps, err := l.svcCtx.DepartmentModel.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	depById := make(map[string]*model.Department, len(deps))
+	for i := range deps {
+		depById[deps[i].ID.Hex()] = deps[i]
+	}
+	// 上级部门排在下级部门之前，导入时才能按顺序理解
+	sort.SliceStable(deps, func(i, j int) bool {
+		return deps[i].Level < deps[j].Level
+	})
+
+	userById, err := l.svcCtx.UserModel.AllToMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*model.User, 0, len(userById))
+	uids := make([]string, 0, len(userById))
+	for uid := range userById {
+		users = append(users, userById[uid])
+		uids = append(uids, uid)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].CreateAt != users[j].CreateAt {
+			return users[i].CreateAt < users[j].CreateAt
+		}
+		return users[i].Name < users[j].Name
+	})
+
+	depUsers, err := l.svcCtx.DepartmentUserModel.ListByUserIds(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	userDeps := make(map[string][]*model.DepartmentUser, len(users))
+	for i := range depUsers {
+		userDeps[depUsers[i].UserId] = append(userDeps[depUsers[i].UserId], depUsers[i])
+	}
+
+	nameOf := func(uid string) string {
+		if u, ok := userById[uid]; ok {
+			return u.Name
+		}
+		return ""
+	}
+
+	rows := [][]string{orgColumns}
+	for _, dep := range deps {
+		var parent string
+		if p, ok := depById[dep.ParentId]; ok {
+			parent = p.Name
+		}
+		rows = append(rows, orgRow(map[string]string{
+			colType:   rowTypeDep,
+			colName:   dep.Name,
+			colParent: parent,
+			colLeader: nameOf(dep.LeaderId),
+		}))
+	}
+	for _, u := range users {
+		var (
+			depNames []string
+			position string
+		)
+		for i, du := range userDeps[u.ID.Hex()] {
+			dep, ok := depById[du.DepId]
+			if !ok {
+				continue
+			}
+			if i == 0 {
+				position = du.Position
+			}
+			depNames = append(depNames, dep.Name)
+		}
+
+		var hireDate string
+		if u.HireDate > 0 {
+			hireDate = timex.Format(u.HireDate)
+		}
+
+		rows = append(rows, orgRow(map[string]string{
+			colType:         rowTypeUser,
+			colName:         u.Name,
+			colEmployeeNo:   u.EmployeeNo,
+			colEmail:        u.Email,
+			colPhone:        u.Phone,
+			colJobTitle:     u.JobTitle,
+			colHireDate:     hireDate,
+			colManager:      nameOf(u.ManagerId),
+			colWorkLocation: u.WorkLocation,
+			colDeps:         strings.Join(depNames, ";"),
+			colPosition:     position,
+		}))
+	}
+
+	return writeRows(req.Format, rows)
+}
+
+// parse 解析表格的每一行，并加载已存在的部门和用户用于校验
+func (l *organization) parse(ctx context.Context, rows [][]string) (*orgImport, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("导入的文件为空")
+	}
+
+	imp := &orgImport{
+		columns:    make(map[string]int),
+		depByName:  make(map[string]*importDep),
+		userByName: make(map[string]*importUser),
+	}
+	for i, name := range rows[0] {
+		imp.columns[strings.TrimSpace(name)] = i
+	}
+	for _, col := range []string{colType, colName} {
+		if !imp.has(col) {
+			return nil, fmt.Errorf("导入的文件缺少【%s】列", col)
+		}
+	}
+
+	for i := 1; i < len(rows); i++ {
+		row := rows[i]
+		if isBlankRow(row) {
+			continue
+		}
+
+		no := i + 1
+		name := imp.cell(row, colName)
+		if len(name) == 0 {
+			imp.fail(no, "名称不能为空")
+			continue
+		}
+
+		switch t := imp.cell(row, colType); t {
+		case rowTypeDep:
+			if _, ok := imp.depByName[name]; ok {
+				imp.fail(no, "部门【%s】重复", name)
+				continue
+			}
+			d := &importDep{
+				row:    no,
+				name:   name,
+				parent: imp.cell(row, colParent),
+				leader: imp.cell(row, colLeader),
+			}
+			imp.deps = append(imp.deps, d)
+			imp.depByName[name] = d
+		case rowTypeUser:
+			if _, ok := imp.userByName[name]; ok {
+				imp.fail(no, "用户【%s】重复", name)
+				continue
+			}
+			hireDate, err := parseDate(imp.cell(row, colHireDate))
+			if err != nil {
+				imp.fail(no, "入职日期【%s】格式不正确", imp.cell(row, colHireDate))
+				continue
+			}
+			u := &importUser{
+				row:          no,
+				name:         name,
+				employeeNo:   imp.cell(row, colEmployeeNo),
+				email:        imp.cell(row, colEmail),
+				phone:        imp.cell(row, colPhone),
+				jobTitle:     imp.cell(row, colJobTitle),
+				hireDate:     hireDate,
+				manager:      imp.cell(row, colManager),
+				workLocation: imp.cell(row, colWorkLocation),
+				deps:         splitNames(imp.cell(row, colDeps)),
+				position:     imp.cell(row, colPosition),
+			}
+			imp.users = append(imp.users, u)
+			imp.userByName[name] = u
+		default:
+			imp.fail(no, "类型【%s】不正确，只能是【%s】或【%s】", t, rowTypeDep, rowTypeUser)
+		}
+	}
+
+	deps, err := l.svcCtx.DepartmentModel.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	imp.dbDeps = make(map[string]*model.Department, len(deps))
+	imp.dbDepById = make(map[string]*model.Department, len(deps))
+	for i := range deps {
+		imp.dbDeps[deps[i].Name] = deps[i]
+		imp.dbDepById[deps[i].ID.Hex()] = deps[i]
+	}
+
+	users, err := l.svcCtx.UserModel.AllToMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	imp.dbUsers = make(map[string]*model.User, len(users))
+	for _, u := range users {
+		imp.dbUsers[u.Name] = u
+	}
+
+	return imp, nil
+}
+
+// validate 校验每一行引用的部门和用户是否存在，以及部门层级和汇报关系不能形成环
+func (imp *orgImport) validate() {
+	for _, d := range imp.deps {
+		if d.parent == d.name {
+			imp.fail(d.row, "上级部门不能是自身")
+			continue
+		}
+		if len(d.parent) > 0 && !imp.depExists(d.parent) {
+			imp.fail(d.row, "上级部门【%s】不存在", d.parent)
+		}
+		if len(d.leader) > 0 && !imp.userExists(d.leader) {
+			imp.fail(d.row, "部门主管【%s】不存在", d.leader)
+		}
+
+		if dep, ok := imp.dbDeps[d.name]; ok {
+			var parent string
+			if p, ok := imp.dbDepById[dep.ParentId]; ok {
+				parent = p.Name
+			}
+			if parent != d.parent {
+				imp.fail(d.row, "不能通过导入调整已有部门的上级部门，请使用部门移动")
+			}
+			continue
+		}
+
+		// 已有部门的上级部门不会变化，只需要沿着新部门向上查找
+		visited := make(map[string]bool)
+		for p := d.parent; len(p) > 0 && !visited[p]; {
+			if p == d.name {
+				imp.fail(d.row, "部门的上级关系形成了环")
+				break
+			}
+			visited[p] = true
+
+			pd, ok := imp.depByName[p]
+			if _, exists := imp.dbDeps[p]; !ok || exists {
+				break
+			}
+			p = pd.parent
+		}
+	}
+
+	employeeNos := make(map[string]string)
+	for name, u := range imp.dbUsers {
+		if _, ok := imp.userByName[name]; !ok && len(u.EmployeeNo) > 0 {
+			employeeNos[u.EmployeeNo] = name
+		}
+	}
+
+	for _, u := range imp.users {
+		for _, dep := range u.deps {
+			if !imp.depExists(dep) {
+				imp.fail(u.row, "所属部门【%s】不存在", dep)
+			}
+		}
+		if len(u.manager) > 0 {
+			if u.manager == u.name {
+				imp.fail(u.row, "直属上级不能是自己")
+			} else if !imp.userExists(u.manager) {
+				imp.fail(u.row, "直属上级【%s】不存在", u.manager)
+			} else if imp.managerCycle(u.name) {
+				imp.fail(u.row, "直属上级的汇报关系形成了环")
+			}
+		}
+		if len(u.employeeNo) > 0 {
+			if other, ok := employeeNos[u.employeeNo]; ok {
+				imp.fail(u.row, "工号【%s】已被用户【%s】使用", u.employeeNo, other)
+			} else {
+				employeeNos[u.employeeNo] = u.name
+			}
+		}
+	}
+}
+
+// managerCycle 判断从用户沿着直属上级向上查找是否会回到用户自己
+func (imp *orgImport) managerCycle(name string) bool {
+	dbNames := make(map[string]string, len(imp.dbUsers))
+	for n, u := range imp.dbUsers {
+		dbNames[u.ID.Hex()] = n
+	}
+
+	managerOf := func(n string) string {
+		if u, ok := imp.userByName[n]; ok {
+			return u.manager
+		}
+		if u, ok := imp.dbUsers[n]; ok {
+			return dbNames[u.ManagerId]
+		}
+		return ""
+	}
+
+	visited := make(map[string]bool)
+	for m := managerOf(name); len(m) > 0 && !visited[m]; m = managerOf(m) {
+		if m == name {
+			return true
+		}
+		visited[m] = true
+	}
+	return false
+}
+
+// apply 写入导入的数据，需要在事务中执行
+func (l *organization) apply(ctx context.Context, imp *orgImport) error {
+	password, err := encrypt.GenPasswordHash([]byte(importDefaultPassword))
+	if err != nil {
+		return err
+	}
+
+	// 先写入用户，部门主管和直属上级都需要用户的ID
+	users := make(map[string]*model.User, len(imp.dbUsers)+len(imp.users))
+	for name, u := range imp.dbUsers {
+		users[name] = u
+	}
+	for _, iu := range imp.users {
+		u, exists := users[iu.name]
+		if !exists {
+			u = &model.User{
+				Name:               iu.name,
+				Password:           string(password),
+				MustChangePassword: true,
+			}
+		}
+		imp.setProfile(u, iu)
+
+		if exists {
+			err = l.svcCtx.UserModel.Update(ctx, u)
+		} else {
+			err = l.svcCtx.UserModel.Insert(ctx, u)
+		}
+		if err != nil {
+			return err
+		}
+		users[iu.name] = u
+	}
+
+	uidOf := func(name string) string {
+		if u, ok := users[name]; ok {
+			return u.ID.Hex()
+		}
+		return ""
+	}
+
+	if imp.has(colManager) {
+		for _, iu := range imp.users {
+			u := users[iu.name]
+			if managerId := uidOf(iu.manager); u.ManagerId != managerId {
+				u.ManagerId = managerId
+				if err = l.svcCtx.UserModel.Update(ctx, u); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// 部门按上级部门优先的顺序创建
+	deps := make(map[string]*model.Department, len(imp.dbDeps)+len(imp.deps))
+	for name, dep := range imp.dbDeps {
+		deps[name] = dep
+	}
+
+	var create func(d *importDep) error
+	create = func(d *importDep) error {
+		if _, ok := deps[d.name]; ok {
+			return nil
+		}
+
+		var parentId, parentPath string
+		if len(d.parent) > 0 {
+			if pd, ok := imp.depByName[d.parent]; ok {
+				if err := create(pd); err != nil {
+					return err
+				}
+			}
+			parent := deps[d.parent]
+			parentId, parentPath = parent.ID.Hex(), parent.Path()
+		}
+
+		dep := &model.Department{
+			Name:       d.name,
+			ParentId:   parentId,
+			ParentPath: parentPath,
+			Level:      model.DepartmentLevel(parentPath),
+			LeaderId:   uidOf(d.leader),
+		}
+		if err := l.svcCtx.DepartmentModel.Insert(ctx, dep); err != nil {
+			return err
+		}
+		deps[d.name] = dep
+		return nil
+	}
+
+	for _, d := range imp.deps {
+		if dep, ok := imp.dbDeps[d.name]; ok {
+			if leaderId := uidOf(d.leader); imp.has(colLeader) && dep.LeaderId != leaderId {
+				dep.LeaderId = leaderId
+				dep.NeedLeader = dep.NeedLeader && len(leaderId) == 0
+				if err = l.svcCtx.DepartmentModel.Update(ctx, dep); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err = create(d); err != nil {
+			return err
+		}
+	}
+
+	// 部门主管也是部门的成员
+	for _, d := range imp.deps {
+		if len(d.leader) == 0 {
+			continue
+		}
+		if _, err = l.member(ctx, deps[d.name].ID.Hex(), uidOf(d.leader)); err != nil {
+			return err
+		}
+	}
+
+	// 用户所属的第一个部门为主部门，部门职位设置在主部门上
+	for _, iu := range imp.users {
+		for i, name := range iu.deps {
+			uid := uidOf(iu.name)
+			depUser, err := l.member(ctx, deps[name].ID.Hex(), uid)
+			if err != nil {
+				return err
+			}
+			if i > 0 {
+				continue
+			}
+
+			if imp.has(colPosition) && depUser.Position != iu.position {
+				depUser.Position = iu.position
+				if err = l.svcCtx.DepartmentUserModel.Update(ctx, depUser); err != nil {
+					return err
+				}
+			}
+			if !depUser.IsPrimary {
+				if err = l.svcCtx.DepartmentUserModel.SetPrimary(ctx, depUser.DepId, uid); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// member 确保用户是部门的成员，用户还不属于任何部门时该部门为主部门
+func (l *organization) member(ctx context.Context, depId, uid string) (*model.DepartmentUser, error) {
+	depUser, err := l.svcCtx.DepartmentUserModel.FindByDepAndUser(ctx, depId, uid)
+	if err == nil || !errors.Is(err, model.ErrNotFound) {
+		return depUser, err
+	}
+
+	isPrimary, err := noDepartment(ctx, l.svcCtx, uid)
+	if err != nil {
+		return nil, err
+	}
+	depUser = &model.DepartmentUser{
+		DepId:     depId,
+		UserId:    uid,
+		IsPrimary: isPrimary,
+	}
+	return depUser, l.svcCtx.DepartmentUserModel.Insert(ctx, depUser)
+}
+
+// setProfile 设置用户的档案信息，文件中没有的列保持原值
+func (imp *orgImport) setProfile(u *model.User, iu *importUser) {
+	if imp.has(colEmployeeNo) {
+		u.EmployeeNo = iu.employeeNo
+	}
+	if imp.has(colEmail) {
+		u.Email = iu.email
+	}
+	if imp.has(colPhone) {
+		u.Phone = iu.phone
+	}
+	if imp.has(colJobTitle) {
+		u.JobTitle = iu.jobTitle
+	}
+	if imp.has(colHireDate) {
+		u.HireDate = iu.hireDate
+	}
+	if imp.has(colWorkLocation) {
+		u.WorkLocation = iu.workLocation
+	}
+}
+
+func (imp *orgImport) has(col string) bool {
+	_, ok := imp.columns[col]
+	return ok
+}
+
+func (imp *orgImport) cell(row []string, col string) string {
+	idx, ok := imp.columns[col]
+	if !ok || idx >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[idx])
+}
+
+func (imp *orgImport) fail(row int, format string, args ...any) {
+	imp.errors = append(imp.errors, &domain.ImportRowError{
+		Row:     row,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (imp *orgImport) depExists(name string) bool {
+	if _, ok := imp.depByName[name]; ok {
+		return true
+	}
+	_, ok := imp.dbDeps[name]
+	return ok
+}
+
+func (imp *orgImport) userExists(name string) bool {
+	if _, ok := imp.userByName[name]; ok {
+		return true
+	}
+	_, ok := imp.dbUsers[name]
+	return ok
+}
+
+// readRows 按文件格式读取表格的所有行
+func readRows(format string, data []byte) ([][]string, error) {
+	switch strings.ToLower(format) {
+	case formatXlsx:
+		return xlsx.Read(bytes.NewReader(data), int64(len(data)))
+	case formatCsv:
+		r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+		r.FieldsPerRecord = -1
+		return r.ReadAll()
+	default:
+		return nil, errors.New("只支持xlsx和csv格式的文件")
+	}
+}
+
+// writeRows 按文件格式写出表格，csv 文件带上BOM便于Excel识别编码
+func writeRows(format string, rows [][]string) (*domain.ExportResp, error) {
+	var buf bytes.Buffer
+	resp := &domain.ExportResp{}
+
+	switch strings.ToLower(format) {
+	case formatXlsx, "":
+		if err := xlsx.Write(&buf, "组织架构", rows); err != nil {
+			return nil, err
+		}
+		resp.Filename = "organization.xlsx"
+		resp.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case formatCsv:
+		buf.WriteString("\xef\xbb\xbf")
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(rows); err != nil {
+			return nil, err
+		}
+		resp.Filename = "organization.csv"
+		resp.ContentType = "text/csv; charset=utf-8"
+	default:
+		return nil, errors.New("只支持xlsx和csv格式的文件")
+	}
+
+	resp.Data = buf.Bytes()
+	return resp, nil
+}
+
+func orgRow(values map[string]string) []string {
+	row := make([]string, len(orgColumns))
+	for i, col := range orgColumns {
+		row[i] = values[col]
+	}
+	return row
+}
+
+func isBlankRow(row []string) bool {
+	for _, v := range row {
+		if len(strings.TrimSpace(v)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// splitNames 拆分以分号或逗号分隔的多个名称
+func splitNames(s string) []string {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ';' || r == '；' || r == ',' || r == '，'
+	})
+
+	res := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); len(f) > 0 {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+// parseDate 解析日期，支持常见的日期格式以及Excel中以数字保存的日期
+func parseDate(s string) (int64, error) {
+	if len(s) == 0 {
+		return 0, nil
+	}
+
+	for _, layout := range []string{"2006-01-02", "2006/01/02", "2006.01.02", "2006-1-2", "2006/1/2"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.Unix(), nil
+		}
+	}
+
+	days, err := strconv.ParseFloat(s, 64)
+	if err != nil || days <= 0 {
+		return 0, errors.New("invalid date")
+	}
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.Local).AddDate(0, 0, int(days)).Unix(), nil
+}
@@ -0,0 +1,129 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/token"
+	"context"
+	"errors"
+	"slices"
+)
+
+// maxSubscribe 一个连接最多订阅的用户数
+const maxSubscribe = 500
+
+var ErrSubscribeTooMany = errors.New("订阅的用户过多")
+
+// Presence 用户的在线状态与正在输入提示，状态由websocket服务根据用户的连接维护
+type Presence interface {
+	// Set 更新当前用户的在线状态
+	Set(ctx context.Context, status model.PresenceStatus) (resp *domain.Presence, err error)
+	// Subscribe 确定可以订阅的用户，返回其当前的在线状态
+	Subscribe(ctx context.Context, req *domain.Message) (resp []*domain.Presence, err error)
+	// Typing 返回需要转发正在输入的会话参与者，不包括当前用户
+	Typing(ctx context.Context, req *domain.Message) (uids []string, err error)
+}
+
+type presence struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewPresence(svcCtx *svc.ServiceContext) Presence {
+	return &presence{
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *presence) Set(ctx context.Context, status model.PresenceStatus) (resp *domain.Presence, err error) {
+	uid := token.GetUId(ctx)
+	if err = l.svcCtx.PresenceModel.Set(ctx, uid, status); err != nil {
+		return nil, err
+	}
+
+	presences, err := l.svcCtx.PresenceModel.ListToMaps(ctx, []string{uid})
+	if err != nil {
+		return nil, err
+	}
+	return presences[uid].ToDomain(), nil
+}
+
+// Subscribe 指定 userIds 时订阅同一租户中的这些用户，指定群聊时订阅群成员，都为空时订阅私聊过的联系人
+func (l *presence) Subscribe(ctx context.Context, req *domain.Message) (resp []*domain.Presence, err error) {
+	uid := token.GetUId(ctx)
+
+	var uids []string
+	switch {
+	case len(req.UserIds) > 0:
+		users, err := l.svcCtx.UserModel.ListToMaps(ctx, &domain.UserListReq{Ids: req.UserIds})
+		if err != nil {
+			return nil, err
+		}
+		for id := range users {
+			uids = append(uids, id)
+		}
+	case len(req.ConversationId) > 0:
+		_, members, err := conversationMembers(ctx, l.svcCtx, req.ConversationId)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(members, uid) {
+			return nil, ErrNotConversationMember
+		}
+		uids = members
+	default:
+		conversations, _, err := l.svcCtx.ConversationModel.ListByUser(ctx, uid, 0, maxSubscribe)
+		if err != nil {
+			return nil, err
+		}
+		for i := range conversations {
+			if conversations[i].ChatType == model.SingleChatType {
+				uids = append(uids, conversations[i].PeerId)
+			}
+		}
+	}
+
+	uids = slices.DeleteFunc(uids, func(id string) bool {
+		return id == uid
+	})
+	if len(uids) > maxSubscribe {
+		return nil, ErrSubscribeTooMany
+	}
+	if len(uids) == 0 {
+		return nil, nil
+	}
+
+	presences, err := l.svcCtx.PresenceModel.ListToMaps(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+	resp = make([]*domain.Presence, 0, len(uids))
+	for _, id := range uids {
+		resp = append(resp, presences[id].ToDomain())
+	}
+	return resp, nil
+}
+
+// Typing 私聊可以只填写 recvId，会话还没有消息时也可以转发
+func (l *presence) Typing(ctx context.Context, req *domain.Message) (uids []string, err error) {
+	uid := token.GetUId(ctx)
+	if model.ChatType(req.ChatType) == model.SingleChatType && len(req.RecvId) > 0 {
+		req.ConversationId = GenerateUniqueID(uid, req.RecvId)
+		if req.RecvId == uid {
+			return nil, nil
+		}
+		return []string{req.RecvId}, nil
+	}
+
+	_, members, err := conversationMembers(ctx, l.svcCtx, req.ConversationId)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(members, uid) {
+		return nil, ErrNotConversationMember
+	}
+
+	return slices.DeleteFunc(members, func(id string) bool {
+		return id == uid
+	}), nil
+}
@@ -0,0 +1,217 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Role interface {
+	List(ctx context.Context) (resp *domain.RoleListResp, err error)
+	Create(ctx context.Context, req *domain.Role) (resp *domain.IdResp, err error)
+	Edit(ctx context.Context, req *domain.Role) (err error)
+	Delete(ctx context.Context, req *domain.IdPathReq) (err error)
+	SetTwoFactor(ctx context.Context, req *domain.RoleTwoFactorReq) (err error)
+	UserRoles(ctx context.Context, req *domain.IdPathReq) (resp *domain.UserRoleListResp, err error)
+	Assign(ctx context.Context, req *domain.UserRole) (resp *domain.IdResp, err error)
+	Revoke(ctx context.Context, req *domain.IdPathReq) (err error)
+}
+
+type role struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewRole(svcCtx *svc.ServiceContext) Role {
+	return &role{
+		svcCtx: svcCtx,
+	}
+}
+
+// List 获取所有角色
+func (l *role) List(ctx context.Context) (resp *domain.RoleListResp, err error) {
+	roles, err := l.svcCtx.RoleModel.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*domain.Role, 0, len(roles))
+	for i := range roles {
+		list = append(list, roles[i].ToDomain())
+	}
+	return &domain.RoleListResp{
+		List: list,
+	}, nil
+}
+
+// Create 创建角色
+func (l *role) Create(ctx context.Context, req *domain.Role) (resp *domain.IdResp, err error) {
+	if err = checkRole(req); err != nil {
+		return nil, err
+	}
+
+	_, err = l.svcCtx.RoleModel.FindByCode(ctx, req.Code)
+	if err == nil {
+		return nil, errors.New("已存在该角色编码")
+	}
+	if !errors.Is(err, model.ErrRoleNotFound) {
+		return nil, err
+	}
+
+	r := &model.Role{
+		Code:        req.Code,
+		Name:        req.Name,
+		Permissions: model.ToModelPermissions(req.Permissions),
+
+		RequireTwoFactor: req.RequireTwoFactor,
+	}
+	if err = l.svcCtx.RoleModel.Insert(ctx, r); err != nil {
+		return nil, err
+	}
+
+	return &domain.IdResp{
+		Id: r.ID.Hex(),
+	}, nil
+}
+
+// Edit 修改角色的名称及权限，角色编码不可修改
+func (l *role) Edit(ctx context.Context, req *domain.Role) (err error) {
+	r, err := l.svcCtx.RoleModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+
+	req.Code = r.Code
+	if err = checkRole(req); err != nil {
+		return err
+	}
+	if r.Code == model.RoleAdmin {
+		return errors.New("不能修改管理员角色的权限")
+	}
+
+	r.Name = req.Name
+	r.Permissions = model.ToModelPermissions(req.Permissions)
+	r.RequireTwoFactor = req.RequireTwoFactor
+	return l.svcCtx.RoleModel.Update(ctx, r)
+}
+
+// SetTwoFactor 设置角色是否要求二次验证，管理员角色的权限不能修改，但可以要求二次验证
+func (l *role) SetTwoFactor(ctx context.Context, req *domain.RoleTwoFactorReq) (err error) {
+	r, err := l.svcCtx.RoleModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+
+	r.RequireTwoFactor = req.RequireTwoFactor
+	return l.svcCtx.RoleModel.Update(ctx, r)
+}
+
+// Delete 删除角色，系统预置的角色以及仍分配给用户的角色不能删除
+func (l *role) Delete(ctx context.Context, req *domain.IdPathReq) (err error) {
+	r, err := l.svcCtx.RoleModel.FindOne(ctx, req.Id)
+	if err != nil {
+		if errors.Is(err, model.ErrRoleNotFound) {
+			return nil
+		}
+		return err
+	}
+	if r.IsSystem {
+		return errors.New("不能删除系统预置的角色")
+	}
+
+	count, err := l.svcCtx.UserRoleModel.CountByRoleId(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("该角色仍有用户在使用")
+	}
+
+	return l.svcCtx.RoleModel.Delete(ctx, req.Id)
+}
+
+// UserRoles 获取用户被分配的角色
+func (l *role) UserRoles(ctx context.Context, req *domain.IdPathReq) (resp *domain.UserRoleListResp, err error) {
+	userRoles, err := l.svcCtx.UserRoleModel.ListByUserId(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := l.svcCtx.RoleModel.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roleById := make(map[string]*model.Role, len(roles))
+	for i := range roles {
+		roleById[roles[i].ID.Hex()] = roles[i]
+	}
+
+	list := make([]*domain.UserRole, 0, len(userRoles))
+	for i := range userRoles {
+		list = append(list, userRoles[i].ToDomain(roleById[userRoles[i].RoleId]))
+	}
+	return &domain.UserRoleListResp{
+		List: list,
+	}, nil
+}
+
+// Assign 为用户分配角色，指定部门时角色的权限仅作用于该部门及其下级部门
+func (l *role) Assign(ctx context.Context, req *domain.UserRole) (resp *domain.IdResp, err error) {
+	if _, err = l.svcCtx.UserModel.FindOne(ctx, req.UserId); err != nil {
+		return nil, err
+	}
+	if _, err = l.svcCtx.RoleModel.FindOne(ctx, req.RoleId); err != nil {
+		return nil, err
+	}
+	if len(req.DepId) > 0 {
+		if _, err = l.svcCtx.DepartmentModel.FindOne(ctx, req.DepId); err != nil {
+			return nil, err
+		}
+	}
+
+	userRole, err := l.svcCtx.UserRoleModel.FindOne(ctx, req.UserId, req.RoleId, req.DepId)
+	if err == nil {
+		return &domain.IdResp{
+			Id: userRole.ID.Hex(),
+		}, nil
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		return nil, err
+	}
+
+	userRole = &model.UserRole{
+		UserId: req.UserId,
+		RoleId: req.RoleId,
+		DepId:  req.DepId,
+	}
+	if err = l.svcCtx.UserRoleModel.Insert(ctx, userRole); err != nil {
+		return nil, err
+	}
+
+	return &domain.IdResp{
+		Id: userRole.ID.Hex(),
+	}, nil
+}
+
+// Revoke 撤销用户的角色分配
+func (l *role) Revoke(ctx context.Context, req *domain.IdPathReq) (err error) {
+	if _, err = primitive.ObjectIDFromHex(req.Id); err != nil {
+		return model.ErrInvalidObjectId
+	}
+	return l.svcCtx.UserRoleModel.Delete(ctx, req.Id)
+}
+
+func checkRole(req *domain.Role) error {
+	if len(req.Code) == 0 || len(req.Name) == 0 {
+		return errors.New("角色编码和名称不能为空")
+	}
+	for _, p := range req.Permissions {
+		if len(p.Resource) == 0 || len(p.Action) == 0 {
+			return errors.New("权限的资源和操作不能为空")
+		}
+	}
+	return nil
+}
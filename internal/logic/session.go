@@ -0,0 +1,82 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/encrypt"
+	"ai/token"
+	"context"
+	"time"
+)
+
+const (
+	// refreshTokenBytes 刷新令牌的随机字节数
+	refreshTokenBytes = 32
+	// defaultRefreshExpire 未配置时刷新令牌的有效期
+	defaultRefreshExpire = 30 * 24 * 60 * 60
+)
+
+// newSession 为用户创建登录会话，签发访问令牌和刷新令牌，twoFactor 表示登录时已完成二次验证
+func newSession(ctx context.Context, svcCtx *svc.ServiceContext, uid string, twoFactor bool) (*domain.LoginResp, error) {
+	refreshToken, err := encrypt.RandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	session := &model.Session{
+		UserId:       uid,
+		RefreshToken: encrypt.Sha256(refreshToken),
+		ExpireAt:     now + refreshExpire(svcCtx),
+		TwoFactor:    twoFactor,
+		TenantId:     token.GetTenantId(ctx),
+	}
+	if err = svcCtx.SessionModel.Insert(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return signSession(svcCtx, session, refreshToken, now)
+}
+
+// rotateSession 轮换会话的刷新令牌并签发新的访问令牌
+func rotateSession(ctx context.Context, svcCtx *svc.ServiceContext, session *model.Session) (*domain.LoginResp, error) {
+	refreshToken, err := encrypt.RandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	session.PrevRefreshToken = session.RefreshToken
+	session.RefreshToken = encrypt.Sha256(refreshToken)
+	session.ExpireAt = now + refreshExpire(svcCtx)
+	if err = svcCtx.SessionModel.Update(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return signSession(svcCtx, session, refreshToken, now)
+}
+
+func signSession(svcCtx *svc.ServiceContext, session *model.Session, refreshToken string, now int64) (*domain.LoginResp, error) {
+	expire := svcCtx.Config.Jwt.Expire
+	tok, err := token.GetJwtToken(svcCtx.Config.Jwt.Secret, now, expire, session.UserId, session.ID.Hex(), session.TenantId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LoginResp{
+		Id:            session.UserId,
+		AccessToken:   tok,
+		AccessExpire:  now + expire,
+		RefreshAfter:  now + expire/2,
+		RefreshToken:  refreshToken,
+		RefreshExpire: session.ExpireAt,
+	}, nil
+}
+
+func refreshExpire(svcCtx *svc.ServiceContext) int64 {
+	if svcCtx.Config.Jwt.RefreshExpire > 0 {
+		return svcCtx.Config.Jwt.RefreshExpire
+	}
+	return defaultRefreshExpire
+}
@@ -0,0 +1,370 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/encrypt"
+	"ai/pkg/oidc"
+	"ai/token"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"gitee.com/dn-jinmin/tlog"
+)
+
+const (
+	// ssoStateExpire 单点登录state的有效期（秒），需要在该时间内完成身份提供方的登录
+	ssoStateExpire = 10 * 60
+	// ssoNonceBytes state标识、浏览器绑定值和校验id_token使用的nonce的随机字节数
+	ssoNonceBytes = 16
+	// ssoVerifierBytes PKCE校验码的随机字节数，编码后长度在43到128之间
+	ssoVerifierBytes = 32
+)
+
+var (
+	ErrSsoDisabled     = errors.New("未开启单点登录")
+	ErrSsoStateInvalid = errors.New("单点登录已过期，请重新登录")
+)
+
+// Sso 基于OpenID Connect授权码模式的单点登录
+type Sso interface {
+	Authorize(ctx context.Context, req *domain.SsoAuthorizeReq) (resp *domain.SsoAuthorizeResp, err error)
+	Callback(ctx context.Context, req *domain.SsoCallbackReq) (resp *domain.LoginResp, err error)
+}
+
+type sso struct {
+	svcCtx *svc.ServiceContext
+	user   *user
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+func NewSso(svcCtx *svc.ServiceContext) Sso {
+	return &sso{
+		svcCtx: svcCtx,
+		user: &user{
+			svcCtx: svcCtx,
+		},
+	}
+}
+
+// Authorize 生成身份提供方的登录地址，state中携带随机标识以及登录的租户。
+// nonce和PKCE校验码保存在服务端，并与写入浏览器Cookie的绑定值关联，回调时只能使用一次
+func (l *sso) Authorize(ctx context.Context, req *domain.SsoAuthorizeReq) (resp *domain.SsoAuthorizeResp, err error) {
+	provider, err := l.getProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err = tenantContext(ctx, l.svcCtx, req.Tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	stateId, err := encrypt.RandomToken(ssoNonceBytes)
+	if err != nil {
+		return nil, err
+	}
+	binding, err := encrypt.RandomToken(ssoNonceBytes)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := encrypt.RandomToken(ssoNonceBytes)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := encrypt.RandomToken(ssoVerifierBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	// 清理未完成登录而过期的state
+	if err = l.svcCtx.SsoStateModel.DeleteExpired(ctx, now); err != nil {
+		return nil, err
+	}
+	err = l.svcCtx.SsoStateModel.Insert(ctx, &model.SsoState{
+		State:        encrypt.Sha256(stateId),
+		Binding:      encrypt.Sha256(binding),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpireAt:     now + ssoStateExpire,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := token.GetSsoStateToken(l.svcCtx.Config.Jwt.Secret, now, ssoStateExpire, stateId, token.GetTenantId(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.SsoAuthorizeResp{
+		Url:     provider.AuthCodeURL(state, nonce, verifier),
+		State:   state,
+		Binding: binding,
+	}, nil
+}
+
+// Callback 使用授权码完成登录，首次登录的用户按配置自动创建账号，并按用户组同步部门和角色
+func (l *sso) Callback(ctx context.Context, req *domain.SsoCallbackReq) (resp *domain.LoginResp, err error) {
+	event := &model.LoginEvent{
+		Ip:        req.Ip,
+		UserAgent: req.UserAgent,
+	}
+	defer func() {
+		l.user.recordLogin(ctx, event, err)
+	}()
+
+	provider, err := l.getProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, _, err := token.NewTokenParse(l.svcCtx.Config.Jwt.Secret).ParseToken(req.State)
+	if err != nil {
+		return nil, ErrSsoStateInvalid
+	}
+	stateId, _ := claims[token.SsoIdentify].(string)
+	if len(stateId) == 0 || len(req.Binding) == 0 {
+		return nil, ErrSsoStateInvalid
+	}
+	tid, _ := claims[token.TenantIdentify].(string)
+	ctx = token.WithTenantId(ctx, tid)
+	if err = l.svcCtx.CheckTenant(ctx); err != nil {
+		return nil, err
+	}
+
+	// 取出即删除，state不能重复使用；绑定值不一致说明回调不是发起登录的浏览器
+	state, err := l.svcCtx.SsoStateModel.Take(ctx, encrypt.Sha256(stateId))
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, ErrSsoStateInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if state.ExpireAt < time.Now().Unix() || state.Binding != encrypt.Sha256(req.Binding) {
+		return nil, ErrSsoStateInvalid
+	}
+
+	tok, err := provider.Exchange(ctx, req.Code, state.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	idClaims, err := provider.Verify(ctx, tok.IdToken, state.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	event.Name = ssoUserName(idClaims)
+
+	u, err := l.provision(ctx, idClaims)
+	if err != nil {
+		return nil, err
+	}
+	event.UserId = u.ID.Hex()
+	event.Name = u.Name
+	if u.Status == model.UserDisabled {
+		return nil, errors.New("账号已停用")
+	}
+
+	if err = l.syncGroups(ctx, u.ID.Hex(), idClaims.Groups); err != nil {
+		return nil, err
+	}
+	return l.user.completeLogin(ctx, u, event)
+}
+
+// getProvider 读取身份提供方的发现文档，失败时下次请求重试
+func (l *sso) getProvider(ctx context.Context) (*oidc.Provider, error) {
+	cfg := l.svcCtx.Config.Oidc
+	if !cfg.Enable {
+		return nil, ErrSsoDisabled
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.provider != nil {
+		return l.provider, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, oidc.Config{
+		Issuer:       cfg.Issuer,
+		ClientId:     cfg.ClientId,
+		ClientSecret: cfg.ClientSecret,
+		RedirectUrl:  cfg.RedirectUrl,
+		Scopes:       cfg.Scopes,
+		GroupsClaim:  cfg.GroupsClaim,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	l.provider = provider
+	return provider, nil
+}
+
+// provision 查找单点登录用户对应的账号：先按身份提供方的用户标识查找，租户开启了邮箱关联时再按已验证的邮箱关联已有账号，
+// 都没有时按配置自动创建账号
+func (l *sso) provision(ctx context.Context, claims *oidc.Claims) (*model.User, error) {
+	u, err := l.svcCtx.UserModel.FindByOidcSubject(ctx, claims.Subject)
+	if err == nil {
+		return u, nil
+	}
+	if !errors.Is(err, model.ErrNotUser) {
+		return nil, err
+	}
+
+	linkEmail, err := l.linkEmail(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if linkEmail && len(claims.Email) > 0 && claims.EmailVerified {
+		u, err = l.svcCtx.UserModel.FindByEmail(ctx, claims.Email)
+		switch {
+		case err == nil:
+			u.OidcSubject = claims.Subject
+			if err = l.svcCtx.UserModel.Update(ctx, u); err != nil {
+				return nil, err
+			}
+			return u, nil
+		case !errors.Is(err, model.ErrNotUser):
+			return nil, err
+		}
+	}
+
+	if !l.svcCtx.Config.Oidc.Provision {
+		return nil, errors.New("账号不存在，请联系管理员开通")
+	}
+
+	name := ssoUserName(claims)
+	if len(name) == 0 {
+		return nil, errors.New("身份提供方未返回用户名")
+	}
+	_, err = l.svcCtx.UserModel.FindByName(ctx, name)
+	if err == nil {
+		return nil, errors.New("已存在同名账号，请联系管理员关联账号")
+	}
+	if !errors.Is(err, model.ErrNotUser) {
+		return nil, err
+	}
+
+	// 单点登录的账号不使用本地密码，设置随机密码
+	password, err := encrypt.RandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := encrypt.GenPasswordHash([]byte(password))
+	if err != nil {
+		return nil, err
+	}
+
+	u = &model.User{
+		Name:        name,
+		Password:    string(hash),
+		Status:      model.UserNormal,
+		OidcSubject: claims.Subject,
+		Email:       claims.Email,
+		PasswordAt:  time.Now().Unix(),
+	}
+	if err = l.svcCtx.UserModel.Insert(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// linkEmail 当前租户是否允许按已验证的邮箱关联已有账号，默认租户使用部署的配置，默认不开启
+func (l *sso) linkEmail(ctx context.Context) (bool, error) {
+	t, err := l.svcCtx.CurrentTenant(ctx)
+	if err != nil {
+		return false, err
+	}
+	if t == nil {
+		return l.svcCtx.Config.Oidc.LinkEmail, nil
+	}
+	return t.Config != nil && t.Config.SsoLinkEmail, nil
+}
+
+// syncGroups 按用户组同步配置中对应的部门和角色：在组内的加入部门、分配角色，不在组内的移出部门、收回角色
+func (l *sso) syncGroups(ctx context.Context, uid string, groups []string) error {
+	for _, mapping := range l.svcCtx.Config.Oidc.GroupMappings {
+		in := slices.Contains(groups, mapping.Group)
+
+		if len(mapping.DepName) > 0 {
+			if err := l.syncDepartment(ctx, mapping.DepName, uid, in); err != nil {
+				return err
+			}
+		}
+		if len(mapping.RoleCode) > 0 {
+			if err := l.syncRole(ctx, mapping.RoleCode, uid, in); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// syncDepartment 加入或移出部门，配置的部门不存在时只记录日志，不影响登录
+func (l *sso) syncDepartment(ctx context.Context, depName, uid string, join bool) error {
+	dep, err := l.svcCtx.DepartmentModel.FindByName(ctx, depName)
+	if errors.Is(err, model.ErrDepNotFound) {
+		tlog.ErrorfCtx(ctx, "sso", "group mapped department %v not found", depName)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !join {
+		return removeMember(ctx, l.svcCtx, dep.ID.Hex(), uid)
+	}
+
+	_, err = l.svcCtx.DepartmentUserModel.FindByDepAndUser(ctx, dep.ID.Hex(), uid)
+	if err == nil || !errors.Is(err, model.ErrNotFound) {
+		return err
+	}
+	isPrimary, err := noDepartment(ctx, l.svcCtx, uid)
+	if err != nil {
+		return err
+	}
+	return l.svcCtx.DepartmentUserModel.Insert(ctx, &model.DepartmentUser{
+		DepId:     dep.ID.Hex(),
+		UserId:    uid,
+		IsPrimary: isPrimary,
+	})
+}
+
+// syncRole 分配或收回不限部门的角色，配置的角色不存在时只记录日志，不影响登录
+func (l *sso) syncRole(ctx context.Context, roleCode, uid string, assign bool) error {
+	role, err := l.svcCtx.RoleModel.FindByCode(ctx, roleCode)
+	if errors.Is(err, model.ErrRoleNotFound) {
+		tlog.ErrorfCtx(ctx, "sso", "group mapped role %v not found", roleCode)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	userRole, err := l.svcCtx.UserRoleModel.FindOne(ctx, uid, role.ID.Hex(), "")
+	switch {
+	case err == nil && !assign:
+		return l.svcCtx.UserRoleModel.Delete(ctx, userRole.ID.Hex())
+	case errors.Is(err, model.ErrNotFound) && assign:
+		return l.svcCtx.UserRoleModel.Insert(ctx, &model.UserRole{
+			UserId: uid,
+			RoleId: role.ID.Hex(),
+		})
+	case errors.Is(err, model.ErrNotFound):
+		return nil
+	}
+	return err
+}
+
+func ssoUserName(claims *oidc.Claims) string {
+	for _, name := range []string{claims.PreferredUsername, claims.Name, claims.Email} {
+		if len(name) > 0 {
+			return name
+		}
+	}
+	return ""
+}
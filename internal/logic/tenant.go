@@ -0,0 +1,267 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/encrypt"
+	"ai/pkg/mongox"
+	"ai/token"
+	"context"
+	"errors"
+	"regexp"
+)
+
+var (
+	ErrTenantNotAllowed = errors.New("只有默认租户的管理员可以管理租户")
+	ErrTenantCode       = errors.New("租户编码只能包含小写字母、数字、下划线和中划线，长度2-32位")
+)
+
+// tenantCodeReg 租户编码同时用作知识库索引名的一部分
+var tenantCodeReg = regexp.MustCompile(`^[a-z0-9_-]{2,32}$`)
+
+// Tenant 租户的开通与配置，开通租户只能由默认租户的管理员操作，租户管理员可以修改本租户的配置
+type Tenant interface {
+	Create(ctx context.Context, req *domain.TenantCreateReq) (resp *domain.IdResp, err error)
+	Info(ctx context.Context, req *domain.IdPathReq) (resp *domain.Tenant, err error)
+	Edit(ctx context.Context, req *domain.TenantEditReq) (err error)
+	List(ctx context.Context, req *domain.TenantListReq) (resp *domain.TenantListResp, err error)
+	Config(ctx context.Context) (resp *domain.TenantConfig, err error)
+	EditConfig(ctx context.Context, req *domain.TenantConfig) (err error)
+}
+
+type tenant struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewTenant(svcCtx *svc.ServiceContext) Tenant {
+	return &tenant{
+		svcCtx: svcCtx,
+	}
+}
+
+// Create 开通租户，初始化租户的预置角色并创建租户管理员
+func (l *tenant) Create(ctx context.Context, req *domain.TenantCreateReq) (resp *domain.IdResp, err error) {
+	if err = l.authorize(ctx, model.ActionCreate); err != nil {
+		return nil, err
+	}
+	if !tenantCodeReg.MatchString(req.Code) {
+		return nil, ErrTenantCode
+	}
+	if len(req.Name) == 0 {
+		return nil, errors.New("请填写租户名称")
+	}
+	if len(req.AdminName) == 0 || len(req.AdminPassword) == 0 {
+		return nil, errors.New("请填写租户管理员的账号和密码")
+	}
+
+	_, err = l.svcCtx.TenantModel.FindByCode(ctx, req.Code)
+	if err == nil {
+		return nil, errors.New("已存在该租户编码")
+	}
+	if !errors.Is(err, model.ErrTenantNotFound) {
+		return nil, err
+	}
+
+	data := &model.Tenant{
+		Code:   req.Code,
+		Name:   req.Name,
+		Status: model.TenantNormal,
+	}
+	if req.Config != nil {
+		data.Config = tenantConfig(req.Config, nil)
+	}
+
+	// 租户、预置角色和租户管理员在同一事务中创建，避免留下没有管理员的租户
+	err = mongox.Transaction(ctx, l.svcCtx.Mongo, func(ctx context.Context) error {
+		if err := l.svcCtx.TenantModel.Insert(ctx, data); err != nil {
+			return err
+		}
+
+		// 之后的数据都写入新租户
+		tctx := token.WithTenantId(ctx, data.ID.Hex())
+		if err := l.svcCtx.InitRoles(tctx); err != nil {
+			return err
+		}
+		return l.initAdmin(tctx, req.AdminName, req.AdminPassword)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.IdResp{
+		Id: data.ID.Hex(),
+	}, nil
+}
+
+func (l *tenant) Info(ctx context.Context, req *domain.IdPathReq) (resp *domain.Tenant, err error) {
+	if err = l.authorize(ctx, model.ActionRead); err != nil {
+		return nil, err
+	}
+
+	data, err := l.svcCtx.TenantModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return data.ToDomain(), nil
+}
+
+// Edit 修改租户名称及状态，停用后租户下的用户不能登录，已登录的令牌随即失效
+func (l *tenant) Edit(ctx context.Context, req *domain.TenantEditReq) (err error) {
+	if err = l.authorize(ctx, model.ActionUpdate); err != nil {
+		return err
+	}
+
+	data, err := l.svcCtx.TenantModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+	if len(req.Name) > 0 {
+		data.Name = req.Name
+	}
+	if req.Status != nil {
+		if *req.Status != model.TenantNormal && *req.Status != model.TenantDisabled {
+			return errors.New("租户状态错误")
+		}
+		data.Status = *req.Status
+	}
+	return l.svcCtx.TenantModel.Update(ctx, data)
+}
+
+func (l *tenant) List(ctx context.Context, req *domain.TenantListReq) (resp *domain.TenantListResp, err error) {
+	if err = l.authorize(ctx, model.ActionRead); err != nil {
+		return nil, err
+	}
+
+	tenants, count, err := l.svcCtx.TenantModel.List(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*domain.Tenant, 0, len(tenants))
+	for i := range tenants {
+		list = append(list, tenants[i].ToDomain())
+	}
+	return &domain.TenantListResp{
+		Count: count,
+		List:  list,
+	}, nil
+}
+
+// Config 当前租户的配置
+func (l *tenant) Config(ctx context.Context) (resp *domain.TenantConfig, err error) {
+	if err = l.svcCtx.Authorize(ctx, model.ResourceTenant, model.ActionRead, ""); err != nil {
+		return nil, err
+	}
+
+	data, err := l.currentTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if data.Config == nil {
+		return &domain.TenantConfig{}, nil
+	}
+	return data.Config.ToDomain(), nil
+}
+
+// EditConfig 修改当前租户的配置
+func (l *tenant) EditConfig(ctx context.Context, req *domain.TenantConfig) (err error) {
+	if err = l.svcCtx.Authorize(ctx, model.ResourceTenant, model.ActionUpdate, ""); err != nil {
+		return err
+	}
+
+	data, err := l.currentTenant(ctx)
+	if err != nil {
+		return err
+	}
+	data.Config = tenantConfig(req, data.Config)
+	return l.svcCtx.TenantModel.Update(ctx, data)
+}
+
+// authorize 租户的开通和管理只能由默认租户中具有租户权限的用户操作
+func (l *tenant) authorize(ctx context.Context, action string) error {
+	if len(token.GetTenantId(ctx)) > 0 {
+		return ErrTenantNotAllowed
+	}
+	return l.svcCtx.Authorize(ctx, model.ResourceTenant, action, "")
+}
+
+func (l *tenant) currentTenant(ctx context.Context) (*model.Tenant, error) {
+	data, err := l.svcCtx.CurrentTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, errors.New("默认租户使用部署配置，不能在线修改")
+	}
+	return data, nil
+}
+
+// initAdmin 创建租户管理员并分配管理员角色
+func (l *tenant) initAdmin(ctx context.Context, name, password string) error {
+	hash, err := encrypt.GenPasswordHash([]byte(password))
+	if err != nil {
+		return err
+	}
+
+	u := &model.User{
+		Name:               name,
+		Password:           string(hash),
+		Status:             model.UserNormal,
+		IsSystem:           true,
+		MustChangePassword: true,
+	}
+	if err = l.svcCtx.UserModel.Insert(ctx, u); err != nil {
+		return err
+	}
+
+	admin, err := l.svcCtx.RoleModel.FindByCode(ctx, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	return l.svcCtx.UserRoleModel.Insert(ctx, &model.UserRole{
+		UserId: u.ID.Hex(),
+		RoleId: admin.ID.Hex(),
+	})
+}
+
+// tenantConfig 转换租户配置，大模型的密钥为空时保留原有的密钥
+func tenantConfig(req *domain.TenantConfig, old *model.TenantConfig) *model.TenantConfig {
+	res := &model.TenantConfig{
+		SsoLinkEmail: req.SsoLinkEmail,
+	}
+	if req.Llm != nil && len(req.Llm.Url) > 0 {
+		res.Llm = &model.TenantLlm{
+			Url:    req.Llm.Url,
+			ApiKey: req.Llm.ApiKey,
+			Model:  req.Llm.Model,
+		}
+		if len(res.Llm.ApiKey) == 0 && old != nil && old.Llm != nil {
+			res.Llm.ApiKey = old.Llm.ApiKey
+		}
+	}
+	for _, tpl := range req.ApprovalTemplates {
+		res.ApprovalTemplates = append(res.ApprovalTemplates, &model.ApprovalTemplate{
+			Type:      model.ApprovalType(tpl.Type),
+			Disabled:  tpl.Disabled,
+			Approvers: tpl.Approvers,
+		})
+	}
+	return res
+}
+
+// tenantContext 登录时按租户编码确定租户，编码为空时为默认租户
+func tenantContext(ctx context.Context, svcCtx *svc.ServiceContext, code string) (context.Context, error) {
+	if len(code) == 0 {
+		return token.WithTenantId(ctx, ""), nil
+	}
+
+	t, err := svcCtx.TenantModel.FindByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status == model.TenantDisabled {
+		return nil, svc.ErrTenantDisabled
+	}
+	return token.WithTenantId(ctx, t.ID.Hex()), nil
+}
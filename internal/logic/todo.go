@@ -9,6 +9,7 @@ import (
 
 	"gitee.com/dn-jinmin/tlog"
 	"github.com/jinzhu/copier"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"ai/internal/domain"
@@ -109,7 +110,7 @@ func (l *todo) Create(ctx context.Context, req *domain.Todo) (resp *domain.IdRes
 	tlog.InfoCtx(ctx, "create todo insert", req)
 
 	id := primitive.NewObjectID()
-	err = l.svcCtx.TodoModel.Insert(ctx, &model.Todo{
+	data := &model.Todo{
 		ID:         id,
 		CreatorId:  uid,
 		Title:      req.Title,
@@ -120,10 +121,11 @@ func (l *todo) Create(ctx context.Context, req *domain.Todo) (resp *domain.IdRes
 		TodoStatus: model.TodoInProgress,
 		CreateAt:   time.Now().Unix(),
 		UpdateAt:   time.Now().Unix(),
-	})
-	if err != nil {
+	}
+	if err = l.svcCtx.TodoModel.Insert(ctx, data); err != nil {
 		return
 	}
+	l.svcCtx.Audit(ctx, model.ActionCreate, model.ResourceTodo, id.Hex(), nil, data)
 
 	return &domain.IdResp{
 		Id: id.Hex(),
@@ -147,7 +149,11 @@ func (l *todo) Delete(ctx context.Context, req *domain.IdPathReq) (err error) {
 		return errors.New("你不能删除该待办事项")
 	}
 
-	return l.svcCtx.TodoModel.Delete(ctx, req.Id)
+	if err = l.svcCtx.TodoModel.Delete(ctx, req.Id); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionDelete, model.ResourceTodo, req.Id, todo, nil)
+	return nil
 }
 
 func (l *todo) Finish(ctx context.Context, req *domain.FinishedTodoReq) (err error) {
@@ -172,7 +178,12 @@ func (l *todo) Finish(ctx context.Context, req *domain.FinishedTodoReq) (err err
 		}
 	}
 
-	return l.svcCtx.TodoModel.UpdateFinished(ctx, todo, isAllFinished)
+	if err = l.svcCtx.TodoModel.UpdateFinished(ctx, todo, isAllFinished); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceTodo, req.TodoId, nil,
+		bson.M{"finishedUserId": req.UserId, "allFinished": isAllFinished})
+	return nil
 }
 
 func (l *todo) CreateRecord(ctx context.Context, req *domain.TodoRecord) (err error) {
@@ -189,7 +200,11 @@ func (l *todo) CreateRecord(ctx context.Context, req *domain.TodoRecord) (err er
 
 	todo.Records = append(todo.Records, &record)
 
-	return l.svcCtx.TodoModel.Update(ctx, todo)
+	if err = l.svcCtx.TodoModel.Update(ctx, todo); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceTodo, req.TodoId, nil, bson.M{"record": &record})
+	return nil
 }
 
 func (l *todo) List(ctx context.Context, req *domain.TodoListReq) (resp *domain.TodoListResp, err error) {
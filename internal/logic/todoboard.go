@@ -0,0 +1,421 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/token"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type TodoBoard interface {
+	Board(ctx context.Context, req *domain.TodoBoardReq) (resp *domain.TodoBoardResp, err error)
+	Move(ctx context.Context, req *domain.MoveTodoReq) (err error)
+	ColumnList(ctx context.Context, req *domain.TodoColumnListReq) (resp *domain.TodoColumnListResp, err error)
+	CreateColumn(ctx context.Context, req *domain.TodoColumn) (resp *domain.IdResp, err error)
+	EditColumn(ctx context.Context, req *domain.TodoColumn) (err error)
+	DeleteColumn(ctx context.Context, req *domain.IdPathReq) (err error)
+}
+
+type todoBoard struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewTodoBoard(svcCtx *svc.ServiceContext) TodoBoard {
+	return &todoBoard{
+		svcCtx: svcCtx,
+	}
+}
+
+// Board 看板查询，按列分组当前用户或部门的待办
+func (l *todoBoard) Board(ctx context.Context, req *domain.TodoBoardReq) (resp *domain.TodoBoardResp, err error) {
+	uid := token.GetUId(ctx)
+	if err = l.checkScope(ctx, uid, req.DepId, false); err != nil {
+		return nil, err
+	}
+
+	columns, groups, err := l.load(ctx, uid, req.DepId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	resp = &domain.TodoBoardResp{
+		DepId:   req.DepId,
+		Columns: make([]*domain.TodoBoardColumn, 0, len(columns)),
+	}
+	for _, column := range columns {
+		todos := groups[column.ID.Hex()]
+		list := make([]*domain.Todo, 0, len(todos))
+		for i := range todos {
+			t := todos[i].ToDomainTodo()
+			t.TodoStatus = int(todos[i].CurrentStatus(now))
+			list = append(list, t)
+		}
+
+		resp.Columns = append(resp.Columns, &domain.TodoBoardColumn{
+			Id:         column.ID.Hex(),
+			Name:       column.Name,
+			TodoStatus: int(column.TodoStatus),
+			Sort:       column.Sort,
+			Count:      int64(len(list)),
+			List:       list,
+		})
+	}
+
+	return resp, nil
+}
+
+// Move 将待办移动到指定列的指定位置，跨状态的移动需要校验状态流转并记录到待办的操作记录中
+func (l *todoBoard) Move(ctx context.Context, req *domain.MoveTodoReq) (err error) {
+	uid := token.GetUId(ctx)
+
+	todo, err := l.svcCtx.TodoModel.FindOne(ctx, req.TodoId)
+	if err != nil {
+		return err
+	}
+	if !isTodoParticipant(todo, uid) {
+		return errors.New("你不能移动该待办事项")
+	}
+
+	column, err := l.svcCtx.TodoColumnModel.FindOne(ctx, req.ColumnId)
+	if err != nil {
+		return err
+	}
+	if len(column.DepId) == 0 && column.UserId != uid {
+		return errors.New("不能操作其他用户的看板")
+	}
+	if err = l.checkScope(ctx, uid, column.DepId, false); err != nil {
+		return err
+	}
+
+	_, groups, err := l.load(ctx, column.UserId, column.DepId)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	from, to := todo.CurrentStatus(now), column.TodoStatus
+	if !from.CanTransit(to) {
+		return fmt.Errorf("待办不能从【%s】移动到【%s】", from.ToString(), to.ToString())
+	}
+
+	if from != to {
+		user, err := l.svcCtx.UserModel.FindOne(ctx, uid)
+		if err != nil {
+			return err
+		}
+
+		// 创建人可以修改整个待办的状态；执行人只能完成自己的部分，所有执行人都完成后待办才完成
+		content := fmt.Sprintf("将待办从【%s】移动到【%s】", from.ToString(), to.ToString())
+		switch {
+		case todo.CreatorId == uid:
+			todo.TodoStatus = to
+			for i := range todo.Executes {
+				todo.Executes[i].TodoStatus = to
+			}
+		case to == model.TodoFinish:
+			content = "完成了自己执行的部分"
+			if finishExecute(todo, uid) {
+				todo.TodoStatus = to
+				content = "完成了自己执行的部分，待办已全部完成"
+			}
+		default:
+			return fmt.Errorf("只有创建人可以将待办移动到【%s】", to.ToString())
+		}
+		todo.Records = append(todo.Records, &model.TodoRecord{
+			UserId:   uid,
+			UserName: user.Name,
+			Content:  content,
+			CreateAt: now,
+		})
+
+		// 其他执行人未完成时待办仍在原状态的列中，不调整目标列的排序
+		if todo.TodoStatus != to {
+			return l.svcCtx.TodoModel.Update(ctx, todo)
+		}
+	}
+
+	// 列内重新排序：移除当前待办后插入到目标位置
+	siblings := make([]*model.Todo, 0, len(groups[req.ColumnId])+1)
+	for _, t := range groups[req.ColumnId] {
+		if t.ID != todo.ID {
+			siblings = append(siblings, t)
+		}
+	}
+
+	idx := req.Sort
+	if idx < 0 {
+		idx = 0
+	}
+	if idx > len(siblings) {
+		idx = len(siblings)
+	}
+	siblings = append(siblings[:idx], append([]*model.Todo{todo}, siblings[idx:]...)...)
+
+	for i, t := range siblings {
+		if t.ID == todo.ID || t.Sort == i {
+			continue
+		}
+		if err = l.svcCtx.TodoModel.UpdateSort(ctx, t.ID, i); err != nil {
+			return err
+		}
+	}
+
+	todo.ColumnId = column.ID.Hex()
+	todo.Sort = idx
+	return l.svcCtx.TodoModel.Update(ctx, todo)
+}
+
+// ColumnList 获取看板的列
+func (l *todoBoard) ColumnList(ctx context.Context, req *domain.TodoColumnListReq) (resp *domain.TodoColumnListResp, err error) {
+	uid := token.GetUId(ctx)
+	if err = l.checkScope(ctx, uid, req.DepId, false); err != nil {
+		return nil, err
+	}
+
+	columns, err := l.columns(ctx, uid, req.DepId)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*domain.TodoColumn, 0, len(columns))
+	for i := range columns {
+		list = append(list, columns[i].ToDomain())
+	}
+
+	return &domain.TodoColumnListResp{
+		List: list,
+	}, nil
+}
+
+// CreateColumn 创建看板列
+func (l *todoBoard) CreateColumn(ctx context.Context, req *domain.TodoColumn) (resp *domain.IdResp, err error) {
+	uid := token.GetUId(ctx)
+	if err = l.checkScope(ctx, uid, req.DepId, true); err != nil {
+		return nil, err
+	}
+	if err = checkColumn(req); err != nil {
+		return nil, err
+	}
+
+	// 先确保默认列已经生成，避免新建的列覆盖掉默认看板
+	if _, err = l.columns(ctx, uid, req.DepId); err != nil {
+		return nil, err
+	}
+
+	column := &model.TodoColumn{
+		Name:       req.Name,
+		DepId:      req.DepId,
+		TodoStatus: model.TodoStatus(req.TodoStatus),
+		Sort:       req.Sort,
+	}
+	if len(req.DepId) == 0 {
+		column.UserId = uid
+	}
+
+	if err = l.svcCtx.TodoColumnModel.Insert(ctx, column); err != nil {
+		return nil, err
+	}
+
+	return &domain.IdResp{
+		Id: column.ID.Hex(),
+	}, nil
+}
+
+// EditColumn 修改看板列
+func (l *todoBoard) EditColumn(ctx context.Context, req *domain.TodoColumn) (err error) {
+	uid := token.GetUId(ctx)
+
+	column, err := l.svcCtx.TodoColumnModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+	if err = l.checkColumnOwner(ctx, uid, column); err != nil {
+		return err
+	}
+	if err = checkColumn(req); err != nil {
+		return err
+	}
+
+	column.Name = req.Name
+	column.TodoStatus = model.TodoStatus(req.TodoStatus)
+	column.Sort = req.Sort
+
+	return l.svcCtx.TodoColumnModel.Update(ctx, column)
+}
+
+// DeleteColumn 删除看板列，列中的待办会回到同状态的其他列中
+func (l *todoBoard) DeleteColumn(ctx context.Context, req *domain.IdPathReq) (err error) {
+	uid := token.GetUId(ctx)
+
+	column, err := l.svcCtx.TodoColumnModel.FindOne(ctx, req.Id)
+	if err != nil {
+		if errors.Is(err, model.ErrColumnNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err = l.checkColumnOwner(ctx, uid, column); err != nil {
+		return err
+	}
+
+	return l.svcCtx.TodoColumnModel.Delete(ctx, req.Id)
+}
+
+// columns 获取看板的列，看板还没有列时生成默认列
+func (l *todoBoard) columns(ctx context.Context, uid, depId string) ([]*model.TodoColumn, error) {
+	columns, err := l.svcCtx.TodoColumnModel.ListByScope(ctx, uid, depId)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) > 0 {
+		return columns, nil
+	}
+
+	owner := uid
+	if len(depId) > 0 {
+		owner = ""
+	}
+	columns = model.DefaultTodoColumns(owner, depId)
+	if err = l.svcCtx.TodoColumnModel.Inserts(ctx, columns); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// load 加载看板的列以及按列分组的待办
+// 待办所在的列不属于该看板或与待办当前状态不一致时，放到同状态的第一列中
+func (l *todoBoard) load(ctx context.Context, uid, depId string) ([]*model.TodoColumn, map[string][]*model.Todo, error) {
+	columns, err := l.columns(ctx, uid, depId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	uids := []string{uid}
+	if len(depId) > 0 {
+		if uids, err = l.depUserIds(ctx, depId); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	todos, err := l.svcCtx.TodoModel.ListByUserIds(ctx, uids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		now         = time.Now().Unix()
+		columnById  = make(map[string]*model.TodoColumn, len(columns))
+		statusFirst = make(map[model.TodoStatus]*model.TodoColumn)
+		groups      = make(map[string][]*model.Todo, len(columns))
+	)
+	for _, column := range columns {
+		columnById[column.ID.Hex()] = column
+		if _, ok := statusFirst[column.TodoStatus]; !ok {
+			statusFirst[column.TodoStatus] = column
+		}
+	}
+
+	for _, todo := range todos {
+		status := todo.CurrentStatus(now)
+		column, ok := columnById[todo.ColumnId]
+		if !ok || column.TodoStatus != status {
+			if column, ok = statusFirst[status]; !ok {
+				continue
+			}
+		}
+		groups[column.ID.Hex()] = append(groups[column.ID.Hex()], todo)
+	}
+
+	return columns, groups, nil
+}
+
+// checkScope 校验用户对看板的权限，部门看板需要是部门成员，修改部门看板需要是部门主管或具有部门的管理权限
+func (l *todoBoard) checkScope(ctx context.Context, uid, depId string, edit bool) error {
+	if len(depId) == 0 {
+		return nil
+	}
+
+	dep, err := l.svcCtx.DepartmentModel.FindOne(ctx, depId)
+	if err != nil {
+		return err
+	}
+	if dep.LeaderId == uid || l.svcCtx.Authorize(ctx, model.ResourceDepartment, model.ActionUpdate, depId) == nil {
+		return nil
+	}
+	if edit {
+		return errors.New("只有部门主管可以修改部门看板")
+	}
+
+	uids, err := l.depUserIds(ctx, depId)
+	if err != nil {
+		return err
+	}
+	for _, id := range uids {
+		if id == uid {
+			return nil
+		}
+	}
+	return errors.New("你不是该部门的成员")
+}
+
+func (l *todoBoard) checkColumnOwner(ctx context.Context, uid string, column *model.TodoColumn) error {
+	if len(column.DepId) == 0 && column.UserId != uid {
+		return errors.New("不能操作其他用户的看板")
+	}
+	return l.checkScope(ctx, uid, column.DepId, true)
+}
+
+func (l *todoBoard) depUserIds(ctx context.Context, depId string) ([]string, error) {
+	depUsers, err := l.svcCtx.DepartmentUserModel.List(ctx, &domain.DepartmentListReq{DepId: depId})
+	if err != nil {
+		return nil, err
+	}
+
+	uids := make([]string, 0, len(depUsers))
+	for i := range depUsers {
+		uids = append(uids, depUsers[i].UserId)
+	}
+	return uids, nil
+}
+
+func checkColumn(req *domain.TodoColumn) error {
+	if len(req.Name) == 0 {
+		return errors.New("看板列名称不能为空")
+	}
+	if len(model.TodoStatus(req.TodoStatus).ToString()) == 0 {
+		return errors.New("看板列映射的待办状态不存在")
+	}
+	return nil
+}
+
+// isTodoParticipant 判断用户是否为待办的创建人或执行人
+// finishExecute 将用户执行的部分标记为已完成，返回所有执行人是否都已完成
+func finishExecute(todo *model.Todo, uid string) bool {
+	allFinished := true
+	for i := range todo.Executes {
+		if todo.Executes[i].UserId == uid {
+			todo.Executes[i].TodoStatus = model.TodoFinish
+		}
+		if todo.Executes[i].TodoStatus != model.TodoFinish {
+			allFinished = false
+		}
+	}
+	return allFinished
+}
+
+func isTodoParticipant(todo *model.Todo, uid string) bool {
+	if todo.CreatorId == uid {
+		return true
+	}
+	for i := range todo.Executes {
+		if todo.Executes[i].UserId == uid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package logic
+
+import (
+	"ai/internal/domain"
+	"ai/internal/model"
+	"ai/internal/svc"
+	"ai/pkg/encrypt"
+	"ai/pkg/totp"
+	"ai/token"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// recoveryCodeCount 每次生成的恢复码数量
+	recoveryCodeCount = 10
+	// recoveryCodeBytes 恢复码的随机字节数
+	recoveryCodeBytes = 5
+)
+
+var ErrTwoFactorCode = errors.New("验证码不正确")
+
+// TwoFactor 基于TOTP的二次验证
+type TwoFactor interface {
+	Enroll(ctx context.Context) (resp *domain.TwoFactorEnrollResp, err error)
+	Activate(ctx context.Context, req *domain.TwoFactorCodeReq) (resp *domain.RecoveryCodesResp, err error)
+	Disable(ctx context.Context, req *domain.TwoFactorCodeReq) (err error)
+	RecoveryCodes(ctx context.Context, req *domain.TwoFactorCodeReq) (resp *domain.RecoveryCodesResp, err error)
+	Reset(ctx context.Context, req *domain.IdPathReq) (err error)
+}
+
+type twoFactor struct {
+	svcCtx *svc.ServiceContext
+}
+
+func NewTwoFactor(svcCtx *svc.ServiceContext) TwoFactor {
+	return &twoFactor{
+		svcCtx: svcCtx,
+	}
+}
+
+// Enroll 生成待确认的二次验证密钥，使用验证器应用扫描后调用 Activate 开启
+func (l *twoFactor) Enroll(ctx context.Context) (resp *domain.TwoFactorEnrollResp, err error) {
+	u, err := l.svcCtx.UserModel.FindOne(ctx, token.GetUId(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if u.TotpEnabled {
+		return nil, errors.New("已开启二次验证")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err = l.svcCtx.UserModel.UpdateTotp(ctx, u.ID, secret, false, nil); err != nil {
+		return nil, err
+	}
+
+	issuer := l.svcCtx.Config.TwoFactor.Issuer
+	if len(issuer) == 0 {
+		issuer = l.svcCtx.Config.Name
+	}
+	return &domain.TwoFactorEnrollResp{
+		Secret: secret,
+		Uri:    totp.URI(issuer, u.Name, secret),
+	}, nil
+}
+
+// Activate 校验验证码后开启二次验证并生成恢复码，当前会话视为已完成二次验证
+func (l *twoFactor) Activate(ctx context.Context, req *domain.TwoFactorCodeReq) (resp *domain.RecoveryCodesResp, err error) {
+	u, err := l.svcCtx.UserModel.FindOne(ctx, token.GetUId(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if u.TotpEnabled {
+		return nil, errors.New("已开启二次验证")
+	}
+	if len(u.TotpSecret) == 0 {
+		return nil, errors.New("请先获取二次验证密钥")
+	}
+	ok, err := verifyTotp(ctx, l.svcCtx, u, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = l.svcCtx.UserModel.UpdateTotp(ctx, u.ID, u.TotpSecret, true, hashes); err != nil {
+		return nil, err
+	}
+	if err = l.svcCtx.SessionModel.SetTwoFactor(ctx, token.GetSessionId(ctx)); err != nil {
+		return nil, err
+	}
+
+	return &domain.RecoveryCodesResp{
+		Codes: codes,
+	}, nil
+}
+
+// Disable 关闭二次验证，角色要求二次验证时不能关闭
+func (l *twoFactor) Disable(ctx context.Context, req *domain.TwoFactorCodeReq) (err error) {
+	u, err := l.svcCtx.UserModel.FindOne(ctx, token.GetUId(ctx))
+	if err != nil {
+		return err
+	}
+	if !u.TotpEnabled {
+		return nil
+	}
+
+	required, err := l.svcCtx.RequireTwoFactor(ctx, u.ID.Hex())
+	if err != nil {
+		return err
+	}
+	if required {
+		return errors.New("角色要求开启二次验证，不能关闭")
+	}
+
+	ok, err := verifyTwoFactor(ctx, l.svcCtx, u, req.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTwoFactorCode
+	}
+	return l.svcCtx.UserModel.UpdateTotp(ctx, u.ID, "", false, nil)
+}
+
+// RecoveryCodes 重新生成恢复码，之前的恢复码全部失效
+func (l *twoFactor) RecoveryCodes(ctx context.Context, req *domain.TwoFactorCodeReq) (resp *domain.RecoveryCodesResp, err error) {
+	u, err := l.svcCtx.UserModel.FindOne(ctx, token.GetUId(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if !u.TotpEnabled {
+		return nil, errors.New("未开启二次验证")
+	}
+	ok, err := verifyTotp(ctx, l.svcCtx, u, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = l.svcCtx.UserModel.UpdateTotp(ctx, u.ID, u.TotpSecret, true, hashes); err != nil {
+		return nil, err
+	}
+	return &domain.RecoveryCodesResp{
+		Codes: codes,
+	}, nil
+}
+
+// Reset 管理员重置用户的二次验证，用于用户丢失验证设备且没有恢复码的情况，同时撤销用户所有的会话
+func (l *twoFactor) Reset(ctx context.Context, req *domain.IdPathReq) (err error) {
+	u, err := l.svcCtx.UserModel.FindOne(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+	if err = l.svcCtx.UserModel.UpdateTotp(ctx, u.ID, "", false, nil); err != nil {
+		return err
+	}
+	return l.svcCtx.SessionModel.RevokeByUserId(ctx, req.Id)
+}
+
+// verifyTwoFactor 校验验证器应用的验证码或恢复码，恢复码使用后失效
+func verifyTwoFactor(ctx context.Context, svcCtx *svc.ServiceContext, u *model.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if ok, err := verifyTotp(ctx, svcCtx, u, code); ok || err != nil {
+		return ok, err
+	}
+
+	// 条件更新移除恢复码，并发使用同一个恢复码时只有一个成功
+	hash := encrypt.Sha256(normalizeRecoveryCode(code))
+	if !slices.Contains(u.RecoveryCodes, hash) {
+		return false, nil
+	}
+	return svcCtx.UserModel.UseRecoveryCode(ctx, u.ID, hash)
+}
+
+// verifyTotp 校验验证器应用的验证码，同一时间步及之前的验证码通过校验后不能再次使用
+func verifyTotp(ctx context.Context, svcCtx *svc.ServiceContext, u *model.User, code string) (bool, error) {
+	step, ok := totp.ValidateStep(u.TotpSecret, strings.TrimSpace(code), time.Now())
+	if !ok || step <= u.TotpStep {
+		return false, nil
+	}
+	return svcCtx.UserModel.UseTotpStep(ctx, u.ID, step)
+}
+
+// newRecoveryCodes 生成恢复码，返回展示给用户的恢复码及保存的哈希
+func newRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := encrypt.RandomToken(recoveryCodeBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, encrypt.Sha256(code))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
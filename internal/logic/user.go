@@ -10,12 +10,15 @@ import (
 	"context"
 	"errors"
 	"time"
-
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type User interface {
 	Login(ctx context.Context, req *domain.LoginReq) (resp *domain.LoginResp, err error)
+	LoginPassword(ctx context.Context, req *domain.LoginPasswordReq) (resp *domain.LoginResp, err error)
+	LoginTwoFactor(ctx context.Context, req *domain.TwoFactorLoginReq) (resp *domain.LoginResp, err error)
+	LoginEvents(ctx context.Context, req *domain.LoginEventListReq) (resp *domain.LoginEventListResp, err error)
+	Refresh(ctx context.Context, req *domain.RefreshReq) (resp *domain.LoginResp, err error)
+	Logout(ctx context.Context) (err error)
 	Info(ctx context.Context, req *domain.IdPathReq) (resp *domain.User, err error)
 	Create(ctx context.Context, req *domain.User) (err error)
 	Edit(ctx context.Context, req *domain.User) (err error)
@@ -36,36 +39,205 @@ func NewUser(svcCtx *svc.ServiceContext) User {
 
 // Login 管理员登录
 func (l *user) Login(ctx context.Context, req *domain.LoginReq) (resp *domain.LoginResp, err error) {
-	user, err := l.svcCtx.UserModel.FindByName(ctx, req.Name)
+	event := &model.LoginEvent{
+		Name:      req.Name,
+		Ip:        req.Ip,
+		UserAgent: req.UserAgent,
+	}
+	defer func() {
+		l.recordLogin(ctx, event, err)
+	}()
+
+	tctx, err := tenantContext(ctx, l.svcCtx, req.Tenant)
+	if err != nil {
+		return nil, err
+	}
+	ctx = tctx
+
+	user, err := l.authenticate(ctx, req.Name, req.Password, req.Ip, event)
+	if err != nil {
+		return nil, err
+	}
+
+	// 需要修改密码时不签发令牌，修改密码后再完成登录
+	if l.passwordExpired(user) {
+		event.Result = model.LoginPasswordChange
+		return &domain.LoginResp{
+			Status: LoginStatusPasswordChange,
+			Id:     user.ID.Hex(),
+			Name:   user.Name,
+		}, nil
+	}
+
+	return l.completeLogin(ctx, user, event)
+}
+
+// LoginPassword 登录时修改初始密码或已过期的密码，修改成功后完成登录
+func (l *user) LoginPassword(ctx context.Context, req *domain.LoginPasswordReq) (resp *domain.LoginResp, err error) {
+	event := &model.LoginEvent{
+		Name:      req.Name,
+		Ip:        req.Ip,
+		UserAgent: req.UserAgent,
+	}
+	defer func() {
+		l.recordLogin(ctx, event, err)
+	}()
+
+	tctx, err := tenantContext(ctx, l.svcCtx, req.Tenant)
+	if err != nil {
+		return nil, err
+	}
+	ctx = tctx
+
+	user, err := l.authenticate(ctx, req.Name, req.OldPwd, req.Ip, event)
+	if err != nil {
+		return nil, err
+	}
+	if err = l.setPassword(ctx, user, req.NewPwd); err != nil {
+		return nil, err
+	}
+
+	return l.completeLogin(ctx, user, event)
+}
+
+// LoginTwoFactor 使用验证器应用的验证码或恢复码完成登录的二次验证
+func (l *user) LoginTwoFactor(ctx context.Context, req *domain.TwoFactorLoginReq) (resp *domain.LoginResp, err error) {
+	event := &model.LoginEvent{
+		Ip:        req.Ip,
+		UserAgent: req.UserAgent,
+	}
+	defer func() {
+		l.recordLogin(ctx, event, err)
+	}()
+
+	claims, _, err := token.NewTokenParse(l.svcCtx.Config.Jwt.Secret).ParseToken(req.Token)
+	if err != nil {
+		return nil, ErrTwoFactorTokenInvalid
+	}
+	uid, _ := claims[token.Identify].(string)
+	if ok, _ := claims[token.TwoFactorIdentify].(bool); !ok || len(uid) == 0 {
+		return nil, ErrTwoFactorTokenInvalid
+	}
+	tid, _ := claims[token.TenantIdentify].(string)
+	ctx = token.WithTenantId(ctx, tid)
+
+	user, err := l.svcCtx.UserModel.FindOne(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	event.UserId = uid
+	event.Name = user.Name
+
+	// 验证码同样需要限制失败次数
+	attempts := []loginAttempt{{
+		key:         model.LoginAttemptTwoFactorKey(uid),
+		maxFailures: orDefault(l.svcCtx.Config.Login.MaxFailures, defaultLoginMaxFailures),
+	}}
+	if err = l.checkLocked(ctx, attempts); err != nil {
+		event.Result = model.LoginLocked
+		return nil, err
+	}
+	if user.Status == model.UserDisabled {
+		return nil, errors.New("账号已停用")
+	}
+
+	ok, err := verifyTwoFactor(ctx, l.svcCtx, user, req.Code)
 	if err != nil {
 		return nil, err
 	}
+	if !ok {
+		return nil, l.loginFailed(ctx, attempts, ErrTwoFactorCode)
+	}
+	if err = l.svcCtx.LoginAttemptModel.DeleteByKey(ctx, attempts[0].key); err != nil {
+		return nil, err
+	}
 
-	if !encrypt.ValidatePasswordHash(req.Password, user.Password) {
-		return nil, errors.New("密码错误")
+	resp, err = newSession(ctx, l.svcCtx, uid, true)
+	if err != nil {
+		return nil, err
 	}
+	resp.Name = user.Name
+	return resp, nil
+}
 
-	now := time.Now().Unix()
-	tok, err := token.GetJwtToken(l.svcCtx.Config.Jwt.Secret, now, l.svcCtx.Config.Jwt.Expire, user.ID.Hex())
+// LoginEvents 查询登录事件
+func (l *user) LoginEvents(ctx context.Context, req *domain.LoginEventListReq) (resp *domain.LoginEventListResp, err error) {
+	data, count, err := l.svcCtx.LoginEventModel.List(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return &domain.LoginResp{
-		Id:           user.ID.Hex(),
-		Name:         user.Name,
-		AccessToken:  tok,
-		AccessExpire: l.svcCtx.Config.Jwt.Expire + now,
+	list := make([]*domain.LoginEvent, 0, len(data))
+	for i := range data {
+		list = append(list, data[i].ToDomain())
+	}
+	return &domain.LoginEventListResp{
+		Count: count,
+		List:  list,
 	}, nil
 }
 
+// Refresh 使用刷新令牌换取新的访问令牌，刷新令牌同时轮换
+func (l *user) Refresh(ctx context.Context, req *domain.RefreshReq) (resp *domain.LoginResp, err error) {
+	hash := encrypt.Sha256(req.RefreshToken)
+	session, err := l.svcCtx.SessionModel.FindByRefreshToken(ctx, hash)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, svc.ErrSessionInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !session.Valid(time.Now().Unix()) {
+		return nil, svc.ErrSessionInvalid
+	}
+
+	// 刷新令牌在所有租户中查找，之后的查询限定在会话所属的租户内
+	ctx = token.WithTenantId(ctx, session.TenantId)
+	if err = l.svcCtx.CheckTenant(ctx); err != nil {
+		return nil, err
+	}
+
+	// 已经轮换掉的刷新令牌被再次使用，刷新令牌可能已经泄露，撤销整个会话
+	if session.RefreshToken != hash {
+		if err = l.svcCtx.SessionModel.Revoke(ctx, session.ID.Hex()); err != nil {
+			return nil, err
+		}
+		return nil, svc.ErrSessionInvalid
+	}
+
+	user, err := l.svcCtx.UserModel.FindOne(ctx, session.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == model.UserDisabled {
+		return nil, errors.New("账号已停用")
+	}
+
+	resp, err = rotateSession(ctx, l.svcCtx, session)
+	if err != nil {
+		return nil, err
+	}
+	resp.Name = user.Name
+	return resp, nil
+}
+
+// Logout 退出登录，撤销当前的会话
+func (l *user) Logout(ctx context.Context) (err error) {
+	return l.svcCtx.SessionModel.Revoke(ctx, token.GetSessionId(ctx))
+}
+
 // Info 获取用户信息
 func (l *user) Info(ctx context.Context, req *domain.IdPathReq) (resp *domain.User, err error) {
 	u, err := l.svcCtx.UserModel.FindOne(ctx, req.Id)
 	if err != nil {
 		return nil, err
 	}
-	return u.ToDomainUser(), nil
+
+	res, err := l.profiles(ctx, []*model.User{u})
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
 }
 
 // Create 创建用户
@@ -88,40 +260,99 @@ func (l *user) Create(ctx context.Context, req *domain.User) (err error) {
 		return xerr.WithMessagef(err, "encrypt.GenPasswordHash req.name %s", password)
 	}
 
-	return l.svcCtx.UserModel.Insert(ctx, &model.User{
-		Name:     req.Name,
-		Password: string(encryptPass),
-	})
+	// 管理员创建的账号需要在首次登录时修改初始密码，服务账号不能登录
+	u = &model.User{
+		Name:               req.Name,
+		Password:           string(encryptPass),
+		ServiceAccount:     req.ServiceAccount,
+		MustChangePassword: !req.ServiceAccount,
+	}
+	if err = l.setProfile(ctx, u, req); err != nil {
+		return err
+	}
+
+	if err = l.svcCtx.UserModel.Insert(ctx, u); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionCreate, model.ResourceUser, u.ID.Hex(), nil, u)
+	return nil
 }
 
 // Edit 用于更新用户信息
 func (l *user) Edit(ctx context.Context, req *domain.User) (err error) {
-	// 将请求中的用户ID字符串转换为MongoDB的ObjectID类型
-	// ObjectID是MongoDB默认的文档唯一标识格式
-	oid, err := primitive.ObjectIDFromHex(req.Id)
+	// 先查询出用户再修改，避免覆盖掉密码等未在请求中的字段
+	u, err := l.svcCtx.UserModel.FindOne(ctx, req.Id)
 	if err != nil {
 		return err
 	}
-	return l.svcCtx.UserModel.Update(ctx, &model.User{
-		ID:     oid,
-		Name:   req.Name,
-		Status: req.Status,
-	})
+
+	if req.Name != u.Name {
+		u2, err := l.svcCtx.UserModel.FindByName(ctx, req.Name)
+		if err != nil && !errors.Is(err, model.ErrNotUser) {
+			return err
+		}
+		if u2 != nil {
+			return errors.New("已存在该用户")
+		}
+	}
+
+	before := *u
+	disabled := u.Status != model.UserDisabled && req.Status == model.UserDisabled
+	u.Name = req.Name
+	u.Status = req.Status
+	if err = l.setProfile(ctx, u, req); err != nil {
+		return err
+	}
+
+	if err = l.svcCtx.UserModel.Update(ctx, u); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionUpdate, model.ResourceUser, req.Id, &before, u)
+	if !disabled {
+		return nil
+	}
+	// 停用账号需要撤销用户所有的会话
+	return l.svcCtx.SessionModel.RevokeByUserId(ctx, req.Id)
 }
 
 // Delete 删除用户
 func (l *user) Delete(ctx context.Context, req *domain.IdPathReq) (err error) {
-	return l.svcCtx.UserModel.Delete(ctx, req.Id)
+	before, _ := l.svcCtx.UserModel.FindOne(ctx, req.Id)
+	if err = l.svcCtx.UserModel.Delete(ctx, req.Id); err != nil {
+		return err
+	}
+	l.svcCtx.Audit(ctx, model.ActionDelete, model.ResourceUser, req.Id, before, nil)
+	if err = l.svcCtx.SessionModel.RevokeByUserId(ctx, req.Id); err != nil {
+		return err
+	}
+	if err = l.svcCtx.ApiKeyModel.RevokeByUserId(ctx, req.Id); err != nil {
+		return err
+	}
+	return l.svcCtx.UserRoleModel.DeleteByUserId(ctx, req.Id)
 }
 
 func (l *user) List(ctx context.Context, req *domain.UserListReq) (resp *domain.UserListResp, err error) {
+	// 按部门名称查询时，只查询这些部门中的成员
+	if len(req.DepName) > 0 {
+		uids, err := l.depMemberIds(ctx, req.DepName, req.Ids)
+		if err != nil {
+			return nil, err
+		}
+		if len(uids) == 0 {
+			return &domain.UserListResp{
+				List: []*domain.User{},
+			}, nil
+		}
+		req.Ids = uids
+	}
+
 	data, count, err := l.svcCtx.UserModel.List(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	resData := make([]*domain.User, 0, len(data))
-	for i := range data {
-		resData = append(resData, data[i].ToDomainUser())
+	resData, err := l.profiles(ctx, data)
+	if err != nil {
+		return nil, err
 	}
 	return &domain.UserListResp{
 		Count: count,
@@ -130,6 +361,13 @@ func (l *user) List(ctx context.Context, req *domain.UserListReq) (resp *domain.
 }
 
 func (l *user) UpPassword(ctx context.Context, req *domain.UpPasswordReq) (err error) {
+	// 修改其他用户的密码需要具有用户的修改权限
+	if req.Id != token.GetUId(ctx) {
+		if err = l.svcCtx.Authorize(ctx, model.ResourceUser, model.ActionUpdate, ""); err != nil {
+			return err
+		}
+	}
+
 	u, err := l.svcCtx.UserModel.FindOne(ctx, req.Id)
 	if err != nil {
 		return err
@@ -137,9 +375,184 @@ func (l *user) UpPassword(ctx context.Context, req *domain.UpPasswordReq) (err e
 	if !encrypt.ValidatePasswordHash(req.OldPwd, u.Password) {
 		return errors.New("旧密码不正确")
 	}
-	password, err := encrypt.GenPasswordHash([]byte(req.NewPwd))
+	// 修改密码后需要重新登录
+	return l.setPassword(ctx, u, req.NewPwd)
+}
+
+// setProfile 设置用户的档案信息，校验工号唯一以及直属上级不能形成环
+func (l *user) setProfile(ctx context.Context, u *model.User, req *domain.User) error {
+	if len(req.EmployeeNo) > 0 && req.EmployeeNo != u.EmployeeNo {
+		u2, err := l.svcCtx.UserModel.FindByEmployeeNo(ctx, req.EmployeeNo)
+		if err != nil && !errors.Is(err, model.ErrNotUser) {
+			return err
+		}
+		if u2 != nil && u2.ID != u.ID {
+			return errors.New("已存在该工号")
+		}
+	}
+
+	if len(req.ManagerId) > 0 && req.ManagerId != u.ManagerId {
+		if err := l.checkManager(ctx, u.ID.Hex(), req.ManagerId); err != nil {
+			return err
+		}
+	}
+
+	u.Email = req.Email
+	u.Phone = req.Phone
+	u.EmployeeNo = req.EmployeeNo
+	u.JobTitle = req.JobTitle
+	u.HireDate = req.HireDate
+	u.ManagerId = req.ManagerId
+	u.Avatar = req.Avatar
+	u.WorkLocation = req.WorkLocation
+	return nil
+}
+
+// checkManager 校验直属上级存在，且沿着汇报线向上不会回到用户自己
+func (l *user) checkManager(ctx context.Context, uid, managerId string) error {
+	visited := make(map[string]struct{})
+	for id := managerId; len(id) > 0; {
+		if id == uid {
+			return errors.New("直属上级不能是自己或自己的下属")
+		}
+		if _, ok := visited[id]; ok {
+			return nil
+		}
+		visited[id] = struct{}{}
+
+		manager, err := l.svcCtx.UserModel.FindOne(ctx, id)
+		if err != nil {
+			if errors.Is(err, model.ErrNotFound) {
+				return errors.New("不存在该直属上级")
+			}
+			return err
+		}
+		id = manager.ManagerId
+	}
+	return nil
+}
+
+// profiles 补充用户的主部门、职位以及汇报对象
+func (l *user) profiles(ctx context.Context, users []*model.User) ([]*domain.User, error) {
+	res := make([]*domain.User, 0, len(users))
+	if len(users) == 0 {
+		return res, nil
+	}
+
+	uids := make([]string, 0, len(users))
+	for i := range users {
+		uids = append(uids, users[i].ID.Hex())
+	}
+
+	depUsers, err := l.svcCtx.DepartmentUserModel.ListByUserIds(ctx, uids)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	primaries := primaryDepartments(depUsers)
+
+	deps, err := l.svcCtx.DepartmentModel.AllToMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	managerIds := make([]string, 0, len(users))
+	for i := range users {
+		if managerId := reportsTo(users[i], primaries[users[i].ID.Hex()], deps); len(managerId) > 0 {
+			managerIds = append(managerIds, managerId)
+		}
+	}
+	managers := make(map[string]*model.User)
+	if len(managerIds) > 0 {
+		if managers, err = l.svcCtx.UserModel.ListToMaps(ctx, &domain.UserListReq{Ids: managerIds}); err != nil {
+			return nil, err
+		}
+	}
+	presences, err := l.svcCtx.PresenceModel.ListToMaps(ctx, uids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range users {
+		u := users[i].ToDomainUser()
+		primary := primaries[u.Id]
+		if primary != nil {
+			u.DepId = primary.DepId
+			u.Position = primary.Position
+			if dep, ok := deps[primary.DepId]; ok {
+				u.DepName = dep.Name
+			}
+		}
+		u.ManagerId = reportsTo(users[i], primary, deps)
+		if manager, ok := managers[u.ManagerId]; ok {
+			u.ManagerName = manager.Name
+		}
+		u.Presence = string(presences[u.Id].Status)
+		u.LastSeen = presences[u.Id].LastSeen
+		res = append(res, u)
+	}
+	return res, nil
+}
+
+// depMemberIds 获取名称匹配的部门中的成员，ids 不为空时只保留其中的成员
+func (l *user) depMemberIds(ctx context.Context, depName string, ids []string) ([]string, error) {
+	deps, err := l.svcCtx.DepartmentModel.List(ctx, &domain.DepartmentListReq{Name: depName})
+	if err != nil || len(deps) == 0 {
+		return nil, err
+	}
+
+	depIds := make([]string, 0, len(deps))
+	for i := range deps {
+		depIds = append(depIds, deps[i].ID.Hex())
+	}
+	depUsers, err := l.svcCtx.DepartmentUserModel.List(ctx, &domain.DepartmentListReq{DepIds: depIds})
+	if err != nil {
+		return nil, err
+	}
+
+	filter := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		filter[id] = true
+	}
+
+	uids := make([]string, 0, len(depUsers))
+	exists := make(map[string]bool, len(depUsers))
+	for i := range depUsers {
+		uid := depUsers[i].UserId
+		if exists[uid] || (len(ids) > 0 && !filter[uid]) {
+			continue
+		}
+		exists[uid] = true
+		uids = append(uids, uid)
+	}
+	return uids, nil
+}
+
+// primaryDepartments 获取每个用户的主部门，未设置主部门时取最早加入的部门
+// depUsers 需要按主部门优先、加入时间先后排序
+func primaryDepartments(depUsers []*model.DepartmentUser) map[string]*model.DepartmentUser {
+	res := make(map[string]*model.DepartmentUser, len(depUsers))
+	for i := range depUsers {
+		if _, ok := res[depUsers[i].UserId]; !ok {
+			res[depUsers[i].UserId] = depUsers[i]
+		}
+	}
+	return res
+}
+
+// reportsTo 获取用户的汇报对象，未指定直属上级时为主部门的主管，用户本身是主管时向上查找上级部门的主管
+func reportsTo(u *model.User, primary *model.DepartmentUser, deps map[string]*model.Department) string {
+	if len(u.ManagerId) > 0 {
+		return u.ManagerId
+	}
+	if primary == nil {
+		return ""
+	}
+
+	uid := u.ID.Hex()
+	for dep, ok := deps[primary.DepId]; ok; dep, ok = deps[dep.ParentId] {
+		if len(dep.LeaderId) > 0 && dep.LeaderId != uid {
+			return dep.LeaderId
+		}
 	}
-	return l.svcCtx.UserModel.UpdatePassword(ctx, u.ID, string(password))
+	return ""
 }
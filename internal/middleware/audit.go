@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditRequest 一次修改请求的信息，请求处理完成后与业务逻辑记录的变更一同写入审计日志
+type AuditRequest struct {
+	Method    string
+	Path      string
+	Route     string // 匹配的路由，如 /v1/user/:id
+	Status    int
+	Ip        string
+	UserAgent string
+}
+
+// AuditBegin 请求开始时在context中准备收集本次请求的审计记录
+type AuditBegin func(ctx context.Context) context.Context
+
+// AuditWriter 请求处理完成后写入审计日志
+type AuditWriter func(ctx context.Context, req *AuditRequest)
+
+// Audit 记录所有修改类请求（POST、PUT、PATCH、DELETE）的审计日志
+type Audit struct {
+	begin AuditBegin
+	write AuditWriter
+}
+
+func NewAudit(begin AuditBegin, write AuditWriter) *Audit {
+	return &Audit{
+		begin: begin,
+		write: write,
+	}
+}
+
+func (m *Audit) Handler(ctx *gin.Context) {
+	switch ctx.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		ctx.Next()
+		return
+	}
+
+	ctx.Request = ctx.Request.WithContext(m.begin(ctx.Request.Context()))
+	ctx.Next()
+
+	// Jwt中间件会替换请求的context，请求结束后的context中才有操作人
+	m.write(ctx.Request.Context(), &AuditRequest{
+		Method:    ctx.Request.Method,
+		Path:      ctx.Request.URL.Path,
+		Route:     ctx.FullPath(),
+		Status:    ctx.Writer.Status(),
+		Ip:        ctx.ClientIP(),
+		UserAgent: ctx.Request.UserAgent(),
+	})
+}
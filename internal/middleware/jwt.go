@@ -3,30 +3,72 @@ package middleware
 import (
 	"ai/pkg/httpx"
 	"ai/token"
+	"context"
+	"errors"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+var ErrApiKeyNotAllowed = errors.New("该接口不能使用API密钥访问")
+
+// SessionChecker 校验令牌所属的会话是否有效，会话被撤销或过期时返回错误
+type SessionChecker func(ctx context.Context, uid, sid string) error
+
+// ApiKeyAuthenticator 校验API密钥，返回写入了密钥所属用户的context
+type ApiKeyAuthenticator func(ctx context.Context, key string) (context.Context, error)
+
 // Jwt 封装了JWT令牌解析器的结构体，用于处理HTTP请求中的JWT验证
 // 作为Gin框架的中间件使用，负责从请求中提取并验证JWT令牌
 type Jwt struct {
 	// tokenParser 用于解析和验证JWT令牌的解析器实例
 	tokenParser *token.Parse
+	// checkSession 校验令牌是否在吊销列表中
+	checkSession SessionChecker
+	// checkTwoFactor 在 checkSession 的基础上，角色要求二次验证时会话必须已完成二次验证
+	checkTwoFactor SessionChecker
+	// authApiKey 校验API密钥
+	authApiKey ApiKeyAuthenticator
 }
 
 // NewJwt 创建一个新的Jwt实例
 // 参数secret: 用于验证JWT签名的密钥
+// 参数checkSession: 用于校验令牌所属的会话是否已被撤销
+// 参数checkTwoFactor: 用于校验角色要求二次验证的用户的会话是否已完成二次验证
+// 参数authApiKey: 用于校验以 token.ApiKeyPrefix 开头的API密钥
 // 返回值: 初始化后的Jwt指针，包含令牌解析器
-func NewJwt(secret string) *Jwt {
+func NewJwt(secret string, checkSession, checkTwoFactor SessionChecker, authApiKey ApiKeyAuthenticator) *Jwt {
 	return &Jwt{
 		// 初始化令牌解析器，传入签名密钥
-		tokenParser: token.NewTokenParse(secret),
+		tokenParser:    token.NewTokenParse(secret),
+		checkSession:   checkSession,
+		checkTwoFactor: checkTwoFactor,
+		authApiKey:     authApiKey,
 	}
 }
 
 // Handler 实现Gin框架的中间件接口，用于处理请求中的JWT令牌验证
-// 将令牌解析后的数据存入请求上下文，供后续处理函数使用
+// 将令牌解析后的数据存入请求上下文，供后续处理函数使用。
+// 角色要求二次验证的用户在会话完成二次验证之前不能访问
 func (m *Jwt) Handler(ctx *gin.Context) {
+	// API密钥作为JWT令牌之外的另一种凭证
+	if key := token.GetRequestToken(ctx.Request); strings.HasPrefix(key, token.ApiKeyPrefix) {
+		c, err := m.authApiKey(ctx.Request.Context(), key)
+		if err != nil {
+			httpx.FailWithErr(ctx, err)
+			ctx.Abort()
+			return
+		}
+		ctx.Request = ctx.Request.WithContext(c)
+		ctx.Next()
+		return
+	}
+
+	m.session(ctx, m.checkTwoFactor)
+}
+
+// session 解析登录令牌并校验所属的会话
+func (m *Jwt) session(ctx *gin.Context, check SessionChecker) {
 	// 调用令牌解析器的ParseWithContext方法，从请求中解析令牌并将信息存入上下文
 	// 该方法会处理Authorization请求头的提取、令牌验证和上下文注入
 	r, err := m.tokenParser.ParseWithContext(ctx.Request)
@@ -37,8 +79,37 @@ func (m *Jwt) Handler(ctx *gin.Context) {
 		return
 	}
 
+	// 签名有效的令牌还需要校验所属的会话未被撤销，如退出登录、修改密码或账号停用
+	err = check(r.Context(), token.GetUId(r.Context()), token.GetSessionId(r.Context()))
+	if err != nil {
+		httpx.FailWithErr(ctx, err)
+		ctx.Abort()
+		return
+	}
+
 	// 解析成功，更新请求对象为包含新上下文的请求
 	ctx.Request = r
 	// 调用Next()方法，将请求传递给下一个中间件或处理函数
 	ctx.Next()
 }
+
+// SessionHandler 只接受登录令牌，用于修改密码、管理API密钥等不允许API密钥访问的接口
+func (m *Jwt) SessionHandler(ctx *gin.Context) {
+	if strings.HasPrefix(token.GetRequestToken(ctx.Request), token.ApiKeyPrefix) {
+		httpx.FailWithErr(ctx, ErrApiKeyNotAllowed)
+		ctx.Abort()
+		return
+	}
+	m.Handler(ctx)
+}
+
+// EnrollHandler 只接受登录令牌且不要求会话已完成二次验证，
+// 用于角色要求二次验证的用户登录后开启二次验证和退出登录
+func (m *Jwt) EnrollHandler(ctx *gin.Context) {
+	if strings.HasPrefix(token.GetRequestToken(ctx.Request), token.ApiKeyPrefix) {
+		httpx.FailWithErr(ctx, ErrApiKeyNotAllowed)
+		ctx.Abort()
+		return
+	}
+	m.session(ctx, m.checkSession)
+}
@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"ai/pkg/encrypt"
+	"ai/token"
+	"context"
 	"fmt"
 	"time"
 
@@ -8,6 +11,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+// TraceHeader 响应头中返回请求的追踪ID，用于按追踪ID查询审计日志
+const TraceHeader = "X-Trace-Id"
+
+// traceIdBytes 追踪ID的随机字节数
+const traceIdBytes = 8
+
 type Log struct{}
 
 func NewLog() *Log {
@@ -18,10 +27,21 @@ func (w *Log) Handler(ctx *gin.Context) {
 	startTime := time.Now()
 	url := fmt.Sprintf("%s:%s", ctx.Request.URL.Path, ctx.Request.Method)
 
-	ctx.Request = ctx.Request.WithContext(tlog.TraceStart(ctx.Request.Context()))
+	c := TraceStart(ctx.Request.Context())
+	ctx.Header(TraceHeader, token.GetTraceId(c))
+	ctx.Request = ctx.Request.WithContext(c)
 	defer func() {
 		tlog.InfoCtx(ctx.Request.Context(), url, "time", tlog.RTField(startTime, time.Now()))
 	}()
 
 	ctx.Next()
 }
+
+// TraceStart 开始日志追踪，并生成请求的追踪ID
+func TraceStart(ctx context.Context) context.Context {
+	id, err := encrypt.RandomToken(traceIdBytes)
+	if err != nil {
+		id = fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return tlog.TraceStart(token.WithTraceId(ctx, id))
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"ai/pkg/httpx"
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authorizer 校验当前请求的用户是否具有资源的操作权限
+type Authorizer func(ctx context.Context, resource, action string) error
+
+// Rbac 基于角色的权限校验中间件，需要在Jwt中间件之后使用
+type Rbac struct {
+	authorize    Authorizer
+	authorizeAny Authorizer
+}
+
+// NewRbac 创建权限校验中间件
+// authorize 要求用户在全局范围内具有权限，authorizeAny 只要求用户在任意部门范围内具有权限
+func NewRbac(authorize, authorizeAny Authorizer) *Rbac {
+	return &Rbac{
+		authorize:    authorize,
+		authorizeAny: authorizeAny,
+	}
+}
+
+// Permission 要求用户在全局范围内具有资源的操作权限
+func (m *Rbac) Permission(resource, action string) gin.HandlerFunc {
+	return m.handler(m.authorize, resource, action)
+}
+
+// DepPermission 只要求用户在某个部门范围内具有资源的操作权限，具体部门的权限由业务逻辑进一步校验
+func (m *Rbac) DepPermission(resource, action string) gin.HandlerFunc {
+	return m.handler(m.authorizeAny, resource, action)
+}
+
+func (m *Rbac) handler(authorize Authorizer, resource, action string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if err := authorize(ctx.Request.Context(), resource, action); err != nil {
+			httpx.FailWithErr(ctx, err)
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
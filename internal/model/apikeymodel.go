@@ -0,0 +1,136 @@
+package model
+
+import (
+	"ai/internal/domain"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ApiKeyModel interface {
+	Insert(ctx context.Context, data *ApiKey) error
+	FindOne(ctx context.Context, id string) (*ApiKey, error)
+	FindByHash(ctx context.Context, hash string) (*ApiKey, error)
+	List(ctx context.Context, req *domain.ApiKeyListReq) ([]*ApiKey, int64, error)
+	UpdateLastUsed(ctx context.Context, id primitive.ObjectID, at int64) error
+	Revoke(ctx context.Context, id string) error
+	RevokeByUserId(ctx context.Context, uid string) error
+}
+
+type defaultApiKeyModel struct {
+	col *tenantCollection
+}
+
+func NewApiKeyModel(db *mongo.Database) ApiKeyModel {
+	col := newTenantCollection(db.Collection("api_key"))
+	return &defaultApiKeyModel{
+		col: col,
+	}
+}
+
+func (m *defaultApiKeyModel) Insert(ctx context.Context, data *ApiKey) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+		data.CreateAt = time.Now().Unix()
+		data.UpdateAt = time.Now().Unix()
+	}
+
+	_, err := m.col.InsertOne(ctx, data)
+	return err
+}
+
+func (m *defaultApiKeyModel) FindOne(ctx context.Context, id string) (*ApiKey, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidObjectId
+	}
+
+	var data ApiKey
+	err = m.col.FindOne(ctx, bson.M{"_id": oid}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+// FindByHash 根据密钥的哈希查询，校验密钥时还不知道所属的租户，在所有租户中查询
+func (m *defaultApiKeyModel) FindByHash(ctx context.Context, hash string) (*ApiKey, error) {
+	var data ApiKey
+	err := m.col.col.FindOne(ctx, bson.M{"hash": hash}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultApiKeyModel) List(ctx context.Context, req *domain.ApiKeyListReq) ([]*ApiKey, int64, error) {
+	var (
+		data []*ApiKey
+		opt  = &options.FindOptions{
+			Sort: bson.M{"createAt": -1},
+		}
+		filter = bson.M{}
+	)
+	opt.Limit, opt.Skip = Pagination(req.Page, req.Count)
+
+	if len(req.UserId) > 0 {
+		filter["userId"] = req.UserId
+	}
+	if !req.Revoked {
+		filter["revokedAt"] = bson.M{"$exists": false}
+	}
+
+	if err := entityList(ctx, m.col, filter, &data, opt); err != nil {
+		return nil, 0, err
+	}
+
+	count, err := m.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, count, nil
+}
+
+// UpdateLastUsed 记录密钥最近一次使用的时间
+func (m *defaultApiKeyModel) UpdateLastUsed(ctx context.Context, id primitive.ObjectID, at int64) error {
+	_, err := m.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"lastUsedAt": at,
+	}})
+	return err
+}
+
+func (m *defaultApiKeyModel) Revoke(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidObjectId
+	}
+
+	now := time.Now().Unix()
+	_, err = m.col.UpdateOne(ctx, bson.M{"_id": oid, "revokedAt": bson.M{"$exists": false}}, bson.M{"$set": bson.M{
+		"revokedAt": now,
+		"updateAt":  now,
+	}})
+	return err
+}
+
+// RevokeByUserId 撤销用户所有的API密钥
+func (m *defaultApiKeyModel) RevokeByUserId(ctx context.Context, uid string) error {
+	now := time.Now().Unix()
+	_, err := m.col.UpdateMany(ctx, bson.M{"userId": uid, "revokedAt": bson.M{"$exists": false}}, bson.M{"$set": bson.M{
+		"revokedAt": now,
+		"updateAt":  now,
+	}})
+	return err
+}
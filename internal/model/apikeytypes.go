@@ -0,0 +1,60 @@
+package model
+
+import (
+	"ai/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ApiKey 用户或服务账号的API密钥，只保存密钥的哈希值
+// 密钥的权限为 Scopes 与所属用户角色权限的交集
+type ApiKey struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	TenantId   string        `bson:"tenantId,omitempty"`
+	UserId     string        `bson:"userId"`
+	CreatorId  string        `bson:"creatorId"`
+	Name       string        `bson:"name"`
+	Prefix     string        `bson:"prefix"` // 密钥的前几位，用于在列表中辨认密钥
+	Hash       string        `bson:"hash"`
+	Scopes     []*Permission `bson:"scopes"`
+	ExpireAt   int64         `bson:"expireAt,omitempty"` // 为0时不过期
+	LastUsedAt int64         `bson:"lastUsedAt,omitempty"`
+	RevokedAt  int64         `bson:"revokedAt,omitempty"`
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
+
+// Valid 密钥未被撤销且未过期
+func (k *ApiKey) Valid(now int64) bool {
+	return k.RevokedAt == 0 && (k.ExpireAt == 0 || now <= k.ExpireAt)
+}
+
+// Allow 判断密钥的授权范围是否包含资源的操作
+func (k *ApiKey) Allow(resource, action string) bool {
+	return allow(k.Scopes, resource, action)
+}
+
+func (k *ApiKey) ToDomain() *domain.ApiKey {
+	scopes := make([]*domain.Permission, 0, len(k.Scopes))
+	for _, p := range k.Scopes {
+		scopes = append(scopes, &domain.Permission{
+			Resource: p.Resource,
+			Action:   p.Action,
+		})
+	}
+
+	return &domain.ApiKey{
+		Id:         k.ID.Hex(),
+		UserId:     k.UserId,
+		CreatorId:  k.CreatorId,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     scopes,
+		ExpireAt:   k.ExpireAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+		CreateAt:   k.CreateAt,
+	}
+}
@@ -17,16 +17,17 @@ type ApprovalModel interface {
 	List(ctx context.Context, req *domain.ApprovalListReq) ([]*Approval, int64, error)
 	Insert(ctx context.Context, data *Approval) error
 	FindOne(ctx context.Context, id string) (*Approval, error)
+	ListPendingByApprover(ctx context.Context, uid string) ([]*Approval, error)
 	Update(ctx context.Context, data *Approval) error
 	Delete(ctx context.Context, id string) error
 }
 
 type defaultApprovalModel struct {
-	col *mongo.Collection
+	col *tenantCollection
 }
 
 func NewApprovalModel(db *mongo.Database) ApprovalModel {
-	col := db.Collection("approval")
+	col := newTenantCollection(db.Collection("approval"))
 	return &defaultApprovalModel{
 		col: col,
 	}
@@ -100,6 +101,19 @@ func (m *defaultApprovalModel) FindOne(ctx context.Context, id string) (*Approva
 	}
 }
 
+// ListPendingByApprover 查询审批人中包含该用户且还未结束的审批
+func (m *defaultApprovalModel) ListPendingByApprover(ctx context.Context, uid string) ([]*Approval, error) {
+	var data []*Approval
+	err := entityList(ctx, m.col, bson.M{
+		"approvers.userId": uid,
+		"status":           bson.M{"$nin": bson.A{Pass, Refuse, Cancel, AutoPass}},
+	}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
 func (m *defaultApprovalModel) Update(ctx context.Context, data *Approval) error {
 	data.UpdateAt = time.Now().Unix()
 	_, err := m.col.UpdateOne(ctx, bson.M{"_id": data.ID}, bson.M{"$set": data})
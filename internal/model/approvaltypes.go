@@ -2,6 +2,7 @@ package model
 
 import (
 	"ai/internal/domain"
+	"slices"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -51,6 +52,8 @@ func (t ApprovalType) ToString() string {
 		return "报销审批"
 	case PositiveApproval:
 		return "转正审批"
+	case DimissionApproval:
+		return "离职审批"
 	case OvertimeApproval:
 		return "加班审批"
 	case BuyerContractApproval:
@@ -75,6 +78,24 @@ const (
 	AutoPass                         //自动通过
 )
 
+func (s ApprovalStatus) ToString() string {
+	switch s {
+	case Notstarted:
+		return "未开始"
+	case Processed:
+		return "处理中"
+	case Pass:
+		return "已通过"
+	case Refuse:
+		return "已拒绝"
+	case Cancel:
+		return "已撤销"
+	case AutoPass:
+		return "自动通过"
+	}
+	return ""
+}
+
 // LeaveType 请假类型
 // 0.事假, 1.调休, 2.病假, 3.年假, 4.产假, 5.陪产假, 6.婚假, 7.丧假, 8.哺乳假
 type LeaveType int
@@ -137,6 +158,7 @@ type (
 		ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 		// TODO: Fill your own fields
 		UserId   string         `bson:"userId,omitempty" json:"userId,omitempty"`
+		DepId    string         `bson:"depId,omitempty" json:"depId,omitempty"` // 提交审批时所在的部门
 		No       string         `bson:"no,omitempty" json:"no,omitempty"`
 		Type     ApprovalType   `bson:"type,omitempty" json:"type,omitempty"`
 		Status   ApprovalStatus `bson:"status,omitempty" json:"status,omitempty"`
@@ -159,6 +181,8 @@ type (
 		Leave    *Leave    `bson:"leave,omitempty" json:"leave,omitempty"`
 		GoOut    *GoOut    `bson:"goOut,omitempty" json:"goOut,omitempty"`
 
+		Dimission *Dimission `bson:"dimission,omitempty" json:"dimission,omitempty"`
+
 		UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
 		CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
 	}
@@ -193,12 +217,30 @@ type (
 		Reason    string `bson:"reason,omitempty"`    //请假原由
 	}
 
+	// Dimission 离职
+	Dimission struct {
+		LastDay int64  `bson:"lastDay,omitempty"` //最后工作日
+		Reason  string `bson:"reason,omitempty"`  //离职原因
+	}
+
 	// ..
 )
 
+// IsParticipant 用户是否为审批的提交人、审批人或抄送人
+func (m *Approval) IsParticipant(uid string) bool {
+	if m.UserId == uid || m.ApprovalId == uid || slices.Contains(m.Participation, uid) {
+		return true
+	}
+	isUser := func(a *Approver) bool {
+		return a.UserId == uid
+	}
+	return slices.ContainsFunc(m.Approvers, isUser) || slices.ContainsFunc(m.CopyPersons, isUser)
+}
+
 func (m *Approval) ToDomainApprovalInfo() *domain.ApprovalInfoResp {
 	res := &domain.ApprovalInfoResp{
 		Id:          m.ID.Hex(),
+		DepId:       m.DepId,
 		No:          m.No,
 		Type:        int(m.Type),
 		Status:      int(m.Status),
@@ -236,6 +278,11 @@ func (m *Approval) ToDomainApprovalInfo() *domain.ApprovalInfoResp {
 			EndTime:   m.GoOut.EndTime,
 			Reason:    m.GoOut.Reason,
 		}
+	case DimissionApproval:
+		res.Dimission = &domain.Dimission{
+			LastDay: m.Dimission.LastDay,
+			Reason:  m.Dimission.Reason,
+		}
 	}
 
 	return res
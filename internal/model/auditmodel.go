@@ -0,0 +1,96 @@
+package model
+
+import (
+	"ai/internal/domain"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuditLogModel interface {
+	Insert(ctx context.Context, data *AuditLog) error
+	List(ctx context.Context, req *domain.AuditListReq) ([]*AuditLog, int64, error)
+	DeleteBefore(ctx context.Context, t int64) (int64, error)
+}
+
+type defaultAuditLogModel struct {
+	col *tenantCollection
+}
+
+func NewAuditLogModel(db *mongo.Database) AuditLogModel {
+	col := newTenantCollection(db.Collection("audit_log"))
+	return &defaultAuditLogModel{
+		col: col,
+	}
+}
+
+func (m *defaultAuditLogModel) Insert(ctx context.Context, data *AuditLog) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+		data.CreateAt = time.Now().Unix()
+	}
+
+	_, err := m.col.InsertOne(ctx, data)
+	return err
+}
+
+func (m *defaultAuditLogModel) List(ctx context.Context, req *domain.AuditListReq) ([]*AuditLog, int64, error) {
+	var (
+		data []*AuditLog
+		opt  = &options.FindOptions{
+			Sort: bson.M{"createAt": -1},
+		}
+		filter = bson.M{}
+	)
+	opt.Limit, opt.Skip = Pagination(req.Page, req.Count)
+
+	if len(req.ActorId) > 0 {
+		filter["actorId"] = req.ActorId
+	}
+	if len(req.Action) > 0 {
+		filter["action"] = req.Action
+	}
+	if len(req.Resource) > 0 {
+		filter["resource"] = req.Resource
+	}
+	if len(req.ResourceId) > 0 {
+		filter["resourceId"] = req.ResourceId
+	}
+	if len(req.TraceId) > 0 {
+		filter["traceId"] = req.TraceId
+	}
+	if req.StartTime > 0 || req.EndTime > 0 {
+		createAt := bson.M{}
+		if req.StartTime > 0 {
+			createAt["$gte"] = req.StartTime
+		}
+		if req.EndTime > 0 {
+			createAt["$lte"] = req.EndTime
+		}
+		filter["createAt"] = createAt
+	}
+
+	err := entityList(ctx, m.col, filter, &data, opt)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := m.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, count, nil
+}
+
+// DeleteBefore 删除所有租户中早于t的审计日志，用于按保留期限清理
+func (m *defaultAuditLogModel) DeleteBefore(ctx context.Context, t int64) (int64, error) {
+	res, err := m.col.col.DeleteMany(ctx, bson.M{"createAt": bson.M{"$lt": t}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
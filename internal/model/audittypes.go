@@ -0,0 +1,59 @@
+package model
+
+import (
+	"ai/internal/domain"
+	"ai/pkg/audit"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ActionInvoke AI对话中调用工具，审计日志使用
+const ActionInvoke = "invoke"
+
+// AuditLog 审计日志，记录谁在什么时间修改了什么
+type AuditLog struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	ActorId    string          `bson:"actorId,omitempty"`
+	ApiKeyId   string          `bson:"apiKeyId,omitempty"` // 使用API密钥操作时的密钥ID
+	Action     string          `bson:"action"`             // create、update、delete、invoke
+	Resource   string          `bson:"resource"`
+	ResourceId string          `bson:"resourceId,omitempty"`
+	Diff       []*audit.Change `bson:"diff,omitempty"`
+
+	Method    string `bson:"method,omitempty"`
+	Path      string `bson:"path,omitempty"`
+	Status    int    `bson:"status,omitempty"` // 接口返回的HTTP状态码
+	Ip        string `bson:"ip,omitempty"`
+	UserAgent string `bson:"userAgent,omitempty"`
+	TraceId   string `bson:"traceId,omitempty"`
+
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
+
+func (m *AuditLog) ToDomain() *domain.AuditLog {
+	diff := make([]*domain.AuditChange, 0, len(m.Diff))
+	for _, c := range m.Diff {
+		diff = append(diff, &domain.AuditChange{
+			Field:  c.Field,
+			Before: c.Before,
+			After:  c.After,
+		})
+	}
+	return &domain.AuditLog{
+		Id:         m.ID.Hex(),
+		ActorId:    m.ActorId,
+		ApiKeyId:   m.ApiKeyId,
+		Action:     m.Action,
+		Resource:   m.Resource,
+		ResourceId: m.ResourceId,
+		Diff:       diff,
+		Method:     m.Method,
+		Path:       m.Path,
+		Status:     m.Status,
+		Ip:         m.Ip,
+		UserAgent:  m.UserAgent,
+		TraceId:    m.TraceId,
+		CreateAt:   m.CreateAt,
+	}
+}
@@ -15,17 +15,31 @@ import (
 type ChatlogModel interface {
 	Insert(ctx context.Context, data *Chatlog) error
 	FindOne(ctx context.Context, id string) (*Chatlog, error)
+	ListByIds(ctx context.Context, ids []string) ([]*Chatlog, error)
 	Update(ctx context.Context, data *Chatlog) error
 	Delete(ctx context.Context, id string) error
 	ListBySendTime(ctx context.Context, cid string, sendStartTime, sendEndTime int64) ([]*Chatlog, error)
+	// ListByCursor 以消息ID为游标分页，before 不为空时查询更早的消息，after 不为空时查询更新的消息，
+	// 都为空时为最新的消息；返回的消息按发送先后排序，不包括用户仅为自己删除的消息
+	ListByCursor(ctx context.Context, uid, cid, before, after string, count int) ([]*Chatlog, error)
+	// Recall 撤回消息，清除消息内容
+	Recall(ctx context.Context, id string) error
+	// DeleteForAll 为所有人删除消息，清除消息内容
+	DeleteForAll(ctx context.Context, id string) error
+	// DeleteFor 仅为用户自己删除消息
+	DeleteFor(ctx context.Context, id, uid string) error
+	// Edit 修改消息的文本内容，保留修改前的内容
+	Edit(ctx context.Context, id, content string, prev *ChatlogEdit) error
+	// FindLatest 会话中最新的一条消息
+	FindLatest(ctx context.Context, cid string) (*Chatlog, error)
 }
 
 type defaultChatlogModel struct {
-	col *mongo.Collection
+	col *tenantCollection
 }
 
 func NewChatlogModel(db *mongo.Database) ChatlogModel {
-	col := db.Collection("chatlog")
+	col := newTenantCollection(db.Collection("chatlog"))
 	return &defaultChatlogModel{
 		col: col,
 	}
@@ -60,6 +74,22 @@ func (m *defaultChatlogModel) FindOne(ctx context.Context, id string) (*Chatlog,
 	}
 }
 
+func (m *defaultChatlogModel) ListByIds(ctx context.Context, ids []string) ([]*Chatlog, error) {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, ErrInvalidObjectId
+		}
+		oids = append(oids, oid)
+	}
+
+	var data []*Chatlog
+	opt := &options.FindOptions{Sort: bson.D{{Key: "sendTime", Value: 1}, {Key: "_id", Value: 1}}}
+	err := entityList(ctx, m.col, bson.M{"_id": bson.M{"$in": oids}}, &data, opt)
+	return data, err
+}
+
 func (m *defaultChatlogModel) Update(ctx context.Context, data *Chatlog) error {
 	data.UpdateAt = time.Now().Unix()
 	_, err := m.col.UpdateOne(ctx, bson.M{"_id": data.ID}, bson.M{"$set": data})
@@ -84,22 +114,148 @@ func (m *defaultChatlogModel) ListBySendTime(ctx context.Context, cid string, se
 		},
 	}
 
+	// 撤回和删除的消息不参与总结
 	filter := bson.M{
 		"conversationId": cid,
+		"recallAt":       bson.M{"$exists": false},
+		"deleteAt":       bson.M{"$exists": false},
 	}
 
+	sendTime := bson.M{}
 	if sendStartTime > 0 {
-		filter["sendTime"] = bson.M{
-			"&gte": sendStartTime,
-		}
+		sendTime["$gte"] = sendStartTime
 	}
-
 	if sendEndTime > 0 {
-		filter["sendTime"] = bson.M{
-			"&lte": sendEndTime,
-		}
+		sendTime["$lte"] = sendEndTime
+	}
+	if len(sendTime) > 0 {
+		filter["sendTime"] = sendTime
 	}
 
 	err := entityList(ctx, m.col, filter, &data, opt)
 	return data, err
 }
+
+func (m *defaultChatlogModel) ListByCursor(ctx context.Context, uid, cid, before, after string, count int) ([]*Chatlog, error) {
+	var (
+		data   []*Chatlog
+		filter = bson.M{"conversationId": cid, "deletedFor": bson.M{"$ne": uid}}
+		limit  = int64(count)
+		// 消息ID随发送先后递增，向前翻页时倒序查询后再反转
+		sort = -1
+	)
+
+	switch {
+	case len(after) > 0:
+		oid, err := primitive.ObjectIDFromHex(after)
+		if err != nil {
+			return nil, ErrInvalidObjectId
+		}
+		filter["_id"] = bson.M{"$gt": oid}
+		sort = 1
+	case len(before) > 0:
+		oid, err := primitive.ObjectIDFromHex(before)
+		if err != nil {
+			return nil, ErrInvalidObjectId
+		}
+		filter["_id"] = bson.M{"$lt": oid}
+	}
+
+	opt := &options.FindOptions{
+		Sort:  bson.M{"_id": sort},
+		Limit: &limit,
+	}
+	if err := entityList(ctx, m.col, filter, &data, opt); err != nil {
+		return nil, err
+	}
+
+	if sort < 0 {
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+	}
+	return data, nil
+}
+
+func (m *defaultChatlogModel) FindLatest(ctx context.Context, cid string) (*Chatlog, error) {
+	var data Chatlog
+	opt := options.FindOne().SetSort(bson.M{"_id": -1})
+	err := m.col.FindOne(ctx, bson.M{"conversationId": cid}, opt).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultChatlogModel) Recall(ctx context.Context, id string) error {
+	return m.withdraw(ctx, id, "recallAt")
+}
+
+func (m *defaultChatlogModel) DeleteForAll(ctx context.Context, id string) error {
+	return m.withdraw(ctx, id, "deleteAt")
+}
+
+// withdraw 清除消息内容及编辑记录，并记录撤回或删除的时间
+func (m *defaultChatlogModel) withdraw(ctx context.Context, id, field string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidObjectId
+	}
+
+	now := time.Now().Unix()
+	_, err = m.col.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{
+		"$set": bson.M{
+			field:        now,
+			"msgContent": "",
+			"updateAt":   now,
+		},
+		"$unset": bson.M{
+			"attachment": "",
+			"card":       "",
+			"edits":      "",
+		},
+	})
+	return err
+}
+
+func (m *defaultChatlogModel) DeleteFor(ctx context.Context, id, uid string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidObjectId
+	}
+	_, err = m.col.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$addToSet": bson.M{"deletedFor": uid}})
+	return err
+}
+
+func (m *defaultChatlogModel) Edit(ctx context.Context, id, content string, prev *ChatlogEdit) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidObjectId
+	}
+
+	// 只编辑未撤回或删除的消息，避免与撤回、删除并发时恢复已清除的内容
+	now := time.Now().Unix()
+	res, err := m.col.UpdateOne(ctx, bson.M{
+		"_id":      oid,
+		"recallAt": bson.M{"$exists": false},
+		"deleteAt": bson.M{"$exists": false},
+	}, bson.M{
+		"$set": bson.M{
+			"msgContent": content,
+			"editAt":     now,
+			"updateAt":   now,
+		},
+		"$push": bson.M{"edits": prev},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrChatlogWithdrawn
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package model
 
 import (
+	"ai/internal/domain"
+	"fmt"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -11,16 +14,147 @@ const (
 	SingleChatType
 )
 
+// ContentType 消息内容的类型
+type ContentType int
+
+const (
+	TextContent  ContentType = iota // 文本
+	ImageContent                    // 图片
+	FileContent                     // 文件
+	VoiceContent                    // 语音
+	CardContent                     // 卡片，如待办、审批
+)
+
+// AssistantId 会话中由AI助手回复的消息的发送者
+const AssistantId = "assistant"
+
+// 卡片消息引用的业务数据
+const (
+	TodoCard     = "todo"
+	ApprovalCard = "approval"
+)
+
+// Attachment 图片、文件和语音消息引用的上传文件
+type Attachment struct {
+	File     string `bson:"file"` // 上传接口返回的文件路径
+	Name     string `bson:"name,omitempty"`
+	Size     int64  `bson:"size,omitempty"`
+	Duration int    `bson:"duration,omitempty"` // 语音的时长，单位秒
+}
+
+// Card 卡片消息，发送时根据引用的待办或审批生成
+type Card struct {
+	Type   string `bson:"type"`
+	Id     string `bson:"id"`
+	Title  string `bson:"title"`
+	Status string `bson:"status,omitempty"`
+	Desc   string `bson:"desc,omitempty"`
+}
+
 type Chatlog struct {
 	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 
-	ConversationId string   `bson:"conversationId"`
-	SendId         string   `bson:"sendId"`
-	RecvId         string   `bson:"recvId"`
-	ChatType       ChatType `bson:"chatType"`
-	MsgContent     string   `bson:"msgContent"`
-	SendTime       int64    `bson:"sendTime"`
+	ConversationId string      `bson:"conversationId"`
+	SendId         string      `bson:"sendId"`
+	RecvId         string      `bson:"recvId"`
+	ChatType       ChatType    `bson:"chatType"`
+	ContentType    ContentType `bson:"contentType"`
+	MsgContent     string      `bson:"msgContent"` // 文本内容，其他类型的消息为附带的说明
+	Attachment     *Attachment `bson:"attachment,omitempty"`
+	Card           *Card       `bson:"card,omitempty"`
+	SendTime       int64       `bson:"sendTime"`
+
+	Edits      []*ChatlogEdit `bson:"edits,omitempty"` // 编辑前的内容，按编辑先后排列
+	EditAt     int64          `bson:"editAt,omitempty"`
+	RecallAt   int64          `bson:"recallAt,omitempty"`
+	DeleteAt   int64          `bson:"deleteAt,omitempty"`   // 发送者为所有人删除
+	DeletedFor []string       `bson:"deletedFor,omitempty"` // 仅为自己删除了消息的用户
 
 	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
 	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
 }
+
+// ChatlogEdit 消息编辑前的内容
+type ChatlogEdit struct {
+	Content string `bson:"content"`
+	EditAt  int64  `bson:"editAt"`
+}
+
+// Withdrawn 消息已撤回或已为所有人删除，内容已经清除
+func (m *Chatlog) Withdrawn() bool {
+	return m.RecallAt > 0 || m.DeleteAt > 0
+}
+
+// Summary 消息的文本描述，用于聊天记录总结等只能处理文本的场景
+func (m *Chatlog) Summary() string {
+	var res string
+	switch m.ContentType {
+	case ImageContent:
+		res = "[图片]"
+	case FileContent:
+		res = "[文件]"
+		if m.Attachment != nil {
+			res = fmt.Sprintf("[文件 %s]", m.Attachment.Name)
+		}
+	case VoiceContent:
+		res = "[语音]"
+		if m.Attachment != nil && m.Attachment.Duration > 0 {
+			res = fmt.Sprintf("[语音 %d秒]", m.Attachment.Duration)
+		}
+	case CardContent:
+		if m.Card != nil {
+			name := "待办"
+			if m.Card.Type == ApprovalCard {
+				name = "审批"
+			}
+			res = fmt.Sprintf("[%s卡片 %s %s]", name, m.Card.Title, m.Card.Status)
+		}
+	default:
+		return m.MsgContent
+	}
+
+	if len(m.MsgContent) > 0 {
+		res += " " + m.MsgContent
+	}
+	return res
+}
+
+func (m *Chatlog) ToDomain() *domain.Message {
+	res := &domain.Message{
+		Id:             m.ID.Hex(),
+		ConversationId: m.ConversationId,
+		RecvId:         m.RecvId,
+		SendId:         m.SendId,
+		ChatType:       int(m.ChatType),
+		Content:        m.MsgContent,
+		ContentType:    int(m.ContentType),
+		SendTime:       m.SendTime,
+		EditAt:         m.EditAt,
+		RecallAt:       m.RecallAt,
+		DeleteAt:       m.DeleteAt,
+	}
+	for _, e := range m.Edits {
+		res.History = append(res.History, &domain.MessageEdit{
+			Content: e.Content,
+			EditAt:  e.EditAt,
+		})
+	}
+	if m.Attachment != nil {
+		res.Attachment = &domain.MessageAttachment{
+			File:     m.Attachment.File,
+			Name:     m.Attachment.Name,
+			Size:     m.Attachment.Size,
+			Duration: m.Attachment.Duration,
+		}
+	}
+	if m.Card != nil {
+		res.Card = &domain.MessageCard{
+			Type:   m.Card.Type,
+			Id:     m.Card.Id,
+			Title:  m.Card.Title,
+			Status: m.Card.Status,
+			Desc:   m.Card.Desc,
+		}
+	}
+	return res
+}
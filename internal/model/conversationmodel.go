@@ -0,0 +1,93 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ConversationModel interface {
+	// Touch 会话中有新消息，更新最后一条消息，unread 为true时未读数加一，会话不存在时创建
+	Touch(ctx context.Context, uid string, msg *Chatlog, peerId string, unread bool) error
+	// Read 用户读到会话中的msgId，清空未读数
+	Read(ctx context.Context, uid, cid, msgId string) error
+	// ListByUser 用户的会话，最近有消息的排在前面
+	ListByUser(ctx context.Context, uid string, page, count int) ([]*Conversation, int64, error)
+	// ListByConversationId 会话的各参与者的记录
+	ListByConversationId(ctx context.Context, cid string) ([]*Conversation, error)
+}
+
+type defaultConversationModel struct {
+	col *tenantCollection
+}
+
+func NewConversationModel(db *mongo.Database) ConversationModel {
+	col := newTenantCollection(db.Collection("conversation"))
+	return &defaultConversationModel{
+		col: col,
+	}
+}
+
+func (m *defaultConversationModel) Touch(ctx context.Context, uid string, msg *Chatlog, peerId string, unread bool) error {
+	now := time.Now().Unix()
+	update := bson.M{
+		"$set": bson.M{
+			"chatType":     msg.ChatType,
+			"peerId":       peerId,
+			"lastMsgId":    msg.ID.Hex(),
+			"lastSendTime": msg.SendTime,
+			"updateAt":     now,
+		},
+		"$setOnInsert": bson.M{
+			"createAt": now,
+		},
+	}
+	if unread {
+		update["$inc"] = bson.M{"unread": 1}
+	} else {
+		update["$setOnInsert"].(bson.M)["unread"] = 0
+	}
+
+	return entityUpdateOrInsert(ctx, m.col, bson.M{"userId": uid, "conversationId": msg.ConversationId}, update)
+}
+
+func (m *defaultConversationModel) Read(ctx context.Context, uid, cid, msgId string) error {
+	now := time.Now().Unix()
+	_, err := m.col.UpdateOne(ctx, bson.M{"userId": uid, "conversationId": cid}, bson.M{"$set": bson.M{
+		"unread":    0,
+		"readMsgId": msgId,
+		"readAt":    now,
+		"updateAt":  now,
+	}})
+	return err
+}
+
+func (m *defaultConversationModel) ListByUser(ctx context.Context, uid string, page, count int) ([]*Conversation, int64, error) {
+	var (
+		data   []*Conversation
+		filter = bson.M{"userId": uid}
+		opt    = &options.FindOptions{
+			Sort: bson.D{{Key: "lastSendTime", Value: -1}, {Key: "_id", Value: -1}},
+		}
+	)
+	opt.Limit, opt.Skip = Pagination(page, count)
+
+	if err := entityList(ctx, m.col, filter, &data, opt); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := m.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, total, nil
+}
+
+func (m *defaultConversationModel) ListByConversationId(ctx context.Context, cid string) ([]*Conversation, error) {
+	var data []*Conversation
+	err := entityList(ctx, m.col, bson.M{"conversationId": cid}, &data)
+	return data, err
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Conversation 用户的一个会话，记录最后一条消息和未读数，每个参与者各有一条
+type Conversation struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	UserId         string   `bson:"userId"`
+	ConversationId string   `bson:"conversationId"`
+	ChatType       ChatType `bson:"chatType"`
+	PeerId         string   `bson:"peerId"` // 私聊为对方的用户ID，群聊为群ID
+	LastMsgId      string   `bson:"lastMsgId,omitempty"`
+	LastSendTime   int64    `bson:"lastSendTime,omitempty"`
+	Unread         int64    `bson:"unread"`
+	ReadMsgId      string   `bson:"readMsgId,omitempty"`
+	ReadAt         int64    `bson:"readAt,omitempty"`
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
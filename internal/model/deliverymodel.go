@@ -0,0 +1,102 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DeliveryModel interface {
+	Inserts(ctx context.Context, data []*Delivery) error
+	// ListPending 用户还未送达的消息，按发送时间排序
+	ListPending(ctx context.Context, uid string) ([]*Delivery, error)
+	SetDelivered(ctx context.Context, uid string, msgIds []string) error
+	// ListUnread 用户在会话中发送时间不晚于sendTime的未读消息
+	ListUnread(ctx context.Context, uid, cid string, sendTime int64) ([]*Delivery, error)
+	SetRead(ctx context.Context, uid, cid string, sendTime int64) error
+}
+
+type defaultDeliveryModel struct {
+	col *tenantCollection
+}
+
+func NewDeliveryModel(db *mongo.Database) DeliveryModel {
+	col := newTenantCollection(db.Collection("delivery"))
+	return &defaultDeliveryModel{
+		col: col,
+	}
+}
+
+func (m *defaultDeliveryModel) Inserts(ctx context.Context, data []*Delivery) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	docs := make([]any, 0, len(data))
+	for i := range data {
+		if data[i].ID.IsZero() {
+			data[i].ID = primitive.NewObjectID()
+			data[i].CreateAt = now
+			data[i].UpdateAt = now
+		}
+		docs = append(docs, data[i])
+	}
+
+	_, err := m.col.InsertMany(ctx, docs)
+	return err
+}
+
+func (m *defaultDeliveryModel) ListPending(ctx context.Context, uid string) ([]*Delivery, error) {
+	var data []*Delivery
+	opt := &options.FindOptions{Sort: bson.D{{Key: "sendTime", Value: 1}, {Key: "_id", Value: 1}}}
+	err := entityList(ctx, m.col, bson.M{"userId": uid, "status": DeliveryPending}, &data, opt)
+	return data, err
+}
+
+func (m *defaultDeliveryModel) SetDelivered(ctx context.Context, uid string, msgIds []string) error {
+	if len(msgIds) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+	_, err := m.col.UpdateMany(ctx, bson.M{
+		"userId": uid,
+		"msgId":  bson.M{"$in": msgIds},
+		"status": DeliveryPending,
+	}, bson.M{"$set": bson.M{
+		"status":    DeliveryDelivered,
+		"deliverAt": now,
+		"updateAt":  now,
+	}})
+	return err
+}
+
+func (m *defaultDeliveryModel) ListUnread(ctx context.Context, uid, cid string, sendTime int64) ([]*Delivery, error) {
+	var data []*Delivery
+	err := entityList(ctx, m.col, m.unreadFilter(uid, cid, sendTime), &data)
+	return data, err
+}
+
+func (m *defaultDeliveryModel) SetRead(ctx context.Context, uid, cid string, sendTime int64) error {
+	now := time.Now().Unix()
+	_, err := m.col.UpdateMany(ctx, m.unreadFilter(uid, cid, sendTime), bson.M{"$set": bson.M{
+		"status":   DeliveryRead,
+		"readAt":   now,
+		"updateAt": now,
+	}})
+	return err
+}
+
+func (m *defaultDeliveryModel) unreadFilter(uid, cid string, sendTime int64) bson.M {
+	return bson.M{
+		"userId":         uid,
+		"conversationId": cid,
+		"sendTime":       bson.M{"$lte": sendTime},
+		"status":         bson.M{"$ne": DeliveryRead},
+	}
+}
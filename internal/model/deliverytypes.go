@@ -0,0 +1,30 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DeliveryStatus int
+
+const (
+	DeliveryPending   DeliveryStatus = iota // 接收者不在线，等待上线后推送
+	DeliveryDelivered                       // 已送达接收者的设备
+	DeliveryRead                            // 接收者已读
+)
+
+// Delivery 一条消息对一个接收者的投递状态，发送者自己不记录
+type Delivery struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	MsgId          string         `bson:"msgId"`
+	ConversationId string         `bson:"conversationId"`
+	SendId         string         `bson:"sendId"`
+	UserId         string         `bson:"userId"` // 接收者
+	SendTime       int64          `bson:"sendTime"`
+	Status         DeliveryStatus `bson:"status"`
+	DeliverAt      int64          `bson:"deliverAt,omitempty"`
+	ReadAt         int64          `bson:"readAt,omitempty"`
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
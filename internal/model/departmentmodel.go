@@ -5,6 +5,7 @@ import (
 	"ai/internal/domain"
 	"ai/pkg/xerr"
 	"context"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -23,18 +24,24 @@ type DepartmentModel interface {
 		error)
 	AllToMap(ctx context.Context) (map[string]*Department,
 		error)
+	ListByParentPath(ctx context.Context, path string) ([]*Department, error)
+	ListByLeaderId(ctx context.Context, uid string) ([]*Department, error)
+	ClearLeader(ctx context.Context, uid string) error
 	FindByName(ctx context.Context, name string) (*Department, error)
 	FindOne(ctx context.Context, id string) (*Department, error)
 	Update(ctx context.Context, data *Department) error
+	UpdateParent(ctx context.Context, id primitive.ObjectID, parentId, parentPath string, level int) error
+	// Touch 更新部门的修改时间，事务中写入部门使并发修改该部门的事务产生写冲突
+	Touch(ctx context.Context, id primitive.ObjectID) error
 	Delete(ctx context.Context, id string) error
 }
 
 type defaultDepartmentModel struct {
-	col *mongo.Collection
+	col *tenantCollection
 }
 
 func NewDepartmentModel(db *mongo.Database) DepartmentModel {
-	col := db.Collection("department")
+	col := newTenantCollection(db.Collection("department"))
 	return &defaultDepartmentModel{
 		col: col,
 	}
@@ -71,6 +78,9 @@ func (m *defaultDepartmentModel) List(ctx context.Context, req *domain.Departmen
 			"$in": oids,
 		}
 	}
+	if len(req.Name) > 0 {
+		filter["name"] = bson.M{"$regex": regexp.QuoteMeta(req.Name), "$options": "i"}
+	}
 	// 查询到数据
 	err := entityList(ctx, m.col, filter, &data, opt)
 	if err != nil {
@@ -122,6 +132,40 @@ func (m *defaultDepartmentModel) All(ctx context.Context) ([]*Department,
 	return list, nil
 }
 
+// ListByParentPath 查询部门的所有下级部门，path 为该部门自身的路径（父级路径:部门id）
+func (m *defaultDepartmentModel) ListByParentPath(ctx context.Context, path string) ([]*Department, error) {
+	var list []*Department
+	err := entityList(ctx, m.col, bson.M{
+		"parent_path": bson.M{"$regex": "^" + regexp.QuoteMeta(path) + "(:|$)"},
+	}, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (m *defaultDepartmentModel) ListByLeaderId(ctx context.Context, uid string) ([]*Department, error) {
+	var data []*Department
+	err := entityList(ctx, m.col, bson.M{"leaderId": uid}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ClearLeader 移除用户担任的所有部门主管，并标记这些部门需要重新指定主管
+func (m *defaultDepartmentModel) ClearLeader(ctx context.Context, uid string) error {
+	_, err := m.col.UpdateMany(ctx, bson.M{"leaderId": uid}, bson.M{
+		"$set": bson.M{
+			"needLeader": true,
+			"updateAt":   time.Now().Unix(),
+		},
+		"$unset": bson.M{"leaderId": ""},
+	})
+	return err
+}
+
 func (m *defaultDepartmentModel) FindOne(ctx context.Context, id string) (*Department, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -159,6 +203,22 @@ func (m *defaultDepartmentModel) Update(ctx context.Context, data *Department) e
 	return err
 }
 
+func (m *defaultDepartmentModel) UpdateParent(ctx context.Context, id primitive.ObjectID, parentId, parentPath string,
+	level int) error {
+	_, err := m.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"parentId":    parentId,
+		"parent_path": parentPath,
+		"level":       level,
+		"updateAt":    time.Now().Unix(),
+	}})
+	return err
+}
+
+func (m *defaultDepartmentModel) Touch(ctx context.Context, id primitive.ObjectID) error {
+	_, err := m.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"updateAt": time.Now().Unix()}})
+	return err
+}
+
 func (m *defaultDepartmentModel) Delete(ctx context.Context, id string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
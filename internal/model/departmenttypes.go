@@ -15,6 +15,8 @@ type Department struct {
 	ParentPath string `bson:"parent_path"`
 	Level      int    `bson:"level,omitempty"`
 	LeaderId   string `bson:"leaderId,omitempty"`
+	NeedLeader bool   `bson:"needLeader"` // 主管离职后需要重新指定主管
+	Group      bool   `bson:"group"`      // 是否开启部门群，群成员跟随部门成员
 
 	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
 	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
@@ -31,6 +33,16 @@ func ParseParentPath(parentPath string) []string {
 	return res[1:]
 }
 
+// DepartmentLevel 根据父级路径计算部门的层级，顶级部门为1
+func DepartmentLevel(parentPath string) int {
+	return len(ParseParentPath(parentPath)) + 1
+}
+
+// Path 部门自身的路径，即下级部门的父级路径
+func (d *Department) Path() string {
+	return DepartmentParentPath(d.ParentPath, d.ID.Hex())
+}
+
 func (d *Department) ToDepartment() *domain.Department {
 	return &domain.Department{
 		Id:         d.ID.Hex(),
@@ -38,6 +50,8 @@ func (d *Department) ToDepartment() *domain.Department {
 		ParentId:   d.ParentId,
 		Level:      d.Level,
 		LeaderId:   d.LeaderId,
+		NeedLeader: d.NeedLeader,
+		Group:      d.Group,
 		ParentPath: d.ParentPath,
 	}
 }
@@ -15,22 +15,30 @@ import (
 
 type DepartmentUserModel interface {
 	Insert(ctx context.Context, data *DepartmentUser) error
-	Inserts(ctx context.Context, depId string, uids []string) error
+	Inserts(ctx context.Context, data []*DepartmentUser) error
 	AllToMap(ctx context.Context) (map[string]*DepartmentUser, error)
 	List(ctx context.Context, req *domain.DepartmentListReq) ([]*DepartmentUser, error)
+	ListByUserId(ctx context.Context, uid string) ([]*DepartmentUser, error)
+	ListByUserIds(ctx context.Context, uids []string) ([]*DepartmentUser, error)
 	FindOne(ctx context.Context, id string) (*DepartmentUser, error)
 	FindByUserId(ctx context.Context, uid string) (*DepartmentUser, error)
+	FindByDepAndUser(ctx context.Context, depId, uid string) (*DepartmentUser, error)
 	Update(ctx context.Context, data *DepartmentUser) error
+	SetPrimary(ctx context.Context, depId, uid string) error
 	Delete(ctx context.Context, id string) error
 	DeleteByDepId(ctx context.Context, id string) error
+	DeleteByUserId(ctx context.Context, uid string) error
 }
 
+// primarySort 用户的部门按主部门优先、加入时间先后排序
+var primarySort = bson.D{{Key: "isPrimary", Value: -1}, {Key: "createAt", Value: 1}}
+
 type defaultDepartmentUserModel struct {
-	col *mongo.Collection
+	col *tenantCollection
 }
 
 func NewDepartmentUserModel(db *mongo.Database) DepartmentUserModel {
-	col := db.Collection("department_user")
+	col := newTenantCollection(db.Collection("department_user"))
 	return &defaultDepartmentUserModel{
 		col: col,
 	}
@@ -47,15 +55,21 @@ func (m *defaultDepartmentUserModel) Insert(ctx context.Context, data *Departmen
 	return err
 }
 
-func (m *defaultDepartmentUserModel) Inserts(ctx context.Context, depId string, uids []string) error {
-	data := make([]interface{}, 0, len(uids))
-	for _, uid := range uids {
-		data = append(data, &DepartmentUser{
-			DepId:  depId,
-			UserId: uid,
-		})
+func (m *defaultDepartmentUserModel) Inserts(ctx context.Context, data []*DepartmentUser) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(data))
+	for i := range data {
+		if data[i].ID.IsZero() {
+			data[i].ID = primitive.NewObjectID()
+			data[i].CreateAt = time.Now().Unix()
+			data[i].UpdateAt = time.Now().Unix()
+		}
+		docs = append(docs, data[i])
 	}
-	_, err := m.col.InsertMany(ctx, data)
+	_, err := m.col.InsertMany(ctx, docs)
 	return err
 }
 
@@ -87,7 +101,10 @@ func (m *defaultDepartmentUserModel) List(ctx context.Context, req *domain.Depar
 	)
 
 	if len(req.DepId) > 0 {
-		filter["dep_id"] = req.DepId
+		filter["depId"] = req.DepId
+	}
+	if len(req.DepIds) > 0 {
+		filter["depId"] = bson.M{"$in": req.DepIds}
 	}
 
 	// 查询到数据
@@ -117,9 +134,49 @@ func (m *defaultDepartmentUserModel) FindOne(ctx context.Context, id string) (*D
 	}
 }
 
+// ListByUserId 查询用户所属的所有部门，主部门排在第一个
+func (m *defaultDepartmentUserModel) ListByUserId(ctx context.Context, uid string) ([]*DepartmentUser, error) {
+	var data []*DepartmentUser
+	err := entityList(ctx, m.col, bson.M{"userId": uid}, &data, &options.FindOptions{
+		Sort: primarySort,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// ListByUserIds 查询多个用户所属的部门，每个用户的主部门排在其他部门之前
+func (m *defaultDepartmentUserModel) ListByUserIds(ctx context.Context, uids []string) ([]*DepartmentUser, error) {
+	var data []*DepartmentUser
+	err := entityList(ctx, m.col, bson.M{"userId": bson.M{"$in": uids}}, &data, &options.FindOptions{
+		Sort: primarySort,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// FindByUserId 查询用户的主部门，没有设置主部门时返回最早加入的部门
 func (m *defaultDepartmentUserModel) FindByUserId(ctx context.Context, uid string) (*DepartmentUser, error) {
 	var data DepartmentUser
-	err := m.col.FindOne(ctx, bson.M{"userId": uid}).Decode(&data)
+	err := m.col.FindOne(ctx, bson.M{"userId": uid}, options.FindOne().SetSort(primarySort)).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultDepartmentUserModel) FindByDepAndUser(ctx context.Context, depId, uid string) (*DepartmentUser, error) {
+	var data DepartmentUser
+	err := m.col.FindOne(ctx, bson.M{"depId": depId, "userId": uid}).Decode(&data)
 	switch err {
 	case nil:
 		return &data, nil
@@ -136,6 +193,24 @@ func (m *defaultDepartmentUserModel) Update(ctx context.Context, data *Departmen
 	return err
 }
 
+// SetPrimary 将用户在指定部门的成员关系设置为主部门，同时取消其他部门的主部门标记
+func (m *defaultDepartmentUserModel) SetPrimary(ctx context.Context, depId, uid string) error {
+	now := time.Now().Unix()
+	_, err := m.col.UpdateMany(ctx, bson.M{"userId": uid, "depId": bson.M{"$ne": depId}}, bson.M{"$set": bson.M{
+		"isPrimary": false,
+		"updateAt":  now,
+	}})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.col.UpdateOne(ctx, bson.M{"userId": uid, "depId": depId}, bson.M{"$set": bson.M{
+		"isPrimary": true,
+		"updateAt":  now,
+	}})
+	return err
+}
+
 func (m *defaultDepartmentUserModel) Delete(ctx context.Context, id string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -145,6 +220,11 @@ func (m *defaultDepartmentUserModel) Delete(ctx context.Context, id string) erro
 	return err
 }
 func (m *defaultDepartmentUserModel) DeleteByDepId(ctx context.Context, id string) error {
-	_, err := m.col.DeleteMany(ctx, bson.M{"dep_id": id})
+	_, err := m.col.DeleteMany(ctx, bson.M{"depId": id})
+	return err
+}
+
+func (m *defaultDepartmentUserModel) DeleteByUserId(ctx context.Context, uid string) error {
+	_, err := m.col.DeleteMany(ctx, bson.M{"userId": uid})
 	return err
 }
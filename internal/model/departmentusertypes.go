@@ -1,16 +1,33 @@
 package model
 
 import (
+	"ai/internal/domain"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// DepartmentUser 部门成员，一个用户可以属于多个部门，其中一个为主部门
 type DepartmentUser struct {
 	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 
-	DepId  string `bson:"depId,omitempty"`
-	UserId string `bson:"userId,omitempty"`
+	DepId     string `bson:"depId,omitempty"`
+	UserId    string `bson:"userId,omitempty"`
+	IsPrimary bool   `bson:"isPrimary"`          // 是否为用户的主部门
+	Position  string `bson:"position,omitempty"` // 在该部门的职位
 
 	// TODO: Fill your own fields
 	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
 	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
 }
+
+func (m *DepartmentUser) ToDomain(userName, depName string) *domain.DepartmentUser {
+	return &domain.DepartmentUser{
+		Id:        m.ID.Hex(),
+		UserId:    m.UserId,
+		DepId:     m.DepId,
+		UserName:  userName,
+		DepName:   depName,
+		IsPrimary: m.IsPrimary,
+		Position:  m.Position,
+	}
+}
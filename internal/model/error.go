@@ -7,8 +7,13 @@ import (
 )
 
 var (
-	ErrNotUser         = errors.New("查询不到该用户")
-	ErrDepNotFound     = errors.New("不存在该部门")
-	ErrNotFound        = mongo.ErrNoDocuments
-	ErrInvalidObjectId = errors.New("invalid objectId")
+	ErrNotUser          = errors.New("查询不到该用户")
+	ErrDepNotFound      = errors.New("不存在该部门")
+	ErrColumnNotFound   = errors.New("不存在该看板列")
+	ErrRoleNotFound     = errors.New("不存在该角色")
+	ErrTenantNotFound   = errors.New("不存在该租户")
+	ErrGroupNotFound    = errors.New("不存在该群聊")
+	ErrChatlogWithdrawn = errors.New("消息已撤回或已删除")
+	ErrNotFound         = mongo.ErrNoDocuments
+	ErrInvalidObjectId  = errors.New("invalid objectId")
 )
@@ -0,0 +1,189 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type GroupModel interface {
+	Insert(ctx context.Context, data *Group) error
+	FindOne(ctx context.Context, id string) (*Group, error)
+	FindByDepId(ctx context.Context, depId string) (*Group, error)
+	ListByIds(ctx context.Context, ids []string) ([]*Group, error)
+	// ListByMember 用户加入的普通群聊，部门群按部门查询
+	ListByMember(ctx context.Context, uid string) ([]*Group, error)
+	ListByDepIds(ctx context.Context, depIds []string) ([]*Group, error)
+	Update(ctx context.Context, data *Group) error
+	UpdateName(ctx context.Context, id, name string) error
+	// AddMembers 原子地加入成员，返回修改后的群聊
+	AddMembers(ctx context.Context, id string, uids []string) (*Group, error)
+	// RemoveMembers 原子地移出成员，同时取消其管理员，返回修改后的群聊
+	RemoveMembers(ctx context.Context, id string, uids []string) (*Group, error)
+	// SetAdmins 设置管理员，管理员不都是群成员时返回 ErrGroupNotFound
+	SetAdmins(ctx context.Context, id string, admins []string) (*Group, error)
+	Delete(ctx context.Context, id string) error
+	DeleteByDepId(ctx context.Context, depId string) error
+}
+
+type defaultGroupModel struct {
+	col *tenantCollection
+}
+
+func NewGroupModel(db *mongo.Database) GroupModel {
+	col := newTenantCollection(db.Collection("group"))
+	return &defaultGroupModel{
+		col: col,
+	}
+}
+
+func (m *defaultGroupModel) Insert(ctx context.Context, data *Group) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+		data.CreateAt = time.Now().Unix()
+		data.UpdateAt = time.Now().Unix()
+	}
+
+	_, err := m.col.InsertOne(ctx, data)
+	return err
+}
+
+func (m *defaultGroupModel) FindOne(ctx context.Context, id string) (*Group, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidObjectId
+	}
+	return m.findOne(ctx, bson.M{"_id": oid})
+}
+
+func (m *defaultGroupModel) FindByDepId(ctx context.Context, depId string) (*Group, error) {
+	return m.findOne(ctx, bson.M{"depId": depId})
+}
+
+func (m *defaultGroupModel) findOne(ctx context.Context, filter bson.M) (*Group, error) {
+	var data Group
+	err := m.col.FindOne(ctx, filter).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrGroupNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultGroupModel) ListByIds(ctx context.Context, ids []string) ([]*Group, error) {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, ErrInvalidObjectId
+		}
+		oids = append(oids, oid)
+	}
+
+	var data []*Group
+	err := entityList(ctx, m.col, bson.M{"_id": bson.M{"$in": oids}}, &data)
+	return data, err
+}
+
+func (m *defaultGroupModel) ListByMember(ctx context.Context, uid string) ([]*Group, error) {
+	var data []*Group
+	opt := &options.FindOptions{Sort: bson.M{"createAt": 1}}
+	err := entityList(ctx, m.col, bson.M{"memberIds": uid}, &data, opt)
+	return data, err
+}
+
+func (m *defaultGroupModel) ListByDepIds(ctx context.Context, depIds []string) ([]*Group, error) {
+	var data []*Group
+	opt := &options.FindOptions{Sort: bson.M{"createAt": 1}}
+	err := entityList(ctx, m.col, bson.M{"depId": bson.M{"$in": depIds}}, &data, opt)
+	return data, err
+}
+
+func (m *defaultGroupModel) Update(ctx context.Context, data *Group) error {
+	data.UpdateAt = time.Now().Unix()
+	_, err := m.col.UpdateOne(ctx, bson.M{"_id": data.ID}, bson.M{"$set": data})
+	return err
+}
+
+func (m *defaultGroupModel) UpdateName(ctx context.Context, id, name string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidObjectId
+	}
+	_, err = m.col.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{
+		"$set": bson.M{"name": name, "updateAt": time.Now().Unix()},
+	})
+	return err
+}
+
+func (m *defaultGroupModel) AddMembers(ctx context.Context, id string, uids []string) (*Group, error) {
+	return m.findOneAndUpdate(ctx, id, nil, bson.M{
+		"$addToSet": bson.M{"memberIds": bson.M{"$each": uids}},
+		"$set":      bson.M{"updateAt": time.Now().Unix()},
+	})
+}
+
+func (m *defaultGroupModel) RemoveMembers(ctx context.Context, id string, uids []string) (*Group, error) {
+	return m.findOneAndUpdate(ctx, id, nil, bson.M{
+		"$pull": bson.M{
+			"memberIds": bson.M{"$in": uids},
+			"adminIds":  bson.M{"$in": uids},
+		},
+		"$set": bson.M{"updateAt": time.Now().Unix()},
+	})
+}
+
+func (m *defaultGroupModel) SetAdmins(ctx context.Context, id string, admins []string) (*Group, error) {
+	// 设置时管理员仍需都是群成员，避免与移出成员并发时设置已移出的成员
+	var filter bson.M
+	if len(admins) > 0 {
+		filter = bson.M{"memberIds": bson.M{"$all": admins}}
+	}
+	return m.findOneAndUpdate(ctx, id, filter, bson.M{
+		"$set": bson.M{"adminIds": admins, "updateAt": time.Now().Unix()},
+	})
+}
+
+// findOneAndUpdate 按ID和附加条件更新群聊，返回更新后的群聊
+func (m *defaultGroupModel) findOneAndUpdate(ctx context.Context, id string, filter, update bson.M) (*Group, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidObjectId
+	}
+	if filter == nil {
+		filter = bson.M{}
+	}
+	filter["_id"] = oid
+
+	var data Group
+	err = m.col.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrGroupNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultGroupModel) Delete(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidObjectId
+	}
+	_, err = m.col.DeleteOne(ctx, bson.M{"_id": oid})
+	return err
+}
+
+func (m *defaultGroupModel) DeleteByDepId(ctx context.Context, depId string) error {
+	_, err := m.col.DeleteMany(ctx, bson.M{"depId": depId})
+	return err
+}
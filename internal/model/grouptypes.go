@@ -0,0 +1,49 @@
+package model
+
+import (
+	"ai/internal/domain"
+	"slices"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Group 群聊，群ID即群聊的会话ID。
+// 部门群的名称、群主和成员都跟随部门，不单独保存
+type Group struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	Name      string   `bson:"name"`
+	OwnerId   string   `bson:"ownerId,omitempty"`
+	AdminIds  []string `bson:"adminIds,omitempty"`
+	MemberIds []string `bson:"memberIds,omitempty"`
+	DepId     string   `bson:"depId,omitempty"`
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
+
+// IsDepartment 是否为部门群
+func (g *Group) IsDepartment() bool {
+	return len(g.DepId) > 0
+}
+
+func (g *Group) IsMember(uid string) bool {
+	return slices.Contains(g.MemberIds, uid)
+}
+
+// IsAdmin 群主和管理员可以管理群聊
+func (g *Group) IsAdmin(uid string) bool {
+	return g.OwnerId == uid || slices.Contains(g.AdminIds, uid)
+}
+
+func (g *Group) ToDomain() *domain.Group {
+	return &domain.Group{
+		Id:        g.ID.Hex(),
+		Name:      g.Name,
+		OwnerId:   g.OwnerId,
+		AdminIds:  g.AdminIds,
+		MemberIds: g.MemberIds,
+		DepId:     g.DepId,
+		CreateAt:  g.CreateAt,
+	}
+}
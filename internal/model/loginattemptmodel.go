@@ -0,0 +1,112 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"gitee.com/dn-jinmin/tlog"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LoginAttemptModel interface {
+	FindByKey(ctx context.Context, key string) (*LoginAttempt, error)
+	// Fail 原子地增加一次失败次数，统计窗口已过期时重新计数，返回增加后的记录
+	Fail(ctx context.Context, key string, now, window int64) (*LoginAttempt, error)
+	// Lock 失败次数达到上限时锁定到 until，并清空计数
+	Lock(ctx context.Context, key string, maxFailures int, until int64) error
+	DeleteByKey(ctx context.Context, key string) error
+}
+
+type defaultLoginAttemptModel struct {
+	col *tenantCollection
+}
+
+func NewLoginAttemptModel(db *mongo.Database) LoginAttemptModel {
+	// 同一租户下每个key只有一条记录，并发的首次失败只有一个能插入
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := db.Collection("login_attempt").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: tenantField, Value: 1}, {Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		tlog.Errorf("NewLoginAttemptModel", "create index fail %v", err.Error())
+	}
+
+	col := newTenantCollection(db.Collection("login_attempt"))
+	return &defaultLoginAttemptModel{
+		col: col,
+	}
+}
+
+func (m *defaultLoginAttemptModel) FindByKey(ctx context.Context, key string) (*LoginAttempt, error) {
+	var data LoginAttempt
+	err := m.col.FindOne(ctx, bson.M{"key": key}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultLoginAttemptModel) Fail(ctx context.Context, key string, now, window int64) (*LoginAttempt, error) {
+	// 窗口过期时先重新开始计数，条件更新保证并发的失败只重置一次
+	_, err := m.col.UpdateOne(ctx, bson.M{
+		"key":         key,
+		"firstFailAt": bson.M{"$lt": now - window},
+	}, bson.M{
+		"$set": bson.M{"failures": 0, "firstFailAt": now},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"failures": 1},
+		"$set": bson.M{"updateAt": now},
+		"$setOnInsert": bson.M{
+			"_id":         primitive.NewObjectID(),
+			"firstFailAt": now,
+			"lockedUntil": int64(0),
+			"createAt":    now,
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var data LoginAttempt
+	err = m.col.FindOneAndUpdate(ctx, bson.M{"key": key}, update, opts).Decode(&data)
+	if mongo.IsDuplicateKeyError(err) {
+		// 并发的首次失败由其他请求插入了记录，重试时更新该记录
+		err = m.col.FindOneAndUpdate(ctx, bson.M{"key": key}, update, opts).Decode(&data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func (m *defaultLoginAttemptModel) Lock(ctx context.Context, key string, maxFailures int, until int64) error {
+	_, err := m.col.UpdateOne(ctx, bson.M{
+		"key":      key,
+		"failures": bson.M{"$gte": maxFailures},
+	}, bson.M{
+		"$set": bson.M{
+			"failures":    0,
+			"firstFailAt": int64(0),
+			"lockedUntil": until,
+			"updateAt":    time.Now().Unix(),
+		},
+	})
+	return err
+}
+
+func (m *defaultLoginAttemptModel) DeleteByKey(ctx context.Context, key string) error {
+	_, err := m.col.DeleteOne(ctx, bson.M{"key": key})
+	return err
+}
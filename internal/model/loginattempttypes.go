@@ -0,0 +1,30 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginAttempt 登录失败计数，Key 为账号或IP，失败次数达到上限后临时锁定
+type LoginAttempt struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	Key         string `bson:"key"`
+	Failures    int    `bson:"failures"`
+	FirstFailAt int64  `bson:"firstFailAt"` // 统计窗口内第一次失败的时间
+	LockedUntil int64  `bson:"lockedUntil"`
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
+
+func LoginAttemptUserKey(name string) string {
+	return "user:" + name
+}
+
+func LoginAttemptIpKey(ip string) string {
+	return "ip:" + ip
+}
+
+func LoginAttemptTwoFactorKey(uid string) string {
+	return "2fa:" + uid
+}
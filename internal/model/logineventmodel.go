@@ -0,0 +1,73 @@
+package model
+
+import (
+	"ai/internal/domain"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LoginEventModel interface {
+	Insert(ctx context.Context, data *LoginEvent) error
+	List(ctx context.Context, req *domain.LoginEventListReq) ([]*LoginEvent, int64, error)
+}
+
+type defaultLoginEventModel struct {
+	col *tenantCollection
+}
+
+func NewLoginEventModel(db *mongo.Database) LoginEventModel {
+	col := newTenantCollection(db.Collection("login_event"))
+	return &defaultLoginEventModel{
+		col: col,
+	}
+}
+
+func (m *defaultLoginEventModel) Insert(ctx context.Context, data *LoginEvent) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+		data.CreateAt = time.Now().Unix()
+	}
+
+	_, err := m.col.InsertOne(ctx, data)
+	return err
+}
+
+func (m *defaultLoginEventModel) List(ctx context.Context, req *domain.LoginEventListReq) ([]*LoginEvent, int64, error) {
+	var (
+		data []*LoginEvent
+		opt  = &options.FindOptions{
+			Sort: bson.M{"createAt": -1},
+		}
+		filter = bson.M{}
+	)
+	opt.Limit, opt.Skip = Pagination(req.Page, req.Count)
+
+	if len(req.UserId) > 0 {
+		filter["userId"] = req.UserId
+	}
+	if len(req.Name) > 0 {
+		filter["name"] = req.Name
+	}
+	if len(req.Ip) > 0 {
+		filter["ip"] = req.Ip
+	}
+	if req.Result > 0 {
+		filter["result"] = req.Result
+	}
+
+	err := entityList(ctx, m.col, filter, &data, opt)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := m.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, count, nil
+}
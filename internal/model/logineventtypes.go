@@ -0,0 +1,62 @@
+package model
+
+import (
+	"ai/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LoginResult 登录结果
+type LoginResult int
+
+const (
+	LoginSuccess        LoginResult = iota + 1 // 登录成功
+	LoginFailure                               // 登录失败
+	LoginLocked                                // 登录失败次数过多被锁定
+	LoginPasswordChange                        // 密码需要修改
+	LoginTwoFactor                             // 等待二次验证
+)
+
+func (r LoginResult) ToString() string {
+	switch r {
+	case LoginSuccess:
+		return "登录成功"
+	case LoginFailure:
+		return "登录失败"
+	case LoginLocked:
+		return "已锁定"
+	case LoginPasswordChange:
+		return "需要修改密码"
+	case LoginTwoFactor:
+		return "等待二次验证"
+	}
+	return ""
+}
+
+// LoginEvent 登录事件
+type LoginEvent struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	UserId    string      `bson:"userId,omitempty"`
+	Name      string      `bson:"name"`
+	Ip        string      `bson:"ip"`
+	UserAgent string      `bson:"userAgent"`
+	Result    LoginResult `bson:"result"`
+	Reason    string      `bson:"reason,omitempty"`
+
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
+
+func (m *LoginEvent) ToDomain() *domain.LoginEvent {
+	return &domain.LoginEvent{
+		Id:         m.ID.Hex(),
+		UserId:     m.UserId,
+		Name:       m.Name,
+		Ip:         m.Ip,
+		UserAgent:  m.UserAgent,
+		Result:     int(m.Result),
+		ResultName: m.Result.ToString(),
+		Reason:     m.Reason,
+		CreateAt:   m.CreateAt,
+	}
+}
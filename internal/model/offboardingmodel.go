@@ -0,0 +1,71 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type OffboardingModel interface {
+	Insert(ctx context.Context, data *Offboarding) error
+	ListDue(ctx context.Context, now int64) ([]*Offboarding, error)
+	FindByApprovalId(ctx context.Context, approvalId string) (*Offboarding, error)
+	Update(ctx context.Context, data *Offboarding) error
+}
+
+type defaultOffboardingModel struct {
+	col *tenantCollection
+}
+
+func NewOffboardingModel(db *mongo.Database) OffboardingModel {
+	col := newTenantCollection(db.Collection("offboarding"))
+	return &defaultOffboardingModel{
+		col: col,
+	}
+}
+
+func (m *defaultOffboardingModel) Insert(ctx context.Context, data *Offboarding) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+		data.CreateAt = time.Now().Unix()
+		data.UpdateAt = time.Now().Unix()
+	}
+
+	_, err := m.col.InsertOne(ctx, data)
+	return err
+}
+
+// ListDue 查询最后工作日已经结束但还未执行交接的记录
+func (m *defaultOffboardingModel) ListDue(ctx context.Context, now int64) ([]*Offboarding, error) {
+	var data []*Offboarding
+	err := entityList(ctx, m.col, bson.M{
+		"status":    OffboardingPending,
+		"executeAt": bson.M{"$lte": now},
+	}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (m *defaultOffboardingModel) FindByApprovalId(ctx context.Context, approvalId string) (*Offboarding, error) {
+	var data Offboarding
+	err := m.col.FindOne(ctx, bson.M{"approvalId": approvalId}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultOffboardingModel) Update(ctx context.Context, data *Offboarding) error {
+	data.UpdateAt = time.Now().Unix()
+	_, err := m.col.UpdateOne(ctx, bson.M{"_id": data.ID}, bson.M{"$set": data})
+	return err
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OffboardingStatus 离职交接状态
+type OffboardingStatus int
+
+const (
+	OffboardingPending OffboardingStatus = iota + 1 // 等待最后工作日
+	OffboardingDone                                 // 交接完成
+)
+
+// Offboarding 离职交接记录，离职审批通过后登记，在最后工作日执行交接
+type Offboarding struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	UserId     string            `bson:"userId"`
+	ApprovalId string            `bson:"approvalId"`
+	LastDay    int64             `bson:"lastDay"`
+	ExecuteAt  int64             `bson:"executeAt"` // 最后工作日结束后执行交接
+	Status     OffboardingStatus `bson:"status"`
+
+	// 交接结果
+	ManagerId      string   `bson:"managerId,omitempty"`
+	TodoIds        []string `bson:"todoIds,omitempty"`      // 转交的待办
+	ApprovalIds    []string `bson:"approvalIds,omitempty"`  // 转交的审批
+	DepIds         []string `bson:"depIds,omitempty"`       // 移出的部门
+	LeaderDepIds   []string `bson:"leaderDepIds,omitempty"` // 需要重新指定主管的部门
+	HandoverTodoId string   `bson:"handoverTodoId,omitempty"`
+	FinishAt       int64    `bson:"finishAt,omitempty"`
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
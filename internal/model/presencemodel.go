@@ -0,0 +1,62 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PresenceModel interface {
+	// Set 更新用户的在线状态，不存在时创建
+	Set(ctx context.Context, uid string, status PresenceStatus) error
+	// ListToMaps 用户的在线状态，没有记录的用户为离线
+	ListToMaps(ctx context.Context, uids []string) (map[string]*Presence, error)
+}
+
+type defaultPresenceModel struct {
+	col *tenantCollection
+}
+
+func NewPresenceModel(db *mongo.Database) PresenceModel {
+	col := newTenantCollection(db.Collection("presence"))
+	return &defaultPresenceModel{
+		col: col,
+	}
+}
+
+func (m *defaultPresenceModel) Set(ctx context.Context, uid string, status PresenceStatus) error {
+	now := time.Now().Unix()
+	return entityUpdateOrInsert(ctx, m.col, bson.M{"userId": uid}, bson.M{
+		"$set": bson.M{
+			"status":   status,
+			"lastSeen": now,
+			"updateAt": now,
+		},
+		"$setOnInsert": bson.M{
+			"createAt": now,
+		},
+	})
+}
+
+func (m *defaultPresenceModel) ListToMaps(ctx context.Context, uids []string) (map[string]*Presence, error) {
+	var data []*Presence
+	if err := entityList(ctx, m.col, bson.M{"userId": bson.M{"$in": uids}}, &data); err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*Presence, len(uids))
+	for i := range data {
+		res[data[i].UserId] = data[i]
+	}
+	for _, uid := range uids {
+		if _, ok := res[uid]; !ok {
+			res[uid] = &Presence{
+				UserId: uid,
+				Status: PresenceOffline,
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"ai/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PresenceStatus string
+
+const (
+	PresenceOnline  PresenceStatus = "online"
+	PresenceAway    PresenceStatus = "away" // 所有设备都处于离开状态
+	PresenceOffline PresenceStatus = "offline"
+)
+
+func (s PresenceStatus) ToString() string {
+	switch s {
+	case PresenceOnline:
+		return "在线"
+	case PresenceAway:
+		return "离开"
+	default:
+		return "离线"
+	}
+}
+
+// Presence 用户的在线状态，由websocket服务在连接和状态变化时更新
+type Presence struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	UserId   string         `bson:"userId"`
+	Status   PresenceStatus `bson:"status"`
+	LastSeen int64          `bson:"lastSeen"` // 最后在线的时间，在线时为状态变化的时间
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
+
+func (m *Presence) ToDomain() *domain.Presence {
+	return &domain.Presence{
+		UserId:   m.UserId,
+		Status:   string(m.Status),
+		LastSeen: m.LastSeen,
+	}
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type RoleModel interface {
+	Insert(ctx context.Context, data *Role) error
+	List(ctx context.Context) ([]*Role, error)
+	ListByIds(ctx context.Context, ids []string) ([]*Role, error)
+	FindOne(ctx context.Context, id string) (*Role, error)
+	FindByCode(ctx context.Context, code string) (*Role, error)
+	Update(ctx context.Context, data *Role) error
+	Delete(ctx context.Context, id string) error
+}
+
+type defaultRoleModel struct {
+	col *tenantCollection
+}
+
+func NewRoleModel(db *mongo.Database) RoleModel {
+	col := newTenantCollection(db.Collection("role"))
+	return &defaultRoleModel{
+		col: col,
+	}
+}
+
+func (m *defaultRoleModel) Insert(ctx context.Context, data *Role) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+		data.CreateAt = time.Now().Unix()
+		data.UpdateAt = time.Now().Unix()
+	}
+
+	_, err := m.col.InsertOne(ctx, data)
+	return err
+}
+
+func (m *defaultRoleModel) List(ctx context.Context) ([]*Role, error) {
+	var data []*Role
+	err := entityList(ctx, m.col, bson.M{}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (m *defaultRoleModel) ListByIds(ctx context.Context, ids []string) ([]*Role, error) {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, ErrInvalidObjectId
+		}
+		oids = append(oids, oid)
+	}
+
+	var data []*Role
+	err := entityList(ctx, m.col, bson.M{"_id": bson.M{"$in": oids}}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (m *defaultRoleModel) FindOne(ctx context.Context, id string) (*Role, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidObjectId
+	}
+
+	var data Role
+	err = m.col.FindOne(ctx, bson.M{"_id": oid}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrRoleNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultRoleModel) FindByCode(ctx context.Context, code string) (*Role, error) {
+	var data Role
+	err := m.col.FindOne(ctx, bson.M{"code": code}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrRoleNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultRoleModel) Update(ctx context.Context, data *Role) error {
+	data.UpdateAt = time.Now().Unix()
+	_, err := m.col.UpdateOne(ctx, bson.M{"_id": data.ID}, bson.M{"$set": data})
+	return err
+}
+
+func (m *defaultRoleModel) Delete(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidObjectId
+	}
+	_, err = m.col.DeleteOne(ctx, bson.M{"_id": oid})
+	return err
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"ai/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 资源
+const (
+	ResourceAll        = "*"
+	ResourceUser       = "user"
+	ResourceDepartment = "department"
+	ResourceTodo       = "todo"
+	ResourceApproval   = "approval"
+	ResourceChat       = "chat"
+	ResourceKnowledge  = "knowledge"
+	ResourceRole       = "role"
+	ResourceTenant     = "tenant"
+	ResourceAudit      = "audit"
+	ResourceGroup      = "group"
+)
+
+// 操作
+const (
+	ActionAll    = "*"
+	ActionRead   = "read"
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// 系统预置角色编码
+const (
+	RoleAdmin    = "admin"
+	RoleHR       = "hr"
+	RoleFinance  = "finance"
+	RoleEmployee = "employee"
+)
+
+// Permission 权限，对某一资源的某一操作，"*"表示全部
+type Permission struct {
+	Resource string `bson:"resource"`
+	Action   string `bson:"action"`
+}
+
+// Role 角色，由一组权限组成
+type Role struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	Code        string        `bson:"code"`
+	Name        string        `bson:"name"`
+	Permissions []*Permission `bson:"permissions"`
+	IsSystem    bool          `bson:"isSystem"`
+
+	RequireTwoFactor bool `bson:"requireTwoFactor"` // 拥有该角色的用户必须开启二次验证
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
+
+// Allow 判断角色是否具有资源的操作权限
+func (r *Role) Allow(resource, action string) bool {
+	return allow(r.Permissions, resource, action)
+}
+
+func allow(permissions []*Permission, resource, action string) bool {
+	for _, p := range permissions {
+		if (p.Resource == ResourceAll || p.Resource == resource) && (p.Action == ActionAll || p.Action == action) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Role) ToDomain() *domain.Role {
+	permissions := make([]*domain.Permission, 0, len(r.Permissions))
+	for _, p := range r.Permissions {
+		permissions = append(permissions, &domain.Permission{
+			Resource: p.Resource,
+			Action:   p.Action,
+		})
+	}
+
+	return &domain.Role{
+		Id:          r.ID.Hex(),
+		Code:        r.Code,
+		Name:        r.Name,
+		Permissions: permissions,
+		IsSystem:    r.IsSystem,
+
+		RequireTwoFactor: r.RequireTwoFactor,
+	}
+}
+
+func ToModelPermissions(permissions []*domain.Permission) []*Permission {
+	res := make([]*Permission, 0, len(permissions))
+	for _, p := range permissions {
+		res = append(res, &Permission{
+			Resource: p.Resource,
+			Action:   p.Action,
+		})
+	}
+	return res
+}
+
+// DefaultRoles 系统预置的角色
+func DefaultRoles() []*Role {
+	return []*Role{
+		{
+			Code: RoleAdmin,
+			Name: "管理员",
+			Permissions: []*Permission{
+				{Resource: ResourceAll, Action: ActionAll},
+			},
+			IsSystem: true,
+		}, {
+			Code: RoleHR,
+			Name: "人事",
+			Permissions: []*Permission{
+				{Resource: ResourceUser, Action: ActionAll},
+				{Resource: ResourceDepartment, Action: ActionAll},
+				{Resource: ResourceTodo, Action: ActionAll},
+				{Resource: ResourceApproval, Action: ActionAll},
+				{Resource: ResourceChat, Action: ActionAll},
+				{Resource: ResourceKnowledge, Action: ActionAll},
+			},
+			IsSystem: true,
+		}, {
+			Code: RoleFinance,
+			Name: "财务",
+			Permissions: []*Permission{
+				{Resource: ResourceUser, Action: ActionRead},
+				{Resource: ResourceDepartment, Action: ActionRead},
+				{Resource: ResourceTodo, Action: ActionAll},
+				{Resource: ResourceApproval, Action: ActionAll},
+				{Resource: ResourceChat, Action: ActionAll},
+				{Resource: ResourceKnowledge, Action: ActionRead},
+			},
+			IsSystem: true,
+		}, {
+			Code: RoleEmployee,
+			Name: "员工",
+			Permissions: []*Permission{
+				{Resource: ResourceUser, Action: ActionRead},
+				{Resource: ResourceDepartment, Action: ActionRead},
+				{Resource: ResourceTodo, Action: ActionAll},
+				{Resource: ResourceApproval, Action: ActionRead},
+				{Resource: ResourceApproval, Action: ActionCreate},
+				{Resource: ResourceApproval, Action: ActionUpdate},
+				{Resource: ResourceChat, Action: ActionAll},
+				{Resource: ResourceKnowledge, Action: ActionRead},
+			},
+			IsSystem: true,
+		},
+	}
+}
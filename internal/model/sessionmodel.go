@@ -0,0 +1,122 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SessionModel interface {
+	Insert(ctx context.Context, data *Session) error
+	FindOne(ctx context.Context, id string) (*Session, error)
+	FindByRefreshToken(ctx context.Context, hash string) (*Session, error)
+	Update(ctx context.Context, data *Session) error
+	SetTwoFactor(ctx context.Context, id string) error
+	Revoke(ctx context.Context, id string) error
+	RevokeByUserId(ctx context.Context, uid string) error
+}
+
+type defaultSessionModel struct {
+	col *tenantCollection
+}
+
+func NewSessionModel(db *mongo.Database) SessionModel {
+	col := newTenantCollection(db.Collection("session"))
+	return &defaultSessionModel{
+		col: col,
+	}
+}
+
+func (m *defaultSessionModel) Insert(ctx context.Context, data *Session) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+		data.CreateAt = time.Now().Unix()
+		data.UpdateAt = time.Now().Unix()
+	}
+
+	_, err := m.col.InsertOne(ctx, data)
+	return err
+}
+
+func (m *defaultSessionModel) FindOne(ctx context.Context, id string) (*Session, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidObjectId
+	}
+
+	var data Session
+	err = m.col.FindOne(ctx, bson.M{"_id": oid}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+// FindByRefreshToken 根据刷新令牌的哈希查询会话，包括已经轮换掉的上一个刷新令牌。
+// 刷新时还不知道用户所属的租户，在所有租户中查询
+func (m *defaultSessionModel) FindByRefreshToken(ctx context.Context, hash string) (*Session, error) {
+	var data Session
+	err := m.col.col.FindOne(ctx, bson.M{"$or": bson.A{
+		bson.M{"refreshToken": hash},
+		bson.M{"prevRefreshToken": hash},
+	}}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultSessionModel) Update(ctx context.Context, data *Session) error {
+	data.UpdateAt = time.Now().Unix()
+	_, err := m.col.UpdateOne(ctx, bson.M{"_id": data.ID}, bson.M{"$set": data})
+	return err
+}
+
+// SetTwoFactor 标记会话已完成二次验证
+func (m *defaultSessionModel) SetTwoFactor(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidObjectId
+	}
+
+	_, err = m.col.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{
+		"twoFactor": true,
+		"updateAt":  time.Now().Unix(),
+	}})
+	return err
+}
+
+func (m *defaultSessionModel) Revoke(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidObjectId
+	}
+
+	now := time.Now().Unix()
+	_, err = m.col.UpdateOne(ctx, bson.M{"_id": oid, "revokedAt": bson.M{"$exists": false}}, bson.M{"$set": bson.M{
+		"revokedAt": now,
+		"updateAt":  now,
+	}})
+	return err
+}
+
+// RevokeByUserId 撤销用户所有的会话
+func (m *defaultSessionModel) RevokeByUserId(ctx context.Context, uid string) error {
+	now := time.Now().Unix()
+	_, err := m.col.UpdateMany(ctx, bson.M{"userId": uid, "revokedAt": bson.M{"$exists": false}}, bson.M{"$set": bson.M{
+		"revokedAt": now,
+		"updateAt":  now,
+	}})
+	return err
+}
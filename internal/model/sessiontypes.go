@@ -0,0 +1,27 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Session 登录会话，访问令牌中携带会话ID，刷新令牌只保存哈希值
+// 撤销的会话即为令牌的吊销列表，会话撤销后其访问令牌和刷新令牌都不能再使用
+type Session struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	TenantId         string `bson:"tenantId,omitempty"`
+	UserId           string `bson:"userId"`
+	RefreshToken     string `bson:"refreshToken"`               // 当前刷新令牌的哈希
+	PrevRefreshToken string `bson:"prevRefreshToken,omitempty"` // 上一个刷新令牌的哈希，用于发现刷新令牌被重复使用
+	ExpireAt         int64  `bson:"expireAt"`                   // 刷新令牌的过期时间
+	RevokedAt        int64  `bson:"revokedAt,omitempty"`
+	TwoFactor        bool   `bson:"twoFactor"` // 会话已完成二次验证
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
+
+// Valid 会话未被撤销且未过期
+func (s *Session) Valid(now int64) bool {
+	return s.RevokedAt == 0 && now <= s.ExpireAt
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SsoStateModel interface {
+	Insert(ctx context.Context, data *SsoState) error
+	// Take 取出并删除state，同一个state只能取出一次
+	Take(ctx context.Context, state string) (*SsoState, error)
+	DeleteExpired(ctx context.Context, now int64) error
+}
+
+type defaultSsoStateModel struct {
+	col *tenantCollection
+}
+
+func NewSsoStateModel(db *mongo.Database) SsoStateModel {
+	col := newTenantCollection(db.Collection("sso_state"))
+	return &defaultSsoStateModel{
+		col: col,
+	}
+}
+
+func (m *defaultSsoStateModel) Insert(ctx context.Context, data *SsoState) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+		data.CreateAt = time.Now().Unix()
+	}
+
+	_, err := m.col.InsertOne(ctx, data)
+	return err
+}
+
+func (m *defaultSsoStateModel) Take(ctx context.Context, state string) (*SsoState, error) {
+	var data SsoState
+	err := m.col.FindOneAndDelete(ctx, bson.M{"state": state}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultSsoStateModel) DeleteExpired(ctx context.Context, now int64) error {
+	_, err := m.col.DeleteMany(ctx, bson.M{"expireAt": bson.M{"$lt": now}})
+	return err
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SsoState 单点登录进行中的state，回调时取出并删除，只能使用一次。
+// State 为state令牌中随机标识的哈希，Binding 为写入浏览器Cookie的绑定值的哈希
+type SsoState struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	State        string `bson:"state"`
+	Binding      string `bson:"binding"`
+	Nonce        string `bson:"nonce"`        // 校验id_token使用的nonce
+	CodeVerifier string `bson:"codeVerifier"` // PKCE的校验码
+	ExpireAt     int64  `bson:"expireAt"`
+
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"ai/token"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// tenantField 文档中保存租户ID的字段
+const tenantField = "tenantId"
+
+// tenantCollection 按租户隔离的集合，租户ID从context中获取。
+// 所有查询、更新、删除的条件都会加上当前租户，写入的文档都会带上当前租户；
+// 默认租户的文档不写入租户字段，兼容开启多租户之前的数据
+type tenantCollection struct {
+	col *mongo.Collection
+}
+
+func newTenantCollection(col *mongo.Collection) *tenantCollection {
+	return &tenantCollection{
+		col: col,
+	}
+}
+
+func (c *tenantCollection) Find(ctx context.Context, filter any, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	return c.col.Find(ctx, tenantFilter(ctx, filter), opts...)
+}
+
+func (c *tenantCollection) FindOne(ctx context.Context, filter any, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	return c.col.FindOne(ctx, tenantFilter(ctx, filter), opts...)
+}
+
+func (c *tenantCollection) CountDocuments(ctx context.Context, filter any, opts ...*options.CountOptions) (int64, error) {
+	return c.col.CountDocuments(ctx, tenantFilter(ctx, filter), opts...)
+}
+
+func (c *tenantCollection) InsertOne(ctx context.Context, document any, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	doc, err := tenantDocument(ctx, document)
+	if err != nil {
+		return nil, err
+	}
+	return c.col.InsertOne(ctx, doc, opts...)
+}
+
+func (c *tenantCollection) InsertMany(ctx context.Context, documents []any, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	docs := make([]any, 0, len(documents))
+	for _, document := range documents {
+		doc, err := tenantDocument(ctx, document)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return c.col.InsertMany(ctx, docs, opts...)
+}
+
+func (c *tenantCollection) ReplaceOne(ctx context.Context, filter, replacement any, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	doc, err := tenantDocument(ctx, replacement)
+	if err != nil {
+		return nil, err
+	}
+	return c.col.ReplaceOne(ctx, tenantFilter(ctx, filter), doc, opts...)
+}
+
+func (c *tenantCollection) UpdateOne(ctx context.Context, filter, update any, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.col.UpdateOne(ctx, tenantFilter(ctx, filter), update, opts...)
+}
+
+func (c *tenantCollection) FindOneAndUpdate(ctx context.Context, filter, update any, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	return c.col.FindOneAndUpdate(ctx, tenantFilter(ctx, filter), update, opts...)
+}
+
+func (c *tenantCollection) FindOneAndDelete(ctx context.Context, filter any, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult {
+	return c.col.FindOneAndDelete(ctx, tenantFilter(ctx, filter), opts...)
+}
+
+func (c *tenantCollection) UpdateMany(ctx context.Context, filter, update any, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return c.col.UpdateMany(ctx, tenantFilter(ctx, filter), update, opts...)
+}
+
+func (c *tenantCollection) DeleteOne(ctx context.Context, filter any, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.col.DeleteOne(ctx, tenantFilter(ctx, filter), opts...)
+}
+
+func (c *tenantCollection) DeleteMany(ctx context.Context, filter any, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return c.col.DeleteMany(ctx, tenantFilter(ctx, filter), opts...)
+}
+
+// tenantFilter 在查询条件上加上当前租户，默认租户匹配没有租户字段的文档
+func tenantFilter(ctx context.Context, filter any) any {
+	var tenant any
+	if tid := token.GetTenantId(ctx); len(tid) > 0 {
+		tenant = tid
+	}
+
+	switch f := filter.(type) {
+	case nil:
+		return bson.M{tenantField: tenant}
+	case bson.M:
+		res := make(bson.M, len(f)+1)
+		for k, v := range f {
+			res[k] = v
+		}
+		res[tenantField] = tenant
+		return res
+	default:
+		return bson.M{"$and": bson.A{filter, bson.M{tenantField: tenant}}}
+	}
+}
+
+// tenantDocument 给写入的文档加上当前租户
+func tenantDocument(ctx context.Context, document any) (any, error) {
+	b, err := bson.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err = bson.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	res := make(bson.D, 0, len(doc)+1)
+	for _, e := range doc {
+		if e.Key != tenantField {
+			res = append(res, e)
+		}
+	}
+	if tid := token.GetTenantId(ctx); len(tid) > 0 {
+		res = append(res, bson.E{Key: tenantField, Value: tid})
+	}
+	return res, nil
+}
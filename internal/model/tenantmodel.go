@@ -0,0 +1,105 @@
+package model
+
+import (
+	"ai/internal/domain"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TenantModel interface {
+	Insert(ctx context.Context, data *Tenant) error
+	FindOne(ctx context.Context, id string) (*Tenant, error)
+	FindByCode(ctx context.Context, code string) (*Tenant, error)
+	List(ctx context.Context, req *domain.TenantListReq) ([]*Tenant, int64, error)
+	Update(ctx context.Context, data *Tenant) error
+}
+
+// defaultTenantModel 租户本身不按租户隔离
+type defaultTenantModel struct {
+	col *mongo.Collection
+}
+
+func NewTenantModel(db *mongo.Database) TenantModel {
+	col := db.Collection("tenant")
+	return &defaultTenantModel{
+		col: col,
+	}
+}
+
+func (m *defaultTenantModel) Insert(ctx context.Context, data *Tenant) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+		data.CreateAt = time.Now().Unix()
+		data.UpdateAt = time.Now().Unix()
+	}
+
+	_, err := m.col.InsertOne(ctx, data)
+	return err
+}
+
+func (m *defaultTenantModel) FindOne(ctx context.Context, id string) (*Tenant, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidObjectId
+	}
+
+	var data Tenant
+	err = m.col.FindOne(ctx, bson.M{"_id": oid}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrTenantNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultTenantModel) FindByCode(ctx context.Context, code string) (*Tenant, error) {
+	var data Tenant
+	err := m.col.FindOne(ctx, bson.M{"code": code}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrTenantNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultTenantModel) List(ctx context.Context, req *domain.TenantListReq) ([]*Tenant, int64, error) {
+	var (
+		data []*Tenant
+		opt  = &options.FindOptions{
+			Sort: bson.M{"createAt": 1},
+		}
+		filter = bson.M{}
+	)
+	opt.Limit, opt.Skip = Pagination(req.Page, req.Count)
+
+	cur, err := m.col.Find(ctx, filter, opt)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = cur.All(ctx, &data); err != nil {
+		return nil, 0, err
+	}
+
+	count, err := m.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, count, nil
+}
+
+func (m *defaultTenantModel) Update(ctx context.Context, data *Tenant) error {
+	data.UpdateAt = time.Now().Unix()
+	_, err := m.col.UpdateOne(ctx, bson.M{"_id": data.ID}, bson.M{"$set": data})
+	return err
+}
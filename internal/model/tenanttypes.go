@@ -0,0 +1,108 @@
+package model
+
+import (
+	"ai/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// 租户状态
+const (
+	TenantNormal   = 0 // 正常
+	TenantDisabled = 1 // 停用，租户下的用户都不能登录
+)
+
+// defaultKnowledgeIndex 默认租户的知识库索引
+const defaultKnowledgeIndex = "knowledge"
+
+// Tenant 租户，一个部署中托管的子公司，租户之间的数据相互隔离
+type Tenant struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	Code   string        `bson:"code"` // 登录时填写的租户编码
+	Name   string        `bson:"name"`
+	Status int           `bson:"status"`
+	Config *TenantConfig `bson:"config,omitempty"`
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
+
+// TenantConfig 租户的配置，未配置的项使用部署的默认配置
+type TenantConfig struct {
+	Llm               *TenantLlm          `bson:"llm,omitempty"`
+	ApprovalTemplates []*ApprovalTemplate `bson:"approvalTemplates,omitempty"`
+	// SsoLinkEmail 单点登录时按已验证的邮箱关联已有账号，默认不关联
+	SsoLinkEmail bool `bson:"ssoLinkEmail,omitempty"`
+}
+
+// TenantLlm 租户使用的大模型服务
+type TenantLlm struct {
+	Url    string `bson:"url"`
+	ApiKey string `bson:"apiKey"`
+	Model  string `bson:"model,omitempty"`
+}
+
+// ApprovalTemplate 租户对某类审批的设置
+type ApprovalTemplate struct {
+	Type      ApprovalType `bson:"type"`
+	Disabled  bool         `bson:"disabled"`            // 停用后不能提交该类审批
+	Approvers []string     `bson:"approvers,omitempty"` // 部门主管审批之后依次审批的用户，如人事、财务
+}
+
+// KnowledgeIndex 租户的知识库索引，默认租户为 knowledge，其他租户按租户编码区分。
+// 索引只由租户编码决定，不能配置，避免读写其他租户的知识库
+func KnowledgeIndex(t *Tenant) string {
+	if t == nil {
+		return defaultKnowledgeIndex
+	}
+	return defaultKnowledgeIndex + "_" + t.Code
+}
+
+// Template 获取租户对某类审批的设置，没有设置时返回nil
+func (c *TenantConfig) Template(t ApprovalType) *ApprovalTemplate {
+	if c == nil {
+		return nil
+	}
+	for _, tpl := range c.ApprovalTemplates {
+		if tpl.Type == t {
+			return tpl
+		}
+	}
+	return nil
+}
+
+func (t *Tenant) ToDomain() *domain.Tenant {
+	res := &domain.Tenant{
+		Id:       t.ID.Hex(),
+		Code:     t.Code,
+		Name:     t.Name,
+		Status:   t.Status,
+		CreateAt: t.CreateAt,
+	}
+	if t.Config != nil {
+		res.Config = t.Config.ToDomain()
+	}
+	return res
+}
+
+// ToDomain 大模型的密钥不返回
+func (c *TenantConfig) ToDomain() *domain.TenantConfig {
+	res := &domain.TenantConfig{
+		SsoLinkEmail: c.SsoLinkEmail,
+	}
+	if c.Llm != nil {
+		res.Llm = &domain.TenantLlm{
+			Url:   c.Llm.Url,
+			Model: c.Llm.Model,
+		}
+	}
+	for _, tpl := range c.ApprovalTemplates {
+		res.ApprovalTemplates = append(res.ApprovalTemplates, &domain.ApprovalTemplate{
+			Type:      int(tpl.Type),
+			Disabled:  tpl.Disabled,
+			Approvers: tpl.Approvers,
+		})
+	}
+	return res
+}
@@ -0,0 +1,115 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TodoColumnModel interface {
+	Insert(ctx context.Context, data *TodoColumn) error
+	Inserts(ctx context.Context, data []*TodoColumn) error
+	ListByScope(ctx context.Context, uid, depId string) ([]*TodoColumn, error)
+	FindOne(ctx context.Context, id string) (*TodoColumn, error)
+	Update(ctx context.Context, data *TodoColumn) error
+	Delete(ctx context.Context, id string) error
+}
+
+type defaultTodoColumnModel struct {
+	col *tenantCollection
+}
+
+func NewTodoColumnModel(db *mongo.Database) TodoColumnModel {
+	col := newTenantCollection(db.Collection("todo_column"))
+	return &defaultTodoColumnModel{
+		col: col,
+	}
+}
+
+func (m *defaultTodoColumnModel) Insert(ctx context.Context, data *TodoColumn) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+		data.CreateAt = time.Now().Unix()
+		data.UpdateAt = time.Now().Unix()
+	}
+
+	_, err := m.col.InsertOne(ctx, data)
+	return err
+}
+
+func (m *defaultTodoColumnModel) Inserts(ctx context.Context, data []*TodoColumn) error {
+	docs := make([]interface{}, 0, len(data))
+	for i := range data {
+		if data[i].ID.IsZero() {
+			data[i].ID = primitive.NewObjectID()
+			data[i].CreateAt = time.Now().Unix()
+			data[i].UpdateAt = time.Now().Unix()
+		}
+		docs = append(docs, data[i])
+	}
+
+	_, err := m.col.InsertMany(ctx, docs)
+	return err
+}
+
+// ListByScope 查询看板的列，depId 不为空时查询部门看板，否则查询用户的个人看板
+func (m *defaultTodoColumnModel) ListByScope(ctx context.Context, uid, depId string) ([]*TodoColumn, error) {
+	var (
+		data []*TodoColumn
+		opt  = &options.FindOptions{
+			Sort: bson.D{{Key: "sort", Value: 1}, {Key: "createAt", Value: 1}},
+		}
+		filter = bson.M{}
+	)
+
+	if len(depId) > 0 {
+		filter["depId"] = depId
+	} else {
+		filter["userId"] = uid
+		filter["depId"] = bson.M{"$exists": false}
+	}
+
+	err := entityList(ctx, m.col, filter, &data, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (m *defaultTodoColumnModel) FindOne(ctx context.Context, id string) (*TodoColumn, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidObjectId
+	}
+
+	var data TodoColumn
+	err = m.col.FindOne(ctx, bson.M{"_id": oid}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrColumnNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultTodoColumnModel) Update(ctx context.Context, data *TodoColumn) error {
+	data.UpdateAt = time.Now().Unix()
+	_, err := m.col.UpdateOne(ctx, bson.M{"_id": data.ID}, bson.M{"$set": data})
+	return err
+}
+
+func (m *defaultTodoColumnModel) Delete(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidObjectId
+	}
+	_, err = m.col.DeleteOne(ctx, bson.M{"_id": oid})
+	return err
+}
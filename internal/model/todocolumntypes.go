@@ -0,0 +1,49 @@
+package model
+
+import (
+	"ai/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TodoColumn 看板列，由用户自定义并映射到待办状态
+// UserId 不为空时为个人看板的列，DepId 不为空时为部门看板的列
+type TodoColumn struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	Name       string     `bson:"name"`
+	UserId     string     `bson:"userId,omitempty"`
+	DepId      string     `bson:"depId,omitempty"`
+	TodoStatus TodoStatus `bson:"todoStatus"`
+	Sort       int        `bson:"sort"`
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
+
+// DefaultTodoColumns 看板未定义任何列时使用的默认列，每个状态一列
+func DefaultTodoColumns(uid, depId string) []*TodoColumn {
+	statuses := []TodoStatus{TodoInProgress, TodoFinish, TodoCancel, TodoTimeout}
+
+	res := make([]*TodoColumn, 0, len(statuses))
+	for i, status := range statuses {
+		res = append(res, &TodoColumn{
+			Name:       status.ToString(),
+			UserId:     uid,
+			DepId:      depId,
+			TodoStatus: status,
+			Sort:       i,
+		})
+	}
+	return res
+}
+
+func (m *TodoColumn) ToDomain() *domain.TodoColumn {
+	return &domain.TodoColumn{
+		Id:         m.ID.Hex(),
+		Name:       m.Name,
+		DepId:      m.DepId,
+		TodoStatus: int(m.TodoStatus),
+		Sort:       m.Sort,
+	}
+}
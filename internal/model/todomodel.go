@@ -16,19 +16,21 @@ import (
 type TodoModel interface {
 	Insert(ctx context.Context, data *Todo) error
 	List(ctx context.Context, req *domain.TodoListReq) ([]*Todo, int64, error)
+	ListByUserIds(ctx context.Context, uids []string) ([]*Todo, error)
 	FindOne(ctx context.Context, id string) (*Todo, error)
 	Update(ctx context.Context, data *Todo) error
+	UpdateSort(ctx context.Context, id primitive.ObjectID, sort int) error
 	UpdateFinished(ctx context.Context, data *Todo, isAllFinished bool) error
 	UpdateRecords(ctx context.Context, data *Todo) error
 	Delete(ctx context.Context, id string) error
 }
 
 type defaultTodoModel struct {
-	col *mongo.Collection
+	col *tenantCollection
 }
 
 func NewTodoModel(db *mongo.Database) TodoModel {
-	col := db.Collection("todo")
+	col := newTenantCollection(db.Collection("todo"))
 	return &defaultTodoModel{
 		col: col,
 	}
@@ -93,6 +95,29 @@ func (m *defaultTodoModel) List(ctx context.Context, req *domain.TodoListReq) ([
 	return data, count, nil
 }
 
+// ListByUserIds 查询用户创建或参与执行的待办，按看板排序
+func (m *defaultTodoModel) ListByUserIds(ctx context.Context, uids []string) ([]*Todo, error) {
+	var (
+		data []*Todo
+		opt  = &options.FindOptions{
+			Sort: bson.D{{Key: "sort", Value: 1}, {Key: "createAt", Value: -1}},
+		}
+		filter = bson.M{
+			"$or": bson.A{
+				bson.M{"creatorId": bson.M{"$in": uids}},
+				bson.M{"executes.userId": bson.M{"$in": uids}},
+			},
+		}
+	)
+
+	err := entityList(ctx, m.col, filter, &data, opt)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
 func (m *defaultTodoModel) FindOne(ctx context.Context, id string) (*Todo, error) {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -117,6 +142,14 @@ func (m *defaultTodoModel) Update(ctx context.Context, data *Todo) error {
 	return err
 }
 
+func (m *defaultTodoModel) UpdateSort(ctx context.Context, id primitive.ObjectID, sort int) error {
+	_, err := m.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"sort":     sort,
+		"updateAt": time.Now().Unix(),
+	}})
+	return err
+}
+
 func (m *defaultTodoModel) UpdateFinished(ctx context.Context, data *Todo, isAllFinished bool) error {
 	// 使用$set操作符包装所有要更新的字段
 	update := bson.M{
@@ -15,6 +15,41 @@ const (
 	TodoTimeout
 )
 
+// todoTransitions 待办状态允许的流转，超时状态由截止时间计算得出，不能直接流转到超时
+var todoTransitions = map[TodoStatus][]TodoStatus{
+	TodoInProgress: {TodoFinish, TodoCancel},
+	TodoFinish:     {TodoInProgress},
+	TodoCancel:     {TodoInProgress},
+	TodoTimeout:    {TodoFinish, TodoCancel},
+}
+
+func (t TodoStatus) ToString() string {
+	switch t {
+	case TodoInProgress:
+		return "进行中"
+	case TodoFinish:
+		return "已完成"
+	case TodoCancel:
+		return "已取消"
+	case TodoTimeout:
+		return "已超时"
+	}
+	return ""
+}
+
+// CanTransit 判断待办能否从当前状态流转到目标状态，状态相同视为列内排序
+func (t TodoStatus) CanTransit(to TodoStatus) bool {
+	if t == to {
+		return true
+	}
+	for _, status := range todoTransitions[t] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
 type (
 	Todo struct {
 		ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
@@ -26,6 +61,8 @@ type (
 		Records    []*TodoRecord `bson:"records"`
 		Executes   []*UserTodo   `bson:"executes"`
 		TodoStatus `bson:"todo_status"`
+		ColumnId   string `bson:"columnId,omitempty"`
+		Sort       int    `bson:"sort"`
 
 		// TODO: Fill your own fields
 		UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
@@ -57,7 +94,30 @@ func (m *Todo) ToDomainTodo() *domain.Todo {
 		Desc:       m.Desc,
 		ExecuteIds: nil,
 		TodoStatus: int(m.TodoStatus),
+		ColumnId:   m.ColumnId,
+		Sort:       m.Sort,
+	}
+}
+
+// CurrentStatus 当前的待办状态，进行中的待办超过截止时间视为超时
+func (m *Todo) CurrentStatus(now int64) TodoStatus {
+	if m.TodoStatus == TodoInProgress && m.DeadlineAt > 0 && now > m.DeadlineAt {
+		return TodoTimeout
+	}
+	return m.TodoStatus
+}
+
+// IsParticipant 用户是否为待办的创建人或执行人
+func (m *Todo) IsParticipant(uid string) bool {
+	if m.CreatorId == uid {
+		return true
+	}
+	for _, e := range m.Executes {
+		if e.UserId == uid {
+			return true
+		}
 	}
+	return false
 }
 
 func (m *TodoRecord) ToDomainTodoRecord() *domain.TodoRecord {
@@ -4,6 +4,7 @@ package model
 import (
 	"ai/internal/domain"
 	"context"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -20,18 +21,26 @@ type UserModel interface {
 	ListToMaps(ctx context.Context, req *domain.UserListReq) (map[string]*User, error)
 	FindSysStemUser(ctx context.Context) (*User, error)
 	FindByName(ctx context.Context, name string) (*User, error)
+	FindByEmployeeNo(ctx context.Context, employeeNo string) (*User, error)
+	FindByEmail(ctx context.Context, email string) (*User, error)
+	FindByOidcSubject(ctx context.Context, subject string) (*User, error)
 	FindOne(ctx context.Context, id string) (*User, error)
 	Update(ctx context.Context, data *User) error
 	UpdatePassword(ctx context.Context, id primitive.ObjectID, password string) error
+	UpdateTotp(ctx context.Context, id primitive.ObjectID, secret string, enabled bool, recoveryCodes []string) error
+	// UseTotpStep 记录通过校验的验证码的时间步，该时间步及之前的验证码已经使用过时返回false
+	UseTotpStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error)
+	// UseRecoveryCode 移除一个恢复码的哈希，恢复码不存在或已被使用时返回false
+	UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error)
 	Delete(ctx context.Context, id string) error
 }
 
 type defaultUserModel struct {
-	col *mongo.Collection
+	col *tenantCollection
 }
 
 func NewUserModel(db *mongo.Database) UserModel {
-	col := db.Collection("user")
+	col := newTenantCollection(db.Collection("user"))
 	return &defaultUserModel{
 		col: col,
 	}
@@ -74,6 +83,22 @@ func (m *defaultUserModel) List(ctx context.Context, req *domain.UserListReq) ([
 	if len(req.Name) > 0 {
 		filter["name"] = req.Name
 	}
+	if len(req.Keyword) > 0 {
+		keyword := bson.M{"$regex": regexp.QuoteMeta(req.Keyword), "$options": "i"}
+		filter["$or"] = bson.A{
+			bson.M{"name": keyword},
+			bson.M{"employeeNo": keyword},
+			bson.M{"email": keyword},
+			bson.M{"phone": keyword},
+			bson.M{"jobTitle": keyword},
+		}
+	}
+	if len(req.WorkLocation) > 0 {
+		filter["workLocation"] = bson.M{"$regex": regexp.QuoteMeta(req.WorkLocation), "$options": "i"}
+	}
+	if len(req.ManagerId) > 0 {
+		filter["managerId"] = req.ManagerId
+	}
 
 	if len(req.Ids) > 0 {
 		oids := make([]primitive.ObjectID, 0, len(req.Ids))
@@ -154,14 +179,55 @@ func (m *defaultUserModel) Update(ctx context.Context, data *User) error {
 	return err
 }
 
+// UpdatePassword 修改密码，同时记录修改时间并清除需要修改密码的标记
 func (m *defaultUserModel) UpdatePassword(ctx context.Context, id primitive.ObjectID, password string) error {
+	now := time.Now().Unix()
 	_, err := m.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
-		"password":  password,
-		"update_at": time.Now().Unix(),
+		"password":           password,
+		"passwordAt":         now,
+		"mustChangePassword": false,
+		"updateAt":           now,
 	}})
 	return err
 }
 
+// UpdateTotp 设置用户的二次验证密钥、开启状态以及恢复码的哈希
+func (m *defaultUserModel) UpdateTotp(ctx context.Context, id primitive.ObjectID, secret string, enabled bool,
+	recoveryCodes []string) error {
+	_, err := m.col.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{
+		"totpSecret":    secret,
+		"totpEnabled":   enabled,
+		"recoveryCodes": recoveryCodes,
+		"updateAt":      time.Now().Unix(),
+	}})
+	return err
+}
+
+func (m *defaultUserModel) UseTotpStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	res, err := m.col.UpdateOne(ctx, bson.M{
+		"_id":      id,
+		"totpStep": bson.M{"$not": bson.M{"$gte": step}},
+	}, bson.M{"$set": bson.M{"totpStep": step}})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (m *defaultUserModel) UseRecoveryCode(ctx context.Context, id primitive.ObjectID, hash string) (bool, error) {
+	res, err := m.col.UpdateOne(ctx, bson.M{
+		"_id":           id,
+		"recoveryCodes": hash,
+	}, bson.M{
+		"$pull": bson.M{"recoveryCodes": hash},
+		"$set":  bson.M{"updateAt": time.Now().Unix()},
+	})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
 func (m *defaultUserModel) Delete(ctx context.Context, id string) error {
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -171,6 +237,45 @@ func (m *defaultUserModel) Delete(ctx context.Context, id string) error {
 	return err
 }
 
+func (m *defaultUserModel) FindByEmployeeNo(ctx context.Context, employeeNo string) (*User, error) {
+	var data User
+	err := m.col.FindOne(ctx, bson.M{"employeeNo": employeeNo}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotUser
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultUserModel) FindByEmail(ctx context.Context, email string) (*User, error) {
+	var data User
+	err := m.col.FindOne(ctx, bson.M{"email": email}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotUser
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultUserModel) FindByOidcSubject(ctx context.Context, subject string) (*User, error) {
+	var data User
+	err := m.col.FindOne(ctx, bson.M{"oidcSubject": subject}).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotUser
+	default:
+		return nil, err
+	}
+}
+
 func (m *defaultUserModel) FindByName(ctx context.Context, name string) (*User, error) {
 	var data User
 	err := m.col.FindOne(ctx, bson.M{"name": name}).Decode(&data)
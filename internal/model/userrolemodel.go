@@ -0,0 +1,91 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type UserRoleModel interface {
+	Insert(ctx context.Context, data *UserRole) error
+	ListByUserId(ctx context.Context, uid string) ([]*UserRole, error)
+	CountByRoleId(ctx context.Context, roleId string) (int64, error)
+	FindOne(ctx context.Context, uid, roleId, depId string) (*UserRole, error)
+	Delete(ctx context.Context, id string) error
+	DeleteByUserId(ctx context.Context, uid string) error
+}
+
+type defaultUserRoleModel struct {
+	col *tenantCollection
+}
+
+func NewUserRoleModel(db *mongo.Database) UserRoleModel {
+	col := newTenantCollection(db.Collection("user_role"))
+	return &defaultUserRoleModel{
+		col: col,
+	}
+}
+
+func (m *defaultUserRoleModel) Insert(ctx context.Context, data *UserRole) error {
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+		data.CreateAt = time.Now().Unix()
+		data.UpdateAt = time.Now().Unix()
+	}
+
+	_, err := m.col.InsertOne(ctx, data)
+	return err
+}
+
+func (m *defaultUserRoleModel) ListByUserId(ctx context.Context, uid string) ([]*UserRole, error) {
+	var data []*UserRole
+	err := entityList(ctx, m.col, bson.M{"userId": uid}, &data)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (m *defaultUserRoleModel) CountByRoleId(ctx context.Context, roleId string) (int64, error) {
+	return m.col.CountDocuments(ctx, bson.M{"roleId": roleId})
+}
+
+// FindOne 查询用户在指定范围内的角色分配，depId 为空时查询全局的分配
+func (m *defaultUserRoleModel) FindOne(ctx context.Context, uid, roleId, depId string) (*UserRole, error) {
+	filter := bson.M{
+		"userId": uid,
+		"roleId": roleId,
+		"depId":  depId,
+	}
+	if len(depId) == 0 {
+		filter["depId"] = bson.M{"$exists": false}
+	}
+
+	var data UserRole
+	err := m.col.FindOne(ctx, filter).Decode(&data)
+	switch err {
+	case nil:
+		return &data, nil
+	case mongo.ErrNoDocuments:
+		return nil, ErrNotFound
+	default:
+		return nil, err
+	}
+}
+
+func (m *defaultUserRoleModel) Delete(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidObjectId
+	}
+	_, err = m.col.DeleteOne(ctx, bson.M{"_id": oid})
+	return err
+}
+
+func (m *defaultUserRoleModel) DeleteByUserId(ctx context.Context, uid string) error {
+	_, err := m.col.DeleteMany(ctx, bson.M{"userId": uid})
+	return err
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"ai/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserRole 用户的角色分配，DepId 不为空时角色的权限仅作用于该部门及其下级部门
+type UserRole struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+
+	UserId string `bson:"userId"`
+	RoleId string `bson:"roleId"`
+	DepId  string `bson:"depId,omitempty"`
+
+	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
+}
+
+func (m *UserRole) ToDomain(role *Role) *domain.UserRole {
+	res := &domain.UserRole{
+		Id:     m.ID.Hex(),
+		UserId: m.UserId,
+		RoleId: m.RoleId,
+		DepId:  m.DepId,
+	}
+	if role != nil {
+		res.RoleCode = role.Code
+		res.RoleName = role.Name
+	}
+	return res
+}
@@ -19,11 +19,11 @@ type UserTodoModel interface {
 }
 
 type defaultUserTodoModel struct {
-	col *mongo.Collection
+	col *tenantCollection
 }
 
 func NewUserTodoModel(db *mongo.Database) UserTodoModel {
-	col := db.Collection("user_todo")
+	col := newTenantCollection(db.Collection("user_todo"))
 	return &defaultUserTodoModel{
 		col: col,
 	}
@@ -6,6 +6,12 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// 用户状态
+const (
+	UserNormal   = 0 // 正常
+	UserDisabled = 1 // 停用
+)
+
 type User struct {
 	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
 
@@ -14,14 +20,47 @@ type User struct {
 	Status   int    `bson:"status"`
 	IsSystem bool   `bson:"isSystem"`
 
+	ServiceAccount     bool  `bson:"serviceAccount"`     // 服务账号不能登录，只能使用API密钥访问
+	MustChangePassword bool  `bson:"mustChangePassword"` // 初始密码需要在首次登录时修改
+	PasswordAt         int64 `bson:"passwordAt"`         // 最近一次修改密码的时间
+
+	// 二次验证，开启前 TotpSecret 为待确认的密钥，恢复码只保存哈希值
+	TotpSecret    string   `bson:"totpSecret,omitempty"`
+	TotpEnabled   bool     `bson:"totpEnabled"`
+	RecoveryCodes []string `bson:"recoveryCodes,omitempty"`
+	TotpStep      int64    `bson:"totpStep,omitempty"` // 最近一次通过校验的验证码的时间步，不能小于等于该值，防止验证码重复使用
+
+	// OidcSubject 单点登录用户在身份提供方的唯一标识
+	OidcSubject string `bson:"oidcSubject,omitempty"`
+
+	Email        string `bson:"email"`
+	Phone        string `bson:"phone"`
+	EmployeeNo   string `bson:"employeeNo"`
+	JobTitle     string `bson:"jobTitle"`
+	HireDate     int64  `bson:"hireDate"`
+	ManagerId    string `bson:"managerId"`
+	Avatar       string `bson:"avatar"`
+	WorkLocation string `bson:"workLocation"`
+
 	UpdateAt int64 `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
 	CreateAt int64 `bson:"createAt,omitempty" json:"createAt,omitempty"`
 }
 
 func (u *User) ToDomainUser() *domain.User {
 	return &domain.User{
-		Id:     u.ID.Hex(),
-		Name:   u.Name,
-		Status: u.Status,
+		Id:           u.ID.Hex(),
+		Name:         u.Name,
+		Status:       u.Status,
+		Email:        u.Email,
+		Phone:        u.Phone,
+		EmployeeNo:   u.EmployeeNo,
+		JobTitle:     u.JobTitle,
+		HireDate:     u.HireDate,
+		ManagerId:    u.ManagerId,
+		Avatar:       u.Avatar,
+		WorkLocation: u.WorkLocation,
+
+		TwoFactorEnabled: u.TotpEnabled,
+		ServiceAccount:   u.ServiceAccount,
 	}
 }
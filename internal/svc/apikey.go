@@ -0,0 +1,69 @@
+package svc
+
+import (
+	"ai/internal/model"
+	"ai/pkg/encrypt"
+	"ai/token"
+	"context"
+	"errors"
+	"time"
+
+	"gitee.com/dn-jinmin/tlog"
+)
+
+// apiKeyUsedInterval 最近使用时间的记录间隔（秒），避免每次请求都写库
+const apiKeyUsedInterval = 60
+
+var ErrApiKeyInvalid = errors.New("API密钥无效或已过期")
+
+// apiKeyCtx context中保存API密钥的键，用于校验密钥的授权范围
+type apiKeyCtx struct{}
+
+// AuthenticateApiKey 校验API密钥，密钥有效且所属用户未停用时，将租户ID、用户ID、密钥ID及授权范围写入context
+func (s *ServiceContext) AuthenticateApiKey(ctx context.Context, key string) (context.Context, error) {
+	data, err := s.ApiKeyModel.FindByHash(ctx, encrypt.Sha256(key))
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, ErrApiKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	if !data.Valid(now) {
+		return nil, ErrApiKeyInvalid
+	}
+
+	// 密钥按哈希全局查找，之后的查询都限定在密钥所属的租户内
+	ctx = token.WithTenantId(ctx, data.TenantId)
+	if err = s.CheckTenant(ctx); err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserModel.FindOne(ctx, data.UserId)
+	if errors.Is(err, model.ErrNotFound) {
+		return nil, ErrApiKeyInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Status == model.UserDisabled {
+		return nil, ErrApiKeyInvalid
+	}
+
+	if now-data.LastUsedAt >= apiKeyUsedInterval {
+		if err = s.ApiKeyModel.UpdateLastUsed(ctx, data.ID, now); err != nil {
+			tlog.ErrorfCtx(ctx, "AuthenticateApiKey", "update last used fail %v, id %v", err, data.ID.Hex())
+		}
+	}
+
+	ctx = context.WithValue(ctx, token.Identify, data.UserId)
+	ctx = context.WithValue(ctx, token.ApiKeyIdentify, data.ID.Hex())
+	return context.WithValue(ctx, apiKeyCtx{}, data), nil
+}
+
+// apiKeyAllow 使用API密钥访问时，资源的操作需要在密钥的授权范围内
+func apiKeyAllow(ctx context.Context, resource, action string) bool {
+	key, ok := ctx.Value(apiKeyCtx{}).(*model.ApiKey)
+	return !ok || key.Allow(resource, action)
+}
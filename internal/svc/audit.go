@@ -0,0 +1,122 @@
+package svc
+
+import (
+	"ai/internal/middleware"
+	"ai/internal/model"
+	"ai/pkg/audit"
+	"ai/token"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"gitee.com/dn-jinmin/tlog"
+)
+
+// auditCtx context中保存本次请求审计记录的键
+type auditCtx struct{}
+
+// auditRecorder 收集一次请求中业务逻辑记录的审计日志，请求结束时带上请求信息统一写入
+type auditRecorder struct {
+	mu   sync.Mutex
+	logs []*model.AuditLog
+}
+
+func (r *auditRecorder) add(data *model.AuditLog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, data)
+}
+
+func (r *auditRecorder) list() []*model.AuditLog {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.logs
+}
+
+// Audit 业务逻辑中记录对资源的操作，before、after 为操作前后的数据，新增时 before 为nil，删除时 after 为nil。
+// HTTP请求中的记录在请求结束时写入，其他场景如websocket中的AI对话直接写入；写入失败只记录日志，不影响业务
+func (s *ServiceContext) Audit(ctx context.Context, action, resource, resourceId string, before, after any) {
+	diff, err := audit.Diff(before, after)
+	if err != nil {
+		tlog.ErrorfCtx(ctx, "audit", "diff fail %v, resource %v, id %v", err, resource, resourceId)
+	}
+
+	data := &model.AuditLog{
+		ActorId:    token.GetUId(ctx),
+		ApiKeyId:   token.GetApiKeyId(ctx),
+		Action:     action,
+		Resource:   resource,
+		ResourceId: resourceId,
+		Diff:       diff,
+		TraceId:    token.GetTraceId(ctx),
+	}
+	if r, ok := ctx.Value(auditCtx{}).(*auditRecorder); ok {
+		r.add(data)
+		return
+	}
+	s.insertAudit(ctx, data)
+}
+
+// AuditBegin 见 middleware.AuditBegin
+func (s *ServiceContext) AuditBegin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditCtx{}, &auditRecorder{})
+}
+
+// AuditWrite 见 middleware.AuditWriter。业务逻辑没有记录时按请求记录一条，未登录的请求（如登录）不记录
+func (s *ServiceContext) AuditWrite(ctx context.Context, req *middleware.AuditRequest) {
+	r, _ := ctx.Value(auditCtx{}).(*auditRecorder)
+	logs := r.list()
+	if len(logs) == 0 {
+		uid := token.GetUId(ctx)
+		if len(uid) == 0 {
+			return
+		}
+		logs = []*model.AuditLog{{
+			ActorId:  uid,
+			ApiKeyId: token.GetApiKeyId(ctx),
+			Action:   auditAction(req.Method),
+			Resource: auditResource(req.Route),
+			TraceId:  token.GetTraceId(ctx),
+		}}
+	}
+
+	for _, data := range logs {
+		data.Method = req.Method
+		data.Path = req.Path
+		data.Status = req.Status
+		data.Ip = req.Ip
+		data.UserAgent = req.UserAgent
+		s.insertAudit(ctx, data)
+	}
+}
+
+func (s *ServiceContext) insertAudit(ctx context.Context, data *model.AuditLog) {
+	if err := s.AuditLogModel.Insert(ctx, data); err != nil {
+		tlog.ErrorfCtx(ctx, "audit", "insert fail %v, resource %v, id %v", err, data.Resource, data.ResourceId)
+	}
+}
+
+func auditAction(method string) string {
+	switch method {
+	case http.MethodPost:
+		return model.ActionCreate
+	case http.MethodDelete:
+		return model.ActionDelete
+	default:
+		return model.ActionUpdate
+	}
+}
+
+// auditResource 从路由中取出资源，如 /v1/user/:id 为 user
+func auditResource(route string) string {
+	for _, seg := range strings.Split(route, "/") {
+		if len(seg) > 0 && seg != "v1" {
+			return seg
+		}
+	}
+	return route
+}
@@ -0,0 +1,173 @@
+package svc
+
+import (
+	"ai/internal/model"
+	"ai/token"
+	"context"
+	"errors"
+	"slices"
+)
+
+// grant 用户被授予的角色以及角色的作用范围
+type grant struct {
+	role  *model.Role
+	depId string
+}
+
+// Authorize 校验当前用户是否具有资源的操作权限，使用API密钥访问时还需要在密钥的授权范围内
+// depId 为空时要求用户在全局范围内具有该权限，否则在该部门或其上级部门范围内具有该权限即可
+func (s *ServiceContext) Authorize(ctx context.Context, resource, action, depId string) error {
+	if !apiKeyAllow(ctx, resource, action) {
+		return ErrAuth
+	}
+
+	grants, err := s.grants(ctx)
+	if err != nil {
+		return err
+	}
+
+	var dep *model.Department
+	for _, g := range grants {
+		if !g.role.Allow(resource, action) {
+			continue
+		}
+		if len(g.depId) == 0 {
+			return nil
+		}
+		if len(depId) == 0 {
+			continue
+		}
+
+		if dep == nil {
+			if dep, err = s.DepartmentModel.FindOne(ctx, depId); err != nil {
+				return err
+			}
+		}
+		if g.depId == depId || slices.Contains(model.ParseParentPath(dep.ParentPath), g.depId) {
+			return nil
+		}
+	}
+
+	return ErrAuth
+}
+
+// AuthorizeAny 校验当前用户是否在任意范围内具有资源的操作权限
+func (s *ServiceContext) AuthorizeAny(ctx context.Context, resource, action string) error {
+	if !apiKeyAllow(ctx, resource, action) {
+		return ErrAuth
+	}
+
+	grants, err := s.grants(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range grants {
+		if g.role.Allow(resource, action) {
+			return nil
+		}
+	}
+	return ErrAuth
+}
+
+// RequireTwoFactor 判断用户的角色是否要求开启二次验证
+func (s *ServiceContext) RequireTwoFactor(ctx context.Context, uid string) (bool, error) {
+	grants, err := s.userGrants(ctx, uid)
+	if err != nil {
+		if errors.Is(err, ErrAuth) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, g := range grants {
+		if g.role.RequireTwoFactor {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// grants 获取当前用户的所有角色
+func (s *ServiceContext) grants(ctx context.Context) ([]*grant, error) {
+	uid := token.GetUId(ctx)
+	if uid == "" {
+		return nil, ErrAuth
+	}
+	return s.userGrants(ctx, uid)
+}
+
+// userGrants 获取用户的所有角色，未分配任何角色的用户默认为员工角色
+func (s *ServiceContext) userGrants(ctx context.Context, uid string) ([]*grant, error) {
+	userRoles, err := s.UserRoleModel.ListByUserId(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(userRoles) == 0 {
+		role, err := s.RoleModel.FindByCode(ctx, model.RoleEmployee)
+		if err != nil {
+			if errors.Is(err, model.ErrRoleNotFound) {
+				return nil, ErrAuth
+			}
+			return nil, err
+		}
+		return []*grant{{role: role}}, nil
+	}
+
+	roleIds := make([]string, 0, len(userRoles))
+	for i := range userRoles {
+		roleIds = append(roleIds, userRoles[i].RoleId)
+	}
+	roles, err := s.RoleModel.ListByIds(ctx, roleIds)
+	if err != nil {
+		return nil, err
+	}
+	roleById := make(map[string]*model.Role, len(roles))
+	for i := range roles {
+		roleById[roles[i].ID.Hex()] = roles[i]
+	}
+
+	grants := make([]*grant, 0, len(userRoles))
+	for i := range userRoles {
+		role, ok := roleById[userRoles[i].RoleId]
+		if !ok {
+			continue
+		}
+		grants = append(grants, &grant{
+			role:  role,
+			depId: userRoles[i].DepId,
+		})
+	}
+	return grants, nil
+}
+
+// initRole 初始化系统预置的角色，并确保系统管理员拥有管理员角色
+func initRole(svc *ServiceContext) error {
+	ctx := context.Background()
+
+	if err := svc.InitRoles(ctx); err != nil {
+		return err
+	}
+
+	systemUser, err := svc.UserModel.FindSysStemUser(ctx)
+	if err != nil {
+		return err
+	}
+	admin, err := svc.RoleModel.FindByCode(ctx, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.UserRoleModel.FindOne(ctx, systemUser.ID.Hex(), admin.ID.Hex(), "")
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, model.ErrNotFound) {
+		return err
+	}
+	return svc.UserRoleModel.Insert(ctx, &model.UserRole{
+		UserId: systemUser.ID.Hex(),
+		RoleId: admin.ID.Hex(),
+	})
+}
@@ -6,7 +6,6 @@ import (
 	"ai/internal/model"
 	"ai/pkg/langchain/callbackx"
 	"ai/pkg/mongox"
-	"ai/token"
 	"context"
 	"errors"
 
@@ -23,6 +22,7 @@ var ErrAuth = errors.New("不具有权限")
 
 type ServiceContext struct {
 	*middleware.Jwt
+	*middleware.Rbac
 
 	Config config.Config
 
@@ -33,15 +33,31 @@ type ServiceContext struct {
 	model.DepartmentUserModel
 	model.UserTodoModel
 	model.TodoModel
+	model.TodoColumnModel
 	model.ApprovalModel
 	model.ChatlogModel
+	model.RoleModel
+	model.UserRoleModel
+	model.OffboardingModel
+	model.SessionModel
+	model.LoginAttemptModel
+	model.LoginEventModel
+	model.ApiKeyModel
+	model.TenantModel
+	model.AuditLogModel
+	model.GroupModel
+	model.DeliveryModel
+	model.ConversationModel
+	model.PresenceModel
+	model.SsoStateModel
+
+	// Tenant 服务上下文所属的租户，默认租户为nil，见 WithTenant
+	Tenant *model.Tenant
 
 	LLMs           *openai.LLM
 	AliProxyOpenai *openaiSdk.Client
 	OpenaiClient   *openaiSdk.Client
 	Callbacks      callbacks.Handler
-
-	Auth func(ctx context.Context) error
 }
 
 func NewServiceContext(c config.Config) (*ServiceContext, error) {
@@ -63,18 +79,11 @@ func NewServiceContext(c config.Config) (*ServiceContext, error) {
 		Callbacks: []callbacks.Handler{
 			callbackx.NewLogHandler(logger),
 			callbackx.NewTitTokenHandle(logger),
+			callbackx.NewStreamHandle(),
 		},
 	}
 
-	opts := []openai.Option{
-		openai.WithBaseURL(c.Langchain.Url),
-		openai.WithToken(c.Langchain.ApiKey),
-		openai.WithCallback(callbacks),
-		openai.WithEmbeddingModel("text-embedding-ada-002"),
-		openai.WithModel("gpt-3.5-turbo"),
-	}
-
-	llm, err := openai.New(opts...)
+	llm, err := newLLM(c.Langchain.Url, c.Langchain.ApiKey, "", callbacks)
 	if err != nil {
 		return nil, err
 	}
@@ -90,41 +99,45 @@ func NewServiceContext(c config.Config) (*ServiceContext, error) {
 	userModel := model.NewUserModel(mongoDb)
 	svc := &ServiceContext{
 		Config:              c,
-		Jwt:                 middleware.NewJwt(c.Jwt.Secret),
 		Mongo:               mongoDb,
 		UserModel:           userModel,
 		DepartmentUserModel: model.NewDepartmentUserModel(mongoDb),
 		DepartmentModel:     model.NewDepartmentModel(mongoDb),
 		UserTodoModel:       model.NewUserTodoModel(mongoDb),
 		TodoModel:           model.NewTodoModel(mongoDb),
+		TodoColumnModel:     model.NewTodoColumnModel(mongoDb),
 		ApprovalModel:       model.NewApprovalModel(mongoDb),
 		ChatlogModel:        model.NewChatlogModel(mongoDb),
+		RoleModel:           model.NewRoleModel(mongoDb),
+		UserRoleModel:       model.NewUserRoleModel(mongoDb),
+		OffboardingModel:    model.NewOffboardingModel(mongoDb),
+		SessionModel:        model.NewSessionModel(mongoDb),
+		LoginAttemptModel:   model.NewLoginAttemptModel(mongoDb),
+		LoginEventModel:     model.NewLoginEventModel(mongoDb),
+		ApiKeyModel:         model.NewApiKeyModel(mongoDb),
+		TenantModel:         model.NewTenantModel(mongoDb),
+		AuditLogModel:       model.NewAuditLogModel(mongoDb),
+		GroupModel:          model.NewGroupModel(mongoDb),
+		DeliveryModel:       model.NewDeliveryModel(mongoDb),
+		ConversationModel:   model.NewConversationModel(mongoDb),
+		PresenceModel:       model.NewPresenceModel(mongoDb),
+		SsoStateModel:       model.NewSsoStateModel(mongoDb),
 
 		LLMs:           llm,
 		Callbacks:      callbacks,
 		AliProxyOpenai: aliProxyOpenAi,
 		OpenaiClient:   openaiGPT,
+	}
 
-		Auth: func(ctx context.Context) error {
-			uid := token.GetUId(ctx)
-			if uid == "" {
-				return ErrAuth
-			}
-
-			user, err := userModel.FindOne(ctx, uid)
-			if err != nil {
-				return err
-			}
-
-			if !user.IsSystem {
-				return ErrAuth
-			}
+	svc.Jwt = middleware.NewJwt(c.Jwt.Secret, svc.CheckSession, svc.CheckTwoFactor, svc.AuthenticateApiKey)
+	svc.Rbac = middleware.NewRbac(func(ctx context.Context, resource, action string) error {
+		return svc.Authorize(ctx, resource, action, "")
+	}, svc.AuthorizeAny)
 
-			return nil
-		},
+	if err = initUser(svc); err != nil {
+		return nil, err
 	}
-
-	return svc, initUser(svc)
+	return svc, initRole(svc)
 }
 
 // initUser 初始化用户数据，确保系统存在默认的管理员用户
@@ -143,11 +156,12 @@ func initUser(svc *ServiceContext) error {
 	}
 
 	// 如果系统用户不存在，则创建默认的root用户
-	// 密码是经过bcrypt加密的"000000"（示例）
+	// 密码是经过bcrypt加密的"000000"（示例），首次登录时必须修改
 	return svc.UserModel.Insert(ctx, &model.User{
-		Name:     "root",
-		Password: "$2a$10$ddIvqt7U6zNA9poys.FNCuEZTJY6V.axWy4P7A44TuT9KBegGZlD6",
-		Status:   0,
-		IsSystem: true,
+		Name:               "root",
+		Password:           "$2a$10$ddIvqt7U6zNA9poys.FNCuEZTJY6V.axWy4P7A44TuT9KBegGZlD6",
+		Status:             0,
+		IsSystem:           true,
+		MustChangePassword: true,
 	})
 }
@@ -0,0 +1,58 @@
+package svc
+
+import (
+	"ai/internal/model"
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrSessionInvalid    = errors.New("登录已失效，请重新登录")
+	ErrTwoFactorRequired = errors.New("需要完成二次验证")
+)
+
+// CheckSession 校验令牌所属的会话没有被撤销或过期，Jwt中间件和websocket鉴权都需要校验
+func (s *ServiceContext) CheckSession(ctx context.Context, uid, sid string) error {
+	_, err := s.session(ctx, uid, sid)
+	return err
+}
+
+// CheckTwoFactor 在 CheckSession 的基础上，角色要求二次验证时会话必须已完成二次验证
+func (s *ServiceContext) CheckTwoFactor(ctx context.Context, uid, sid string) error {
+	session, err := s.session(ctx, uid, sid)
+	if err != nil || session.TwoFactor {
+		return err
+	}
+
+	required, err := s.RequireTwoFactor(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrTwoFactorRequired
+	}
+	return nil
+}
+
+func (s *ServiceContext) session(ctx context.Context, uid, sid string) (*model.Session, error) {
+	if len(sid) == 0 {
+		return nil, ErrSessionInvalid
+	}
+
+	session, err := s.SessionModel.FindOne(ctx, sid)
+	if errors.Is(err, model.ErrNotFound) || errors.Is(err, model.ErrInvalidObjectId) {
+		return nil, ErrSessionInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if session.UserId != uid || !session.Valid(time.Now().Unix()) {
+		return nil, ErrSessionInvalid
+	}
+	if err = s.CheckTenant(ctx); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
@@ -0,0 +1,101 @@
+package svc
+
+import (
+	"ai/internal/model"
+	"ai/token"
+	"context"
+	"errors"
+
+	openaiSdk "github.com/sashabaranov/go-openai"
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// defaultLLMModel 未配置模型时使用的大模型
+const defaultLLMModel = "gpt-3.5-turbo"
+
+var ErrTenantDisabled = errors.New("租户已停用")
+
+// CurrentTenant 获取当前租户，默认租户返回nil
+func (s *ServiceContext) CurrentTenant(ctx context.Context) (*model.Tenant, error) {
+	tid := token.GetTenantId(ctx)
+	if len(tid) == 0 {
+		return nil, nil
+	}
+	return s.TenantModel.FindOne(ctx, tid)
+}
+
+// CheckTenant 校验当前租户存在且未停用
+func (s *ServiceContext) CheckTenant(ctx context.Context) error {
+	t, err := s.CurrentTenant(ctx)
+	if errors.Is(err, model.ErrTenantNotFound) || errors.Is(err, model.ErrInvalidObjectId) {
+		return ErrSessionInvalid
+	}
+	if err != nil {
+		return err
+	}
+	if t != nil && t.Status == model.TenantDisabled {
+		return ErrTenantDisabled
+	}
+	return nil
+}
+
+// WithTenant 返回使用租户配置的服务上下文，租户配置了大模型服务时使用租户自己的大模型
+func (s *ServiceContext) WithTenant(t *model.Tenant) (*ServiceContext, error) {
+	if t == nil {
+		return s, nil
+	}
+
+	res := *s
+	res.Tenant = t
+	if t.Config == nil || t.Config.Llm == nil || len(t.Config.Llm.Url) == 0 {
+		return &res, nil
+	}
+
+	llm, err := newLLM(t.Config.Llm.Url, t.Config.Llm.ApiKey, t.Config.Llm.Model, s.Callbacks)
+	if err != nil {
+		return nil, err
+	}
+	cfg := openaiSdk.DefaultConfig(t.Config.Llm.ApiKey)
+	cfg.BaseURL = t.Config.Llm.Url
+
+	res.LLMs = llm
+	res.OpenaiClient = openaiSdk.NewClientWithConfig(cfg)
+	return &res, nil
+}
+
+// KnowledgeIndex 当前服务上下文所属租户的知识库索引
+func (s *ServiceContext) KnowledgeIndex() string {
+	return model.KnowledgeIndex(s.Tenant)
+}
+
+// InitRoles 在当前租户中初始化系统预置的角色，已存在的角色不会覆盖
+func (s *ServiceContext) InitRoles(ctx context.Context) error {
+	for _, role := range model.DefaultRoles() {
+		_, err := s.RoleModel.FindByCode(ctx, role.Code)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, model.ErrRoleNotFound) {
+			return err
+		}
+		if err = s.RoleModel.Insert(ctx, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newLLM 创建大模型客户端，model 为空时使用默认模型
+func newLLM(url, apiKey, model string, cb callbacks.Handler) (*openai.LLM, error) {
+	if len(model) == 0 {
+		model = defaultLLMModel
+	}
+	return openai.New(
+		openai.WithBaseURL(url),
+		openai.WithToken(apiKey),
+		openai.WithCallback(cb),
+		openai.WithEmbeddingModel("text-embedding-ada-002"),
+		openai.WithModel(model),
+	)
+}
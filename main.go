@@ -63,7 +63,10 @@ func main() {
 		if err != nil {
 			panic(err)
 		}
-		srv := ws.NewWs(svc)
+		srv, err := ws.NewWs(svc)
+		if err != nil {
+			panic(err)
+		}
 		srv.Run()
 	}()
 
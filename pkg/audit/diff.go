@@ -0,0 +1,129 @@
+// Package audit 计算审计日志中记录的数据变更
+package audit
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Redacted 敏感字段变更后记录的值
+const Redacted = "******"
+
+// sensitive 不记录原值的字段，按字段名的小写匹配
+var sensitive = map[string]bool{
+	"password":         true,
+	"totpsecret":       true,
+	"recoverycodes":    true,
+	"hash":             true,
+	"refreshtoken":     true,
+	"prevrefreshtoken": true,
+	"apikey":           true,
+	"secret":           true,
+}
+
+// ignored 每次修改都会变化的字段，不作为变更记录
+var ignored = map[string]bool{
+	"updateAt": true,
+}
+
+// Change 一个字段的变更，嵌套的字段使用 . 连接，新增时 Before 为空，删除时 After 为空
+type Change struct {
+	Field  string `bson:"field" json:"field"`
+	Before any    `bson:"before,omitempty" json:"before,omitempty"`
+	After  any    `bson:"after,omitempty" json:"after,omitempty"`
+}
+
+// Diff 比较修改前后的数据，按 bson 字段名返回有变化的字段，before 或 after 为nil时表示新增或删除。
+// 数组作为整体比较，敏感字段只记录发生了变更
+func Diff(before, after any) ([]*Change, error) {
+	b, err := flatten(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := flatten(after)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(a)+len(b))
+	for k := range b {
+		fields = append(fields, k)
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+
+	var changes []*Change
+	for _, field := range fields {
+		bv, av := b[field], a[field]
+		if reflect.DeepEqual(bv, av) {
+			continue
+		}
+		if isSensitive(field) {
+			bv, av = redact(bv), redact(av)
+		}
+		changes = append(changes, &Change{
+			Field:  field,
+			Before: bv,
+			After:  av,
+		})
+	}
+	return changes, nil
+}
+
+func flatten(v any) (map[string]any, error) {
+	res := make(map[string]any)
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil() {
+		return res, nil
+	}
+
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err = bson.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	flattenDoc(res, "", doc)
+	return res, nil
+}
+
+func flattenDoc(res map[string]any, prefix string, doc bson.D) {
+	for _, e := range doc {
+		if len(prefix) == 0 && ignored[e.Key] {
+			continue
+		}
+		key := e.Key
+		if len(prefix) > 0 {
+			key = prefix + "." + e.Key
+		}
+		if sub, ok := e.Value.(bson.D); ok {
+			flattenDoc(res, key, sub)
+			continue
+		}
+		res[key] = e.Value
+	}
+}
+
+func isSensitive(field string) bool {
+	for _, name := range strings.Split(field, ".") {
+		if sensitive[strings.ToLower(name)] {
+			return true
+		}
+	}
+	return false
+}
+
+func redact(v any) any {
+	if v == nil {
+		return nil
+	}
+	return Redacted
+}
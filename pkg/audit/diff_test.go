@@ -0,0 +1,93 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type profile struct {
+	Email string `bson:"email,omitempty"`
+	Phone string `bson:"phone,omitempty"`
+}
+
+type user struct {
+	Name     string   `bson:"name"`
+	Password string   `bson:"password"`
+	Status   int      `bson:"status"`
+	Tags     []string `bson:"tags,omitempty"`
+	Profile  *profile `bson:"profile,omitempty"`
+	UpdateAt int64    `bson:"updateAt,omitempty"`
+}
+
+func TestDiff(t *testing.T) {
+	before := &user{
+		Name:     "zhangsan",
+		Password: "hash-1",
+		Tags:     []string{"a"},
+		Profile:  &profile{Email: "a@example.com"},
+		UpdateAt: 1,
+	}
+	after := &user{
+		Name:     "zhangsan",
+		Password: "hash-2",
+		Status:   1,
+		Tags:     []string{"a", "b"},
+		Profile:  &profile{Email: "a@example.com", Phone: "13800000000"},
+		UpdateAt: 2,
+	}
+
+	changes, err := Diff(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][2]any{
+		"password":      {Redacted, Redacted},
+		"profile.phone": {nil, "13800000000"},
+		"status":        {int32(0), int32(1)},
+		"tags":          {bson.A{"a"}, bson.A{"a", "b"}},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %d, want %d", len(changes), len(want))
+	}
+	for _, c := range changes {
+		w, ok := want[c.Field]
+		if !ok {
+			t.Fatalf("unexpected change %s", c.Field)
+		}
+		if !reflect.DeepEqual(c.Before, w[0]) || !reflect.DeepEqual(c.After, w[1]) {
+			t.Fatalf("%s: %v -> %v, want %v -> %v", c.Field, c.Before, c.After, w[0], w[1])
+		}
+	}
+}
+
+func TestDiffCreateDelete(t *testing.T) {
+	u := &user{Name: "lisi", Password: "hash"}
+
+	changes, err := Diff(nil, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("create changes = %d", len(changes))
+	}
+	for _, c := range changes {
+		if c.Before != nil {
+			t.Fatalf("create %s before = %v", c.Field, c.Before)
+		}
+		if c.Field == "password" && c.After != Redacted {
+			t.Fatalf("password not redacted")
+		}
+	}
+
+	var nilUser *user
+	changes, err = Diff(u, nilUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 || changes[0].After != nil {
+		t.Fatalf("delete changes = %+v", changes)
+	}
+}
@@ -1,6 +1,12 @@
 package encrypt
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 // hash加密
 func GenPasswordHash(password []byte) ([]byte, error) {
@@ -14,3 +20,18 @@ func ValidatePasswordHash(password string, hashed string) bool {
 	}
 	return true
 }
+
+// RandomToken 生成指定字节数的随机令牌，以十六进制字符串返回
+func RandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Sha256 计算字符串的sha256哈希，用于保存令牌等不需要还原的敏感数据
+func Sha256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,56 @@
+package encrypt
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+var (
+	ErrPasswordUpper  = errors.New("密码需要包含大写字母")
+	ErrPasswordLower  = errors.New("密码需要包含小写字母")
+	ErrPasswordDigit  = errors.New("密码需要包含数字")
+	ErrPasswordSymbol = errors.New("密码需要包含特殊字符")
+)
+
+// PasswordPolicy 密码复杂度策略
+type PasswordPolicy struct {
+	MinLength     int  // 最小长度
+	RequireUpper  bool // 需要包含大写字母
+	RequireLower  bool // 需要包含小写字母
+	RequireDigit  bool // 需要包含数字
+	RequireSymbol bool // 需要包含特殊字符
+}
+
+// Validate 校验密码是否满足复杂度策略
+func (p *PasswordPolicy) Validate(password string) error {
+	if n := len([]rune(password)); n < p.MinLength {
+		return fmt.Errorf("密码长度不能少于%d位", p.MinLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+
+	switch {
+	case p.RequireUpper && !upper:
+		return ErrPasswordUpper
+	case p.RequireLower && !lower:
+		return ErrPasswordLower
+	case p.RequireDigit && !digit:
+		return ErrPasswordDigit
+	case p.RequireSymbol && !symbol:
+		return ErrPasswordSymbol
+	}
+	return nil
+}
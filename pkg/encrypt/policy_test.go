@@ -0,0 +1,44 @@
+package encrypt
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	p := &PasswordPolicy{
+		MinLength:     8,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+
+	tests := []struct {
+		password string
+		ok       bool
+		err      error // 为空时只校验返回错误
+	}{
+		{"Aa1!aaaa", true, nil},
+		{"Aa1!", false, nil},
+		{"aa1!aaaa", false, ErrPasswordUpper},
+		{"AA1!AAAA", false, ErrPasswordLower},
+		{"Aa!aaaaa", false, ErrPasswordDigit},
+		{"Aa1aaaaa", false, ErrPasswordSymbol},
+	}
+	for _, tt := range tests {
+		err := p.Validate(tt.password)
+		switch {
+		case tt.ok && err != nil:
+			t.Errorf("Validate(%q) = %v, want nil", tt.password, err)
+		case !tt.ok && err == nil:
+			t.Errorf("Validate(%q) = nil, want error", tt.password)
+		case tt.err != nil && !errors.Is(err, tt.err):
+			t.Errorf("Validate(%q) = %v, want %v", tt.password, err, tt.err)
+		}
+	}
+
+	if err := (&PasswordPolicy{}).Validate("000000"); err != nil {
+		t.Errorf("empty policy should accept any password, got %v", err)
+	}
+}
@@ -67,6 +67,12 @@ func Fail(ctx *gin.Context) {
 }
 
 func FailWithErr(ctx *gin.Context, err error) {
+	res := ErrorResult(ctx, err)
+	Result(ctx, res.Code, res.Data, res.Msg)
+}
+
+// ErrorResult 按错误处理器转换错误，用于不能直接返回JSON的响应，如SSE的错误事件
+func ErrorResult(ctx *gin.Context, err error) *Response {
 	errorLock.RLock()
 	handler := errorHandler
 	errorLock.RUnlock()
@@ -75,5 +81,5 @@ func FailWithErr(ctx *gin.Context, err error) {
 	if handler != nil {
 		code, err = handler(ctx, err)
 	}
-	Result(ctx, code, NULL, err.Error())
+	return &Response{Code: code, Data: NULL, Msg: err.Error()}
 }
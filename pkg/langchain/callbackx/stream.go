@@ -0,0 +1,221 @@
+package callbackx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/tmc/langchaingo/callbacks"
+	"github.com/tmc/langchaingo/chains"
+	"github.com/tmc/langchaingo/schema"
+)
+
+// 流式输出的事件类型
+const (
+	StreamProgress = "progress" // 处理进度
+	StreamChunk    = "chunk"    // 回复的部分文本
+)
+
+// 处理进度的阶段
+const (
+	StepRoute = "route" // 路由选择了处理器
+	StepTool  = "tool"  // 代理调用了工具
+)
+
+// routeDestinations 路由决策中选择的处理器字段，与 router 的提示词一致
+const routeDestinations = "destinations"
+
+// StreamEvent 流式输出的事件
+type StreamEvent struct {
+	Type string
+	Step string // 处理进度的阶段
+	Name string // 选择的处理器或调用的工具
+	Text string // 回复的部分文本
+}
+
+// Streamer 接收一次对话中流式输出的事件
+type Streamer func(ctx context.Context, ev StreamEvent)
+
+type streamerKey struct{}
+
+// WithStreamer 在上下文中设置流式输出的接收者，对话中的回调和最终生成回复的LLM据此推送事件
+func WithStreamer(ctx context.Context, s Streamer) context.Context {
+	return context.WithValue(ctx, streamerKey{}, s)
+}
+
+// GetStreamer 获取上下文中流式输出的接收者，没有时为nil
+func GetStreamer(ctx context.Context) Streamer {
+	s, _ := ctx.Value(streamerKey{}).(Streamer)
+	return s
+}
+
+// StreamOptions 上下文中有接收者时，将LLM生成的文本作为部分文本推送。
+// structured 为true时LLM输出的是结构化的 ChatResp，只推送其中 data 字段的文本
+func StreamOptions(ctx context.Context, structured bool) []chains.ChainCallOption {
+	s := GetStreamer(ctx)
+	if s == nil {
+		return nil
+	}
+
+	d := &dataStream{emit: func(text string) {
+		s(ctx, StreamEvent{Type: StreamChunk, Text: text})
+	}}
+	if !structured {
+		d.state = streamRaw
+	}
+	return []chains.ChainCallOption{chains.WithStreamingFunc(func(_ context.Context, chunk []byte) error {
+		d.write(chunk)
+		return nil
+	})}
+}
+
+// StreamHandle 将路由选择的处理器和代理调用的工具作为处理进度推送给上下文中的接收者
+type StreamHandle struct {
+	callbacks.SimpleHandler
+}
+
+func NewStreamHandle() *StreamHandle {
+	return &StreamHandle{}
+}
+
+// HandleChainEnd 路由结束时推送选择的处理器
+func (h *StreamHandle) HandleChainEnd(ctx context.Context, outputs map[string]any) {
+	s := GetStreamer(ctx)
+	if s == nil {
+		return
+	}
+	// 路由结束的回调中 out 为解析后的路由决策
+	out, ok := outputs["out"].(map[string]string)
+	if !ok {
+		return
+	}
+	if name, ok := out[routeDestinations]; ok {
+		s(ctx, StreamEvent{Type: StreamProgress, Step: StepRoute, Name: name})
+	}
+}
+
+// HandleAgentAction 代理调用工具前推送工具名称
+func (h *StreamHandle) HandleAgentAction(ctx context.Context, action schema.AgentAction) {
+	if s := GetStreamer(ctx); s != nil {
+		s(ctx, StreamEvent{Type: StreamProgress, Step: StepTool, Name: action.Tool})
+	}
+}
+
+const (
+	streamSeek  = iota // 查找 data 字段
+	streamValue        // 在 data 字段的字符串中
+	streamRaw          // 输出不是JSON，原样推送
+	streamDone         // data 字段已结束
+)
+
+// dataReg JSON中 data 字段字符串值的开始
+var dataReg = regexp.MustCompile(`"data"\s*:\s*"`)
+
+// dataStream 从流式输出的JSON中提取 data 字段的字符串值并解码转义，输出不是JSON时原样推送。
+// 不完整的UTF-8字符和转义留到下一段输出
+type dataStream struct {
+	emit  func(text string)
+	state int
+	buf   []byte // 找到 data 字段之前的输出
+	esc   []byte // 未完成的转义
+	out   []byte // 未推送的文本
+}
+
+func (d *dataStream) write(chunk []byte) {
+	switch d.state {
+	case streamSeek:
+		d.buf = append(d.buf, chunk...)
+		// 输出以代码块或JSON开始时才查找 data 字段
+		if s := bytes.TrimSpace(d.buf); len(s) > 0 && s[0] != '{' && s[0] != '`' {
+			d.state = streamRaw
+			d.out = append(d.out, d.buf...)
+			d.buf = nil
+			break
+		}
+		loc := dataReg.FindIndex(d.buf)
+		if loc == nil {
+			return
+		}
+		rest := d.buf[loc[1]:]
+		d.state = streamValue
+		d.buf = nil
+		d.value(rest)
+	case streamValue:
+		d.value(chunk)
+	case streamRaw:
+		d.out = append(d.out, chunk...)
+	default:
+		return
+	}
+	d.flush()
+}
+
+// value 解码 data 字段字符串中的内容，遇到结束的引号后不再推送
+func (d *dataStream) value(b []byte) {
+	for _, c := range b {
+		if d.state != streamValue {
+			return
+		}
+
+		switch {
+		case len(d.esc) > 0:
+			d.esc = append(d.esc, c)
+			d.unescape()
+		case c == '\\':
+			d.esc = append(d.esc, c)
+		case c == '"':
+			d.state = streamDone
+		default:
+			d.out = append(d.out, c)
+		}
+	}
+}
+
+// unescape 转义完整后解码，\u 转义为代理对的高位时等待低位
+func (d *dataStream) unescape() {
+	n := 2
+	if d.esc[1] == 'u' {
+		n = 6
+		if len(d.esc) >= 6 && isHighSurrogate(d.esc[2:6]) {
+			n = 12
+		}
+	}
+	if len(d.esc) < n {
+		return
+	}
+
+	var s string
+	if err := json.Unmarshal(append(append([]byte{'"'}, d.esc...), '"'), &s); err == nil {
+		d.out = append(d.out, s...)
+	}
+	d.esc = nil
+}
+
+// isHighSurrogate \u 转义的4位十六进制是否为代理对的高位 D800-DBFF
+func isHighSurrogate(hex []byte) bool {
+	r, err := strconv.ParseUint(string(hex), 16, 16)
+	return err == nil && utf16.IsSurrogate(rune(r)) && r < 0xdc00
+}
+
+// flush 推送完整的UTF-8字符
+func (d *dataStream) flush() {
+	n := len(d.out)
+	for i := n - 1; i >= 0 && i >= n-utf8.UTFMax; i-- {
+		if utf8.RuneStart(d.out[i]) {
+			if !utf8.FullRune(d.out[i:]) {
+				n = i
+			}
+			break
+		}
+	}
+	if n == 0 {
+		return
+	}
+
+	d.emit(string(d.out[:n]))
+	d.out = append(d.out[:0], d.out[n:]...)
+}
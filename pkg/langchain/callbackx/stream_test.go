@@ -0,0 +1,59 @@
+package callbackx
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_dataStream(t *testing.T) {
+	tests := []struct {
+		name       string
+		structured bool
+		chunks     []string
+		want       string
+	}{
+		{
+			name:       "json",
+			structured: true,
+			chunks:     []string{`{"chatType": 0, "da`, `ta": "你好`, `\n世界\"`, `中", "x": "y"}`},
+			want:       "你好\n世界\"中",
+		}, {
+			name:       "code block",
+			structured: true,
+			chunks:     []string{"```json\n{\"data\":", "\"ok\"}\n```"},
+			want:       "ok",
+		}, {
+			name:       "surrogate",
+			structured: true,
+			chunks:     []string{`{"data":"\ud83d`, `\ude00"}`},
+			want:       "😀",
+		}, {
+			name:       "plain text",
+			structured: true,
+			chunks:     []string{"你", "好"},
+			want:       "你好",
+		}, {
+			name:   "raw split rune",
+			chunks: []string{"你"[:2], "你"[2:] + "好"},
+			want:   "你好",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			d := &dataStream{emit: func(text string) {
+				got.WriteString(text)
+			}}
+			if !tt.structured {
+				d.state = streamRaw
+			}
+			for _, c := range tt.chunks {
+				d.write([]byte(c))
+			}
+			if got.String() != tt.want {
+				t.Fatalf("got %q, want %q", got.String(), tt.want)
+			}
+		})
+	}
+}
@@ -59,3 +59,23 @@ func (t *MongodbConfig) GetApplyURI() []*options.ClientOptions {
 	}
 	return ops
 }
+
+// Transaction 在事务中执行fn，fn中的数据库操作需要使用传入的ctx才会加入到事务中。
+// ctx已经在事务中时直接执行fn，加入外层的事务
+// 注意：MongoDB的事务需要副本集或分片集群的部署方式
+func Transaction(ctx context.Context, db *mongo.Database, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := db.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
@@ -0,0 +1,334 @@
+// Package oidc OpenID Connect 授权码模式的客户端，只依赖标准库和jwt
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	// DefaultGroupsClaim 默认的用户组声明字段
+	DefaultGroupsClaim = "groups"
+)
+
+var (
+	ErrIdTokenInvalid = errors.New("oidc: id_token 无效")
+	ErrNonceInvalid   = errors.New("oidc: nonce 不匹配")
+)
+
+// Config 身份提供方及客户端配置
+type Config struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string   // 身份提供方回调的地址，需要在身份提供方登记
+	Scopes       []string // 为空时使用 openid profile email
+	GroupsClaim  string   // id_token 中用户组的声明字段，为空时使用 groups
+}
+
+// Metadata 身份提供方的发现文档
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// Token 授权码换取的令牌
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IdToken     string `json:"id_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Claims id_token 中的用户信息
+type Claims struct {
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+	Groups            []string
+	Raw               map[string]any
+}
+
+type Provider struct {
+	cfg      Config
+	client   *http.Client
+	metadata Metadata
+
+	mu   sync.RWMutex
+	keys map[string]*rsa.PublicKey
+}
+
+// NewProvider 读取身份提供方的发现文档，client 为空时使用 http.DefaultClient
+func NewProvider(ctx context.Context, cfg Config, client *http.Client) (*Provider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "profile", "email"}
+	}
+	if len(cfg.GroupsClaim) == 0 {
+		cfg.GroupsClaim = DefaultGroupsClaim
+	}
+
+	p := &Provider{
+		cfg:    cfg,
+		client: client,
+	}
+	issuer := strings.TrimSuffix(cfg.Issuer, "/")
+	if err := p.getJson(ctx, issuer+discoveryPath, &p.metadata); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(p.metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc: issuer 不匹配，配置为 %s，发现文档为 %s", cfg.Issuer, p.metadata.Issuer)
+	}
+	return p, nil
+}
+
+func (p *Provider) Metadata() Metadata {
+	return p.metadata
+}
+
+// AuthCodeURL 生成跳转到身份提供方的登录地址，codeVerifier 为PKCE的校验码，换取令牌时需要传入同一个值
+func (p *Provider) AuthCodeURL(state, nonce, codeVerifier string) string {
+	v := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientId},
+		"redirect_uri":          {p.cfg.RedirectUrl},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.metadata.AuthorizationEndpoint + sep + v.Encode()
+}
+
+// Exchange 使用授权码和生成登录地址时的PKCE校验码换取令牌
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectUrl},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientId), url.QueryEscape(p.cfg.ClientSecret))
+
+	var tok Token
+	if err = p.do(req, &tok); err != nil {
+		return nil, err
+	}
+	if len(tok.IdToken) == 0 {
+		return nil, errors.New("oidc: 令牌响应中没有 id_token")
+	}
+	return &tok, nil
+}
+
+// CodeChallenge 按 S256 方式由PKCE的校验码生成 code_challenge
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Verify 校验 id_token 的签名、签发方、受众、有效期和 nonce，返回用户信息
+func (p *Provider) Verify(ctx context.Context, rawIdToken, nonce string) (*Claims, error) {
+	tok, err := jwt.Parse(rawIdToken, func(t *jwt.Token) (any, error) {
+		if t.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+			return nil, fmt.Errorf("oidc: 不支持的签名算法 %s", t.Method.Alg())
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrIdTokenInvalid, err)
+	}
+	raw, ok := tok.Claims.(jwt.MapClaims)
+	if !ok || !tok.Valid {
+		return nil, ErrIdTokenInvalid
+	}
+
+	if iss, _ := raw["iss"].(string); strings.TrimSuffix(iss, "/") != strings.TrimSuffix(p.metadata.Issuer, "/") {
+		return nil, fmt.Errorf("%w: issuer 不匹配", ErrIdTokenInvalid)
+	}
+	if !containsAudience(raw["aud"], p.cfg.ClientId) {
+		return nil, fmt.Errorf("%w: audience 不匹配", ErrIdTokenInvalid)
+	}
+	if _, ok := raw["exp"]; !ok {
+		return nil, fmt.Errorf("%w: 缺少 exp", ErrIdTokenInvalid)
+	}
+	if n, _ := raw["nonce"].(string); n != nonce {
+		return nil, ErrNonceInvalid
+	}
+
+	claims := &Claims{
+		Groups: stringList(raw[p.cfg.GroupsClaim]),
+		Raw:    raw,
+	}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Email, _ = raw["email"].(string)
+	claims.EmailVerified, _ = raw["email_verified"].(bool)
+	claims.Name, _ = raw["name"].(string)
+	claims.PreferredUsername, _ = raw["preferred_username"].(string)
+	if len(claims.Subject) == 0 {
+		return nil, fmt.Errorf("%w: 缺少 sub", ErrIdTokenInvalid)
+	}
+	return claims, nil
+}
+
+// key 获取签名公钥，找不到时重新拉取一次，用于身份提供方轮换密钥的情况
+func (p *Provider) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.RLock()
+	k, ok := p.lookup(kid)
+	p.mu.RUnlock()
+	if ok {
+		return k, nil
+	}
+
+	keys, err := p.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = keys
+	if k, ok = p.lookup(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("oidc: 未找到签名公钥 %s", kid)
+}
+
+// lookup kid 为空且只有一个公钥时使用该公钥
+func (p *Provider) lookup(kid string) (*rsa.PublicKey, bool) {
+	if len(kid) == 0 && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+func (p *Provider) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := p.getJson(ctx, p.metadata.JwksUri, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (len(k.Use) > 0 && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: 公钥 %s 格式错误: %v", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("oidc: 公钥 %s 格式错误: %v", k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+func (p *Provider) getJson(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	return p.do(req, v)
+}
+
+func (p *Provider) do(req *http.Request, v any) error {
+	client := *p.client
+	if client.Timeout == 0 {
+		client.Timeout = 10 * time.Second
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: %s %s 返回 %d: %s", req.Method, req.URL.Path, resp.StatusCode, body)
+	}
+	return json.Unmarshal(body, v)
+}
+
+func containsAudience(aud any, clientId string) bool {
+	for _, v := range stringList(aud) {
+		if v == clientId {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList 声明的值可能是单个字符串或字符串数组
+func stringList(v any) []string {
+	switch val := v.(type) {
+	case string:
+		return []string{val}
+	case []string:
+		return val
+	case []any:
+		res := make([]string, 0, len(val))
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"ai/pkg/oidc"
+	"ai/pkg/oidc/oidctest"
+)
+
+func newProvider(t *testing.T) (*oidctest.Server, *oidc.Provider) {
+	t.Helper()
+
+	srv, err := oidctest.NewServer("aiworkc", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+
+	p, err := oidc.NewProvider(context.Background(), oidc.Config{
+		Issuer:       srv.Issuer(),
+		ClientId:     "aiworkc",
+		ClientSecret: "secret",
+		RedirectUrl:  "http://127.0.0.1:8888/sso/callback",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, p
+}
+
+func TestLogin(t *testing.T) {
+	srv, p := newProvider(t)
+	srv.SetUser(map[string]any{
+		"sub":                "u-1001",
+		"email":              "zhangsan@example.com",
+		"email_verified":     true,
+		"name":               "张三",
+		"preferred_username": "zhangsan",
+		"groups":             []string{"研发部", "admin"},
+	})
+
+	code, state, err := srv.Code(p.AuthCodeURL("state-1", "nonce-1", "verifier-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != "state-1" {
+		t.Fatalf("state = %q", state)
+	}
+
+	ctx := context.Background()
+	tok, err := p.Exchange(ctx, code, "verifier-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := p.Verify(ctx, tok.IdToken, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.Subject != "u-1001" || claims.Email != "zhangsan@example.com" || !claims.EmailVerified ||
+		claims.Name != "张三" || claims.PreferredUsername != "zhangsan" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	if !slices.Equal(claims.Groups, []string{"研发部", "admin"}) {
+		t.Fatalf("groups = %v", claims.Groups)
+	}
+
+	// 授权码只能使用一次
+	if _, err = p.Exchange(ctx, code, "verifier-1"); err == nil {
+		t.Fatal("expected error when reusing code")
+	}
+}
+
+func TestVerifyFail(t *testing.T) {
+	srv, p := newProvider(t)
+	srv.SetUser(map[string]any{"sub": "u-1001"})
+
+	ctx := context.Background()
+	code, _, err := srv.Code(p.AuthCodeURL("state", "nonce", "verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := p.Exchange(ctx, code, "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = p.Verify(ctx, tok.IdToken, "other"); !errors.Is(err, oidc.ErrNonceInvalid) {
+		t.Fatalf("nonce mismatch: err = %v", err)
+	}
+	if _, err = p.Verify(ctx, tok.IdToken+"x", "nonce"); !errors.Is(err, oidc.ErrIdTokenInvalid) {
+		t.Fatalf("bad signature: err = %v", err)
+	}
+}
+
+func TestCodeVerifierMismatch(t *testing.T) {
+	srv, p := newProvider(t)
+	srv.SetUser(map[string]any{"sub": "u-1001"})
+
+	code, _, err := srv.Code(p.AuthCodeURL("state", "nonce", "verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Exchange(context.Background(), code, "other"); err == nil {
+		t.Fatal("expected error when code_verifier does not match")
+	}
+}
+
+func TestIssuerMismatch(t *testing.T) {
+	srv, err := oidctest.NewServer("aiworkc", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	_, err = oidc.NewProvider(context.Background(), oidc.Config{
+		Issuer:   srv.Issuer() + "/other",
+		ClientId: "aiworkc",
+	}, nil)
+	if err == nil {
+		t.Fatal("expected issuer mismatch error")
+	}
+}
@@ -0,0 +1,74 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录B的测试向量（SHA1），取后6位
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		code, err := Code(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.code {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	code, err := Code(secret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !Validate(secret, code, now) {
+		t.Error("current code should be valid")
+	}
+	if !Validate(secret, code, now.Add(Period*time.Second)) {
+		t.Error("code of previous step should be valid")
+	}
+	if Validate(secret, code, now.Add(3*Period*time.Second)) {
+		t.Error("expired code should be invalid")
+	}
+	if Validate(secret, "12345", now) {
+		t.Error("code with wrong length should be invalid")
+	}
+
+	step, ok := ValidateStep(secret, code, now.Add(Period*time.Second))
+	if !ok || step != now.Unix()/Period {
+		t.Errorf("ValidateStep() = %v, %v, want %v, true", step, ok, now.Unix()/Period)
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("aiworkc", "root", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/aiworkc:root?") {
+		t.Errorf("unexpected uri %s", uri)
+	}
+	if !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") || !strings.Contains(uri, "issuer=aiworkc") {
+		t.Errorf("uri missing parameters %s", uri)
+	}
+}
@@ -0,0 +1,23 @@
+// Package wsbus websocket服务多实例部署时，记录用户的连接所在的实例，并在实例之间转发消息。
+// 部署单个实例或测试时使用进程内的实现，多个实例时使用Redis
+package wsbus
+
+import (
+	"context"
+	"time"
+)
+
+type Bus interface {
+	// SetUser 记录用户在实例上的状态，status 为空时表示用户在该实例上已经没有连接
+	SetUser(ctx context.Context, uid, node, status string) error
+	// Users 用户在各存活实例上的状态，用户ID到实例ID到状态，没有连接的用户不返回
+	Users(ctx context.Context, uids ...string) (map[string]map[string]string, error)
+	// KeepAlive 标记实例在 ttl 内存活，实例需要定时调用，过期的实例上的用户不再返回
+	KeepAlive(ctx context.Context, node string, ttl time.Duration) error
+	// Publish 向频道发布消息
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Subscribe 订阅频道，收到消息时依次调用 handle，直到 ctx 结束或连接断开。
+	// handle 在接收消息的连接上执行，需要尽快返回，不能在其中调用总线的其他方法
+	Subscribe(ctx context.Context, handle func(channel string, payload []byte), channels ...string) error
+	Close()
+}
@@ -0,0 +1,140 @@
+package wsbus
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// subscriberBuffer 进程内订阅者的消息缓冲，缓冲满时发布方等待
+const subscriberBuffer = 1024
+
+type message struct {
+	channel string
+	payload []byte
+}
+
+type subscriber struct {
+	channels map[string]struct{}
+	ch       chan message
+	done     chan struct{}
+}
+
+// memoryBus 进程内的实现，同一进程中的多个实例共用一个 memoryBus 即可模拟多实例部署
+type memoryBus struct {
+	mu          sync.RWMutex
+	users       map[string]map[string]string // 用户ID到各实例上的状态
+	alive       map[string]time.Time         // 实例的存活截止时间
+	subscribers map[*subscriber]struct{}
+}
+
+func NewMemoryBus() Bus {
+	return &memoryBus{
+		users:       make(map[string]map[string]string),
+		alive:       make(map[string]time.Time),
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+func (b *memoryBus) SetUser(ctx context.Context, uid, node, status string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	nodes, ok := b.users[uid]
+	if len(status) == 0 {
+		delete(nodes, node)
+		if len(nodes) == 0 {
+			delete(b.users, uid)
+		}
+		return nil
+	}
+
+	if !ok {
+		nodes = make(map[string]string)
+		b.users[uid] = nodes
+	}
+	nodes[node] = status
+	return nil
+}
+
+func (b *memoryBus) Users(ctx context.Context, uids ...string) (map[string]map[string]string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	now := time.Now()
+	res := make(map[string]map[string]string, len(uids))
+	for _, uid := range uids {
+		for node, status := range b.users[uid] {
+			if deadline, ok := b.alive[node]; !ok || deadline.Before(now) {
+				continue
+			}
+			if res[uid] == nil {
+				res[uid] = make(map[string]string)
+			}
+			res[uid][node] = status
+		}
+	}
+	return res, nil
+}
+
+func (b *memoryBus) KeepAlive(ctx context.Context, node string, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.alive[node] = time.Now().Add(ttl)
+	return nil
+}
+
+func (b *memoryBus) Publish(ctx context.Context, channel string, payload []byte) error {
+	b.mu.RLock()
+	var subs []*subscriber
+	for s := range b.subscribers {
+		if _, ok := s.channels[channel]; ok {
+			subs = append(subs, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, s := range subs {
+		select {
+		case s.ch <- message{channel: channel, payload: payload}:
+		case <-s.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *memoryBus) Subscribe(ctx context.Context, handle func(channel string, payload []byte), channels ...string) error {
+	s := &subscriber{
+		channels: make(map[string]struct{}, len(channels)),
+		ch:       make(chan message, subscriberBuffer),
+		done:     make(chan struct{}),
+	}
+	for _, channel := range channels {
+		s.channels[channel] = struct{}{}
+	}
+
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.subscribers, s)
+		b.mu.Unlock()
+		close(s.done)
+	}()
+
+	for {
+		select {
+		case m := <-s.ch:
+			handle(m.channel, m.payload)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *memoryBus) Close() {}
@@ -0,0 +1,86 @@
+package wsbus
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryBus_Users(t *testing.T) {
+	ctx := context.Background()
+	bus := NewMemoryBus()
+
+	bus.KeepAlive(ctx, "node-1", time.Minute)
+	bus.KeepAlive(ctx, "node-2", time.Minute)
+	bus.SetUser(ctx, "u1", "node-1", "online")
+	bus.SetUser(ctx, "u1", "node-2", "away")
+	// 过期的实例不再返回
+	bus.KeepAlive(ctx, "node-3", -time.Second)
+	bus.SetUser(ctx, "u1", "node-3", "online")
+
+	got, _ := bus.Users(ctx, "u1", "u2")
+	want := map[string]map[string]string{"u1": {"node-1": "online", "node-2": "away"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Users() = %v, want %v", got, want)
+	}
+
+	bus.SetUser(ctx, "u1", "node-1", "")
+	bus.SetUser(ctx, "u1", "node-2", "")
+	got, _ = bus.Users(ctx, "u1")
+	if len(got) != 0 {
+		t.Fatalf("Users() after remove = %v, want empty", got)
+	}
+}
+
+func TestMemoryBus_Publish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bus := NewMemoryBus()
+
+	received := make(chan string, 4)
+	subscribe := func(channels ...string) {
+		go bus.Subscribe(ctx, func(channel string, payload []byte) {
+			received <- channel + ":" + string(payload)
+		}, channels...)
+	}
+	subscribe("node-1", "broadcast")
+	subscribe("node-2")
+
+	// 等待订阅生效
+	deadline := time.Now().Add(time.Second)
+	for {
+		b := bus.(*memoryBus)
+		b.mu.RLock()
+		n := len(b.subscribers)
+		b.mu.RUnlock()
+		if n == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	bus.Publish(ctx, "node-1", []byte("a"))
+	bus.Publish(ctx, "node-3", []byte("b"))
+	bus.Publish(ctx, "broadcast", []byte("c"))
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case m := <-received:
+			got = append(got, m)
+		case <-time.After(time.Second):
+			t.Fatalf("received %v, want 2 messages", got)
+		}
+	}
+	want := []string{"node-1:a", "broadcast:c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("received %v, want %v", got, want)
+	}
+
+	select {
+	case m := <-received:
+		t.Fatalf("unexpected message %v", m)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
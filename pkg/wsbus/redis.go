@@ -0,0 +1,125 @@
+package wsbus
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/redis/rueidis"
+)
+
+const (
+	userKeyPrefix = "ws:user:" // 哈希，字段为实例ID，值为用户在该实例上的状态
+	nodeKeyPrefix = "ws:node:" // 实例的存活标记
+	// userKeyExpire 所有实例都异常退出时，用户的记录最多保留的时长
+	userKeyExpire = 24 * time.Hour
+)
+
+type redisBus struct {
+	client rueidis.Client
+}
+
+func NewRedisBus(addr string) (Bus, error) {
+	client, err := rueidis.NewClient(rueidis.ClientOption{
+		InitAddress:  []string{addr},
+		DisableCache: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &redisBus{
+		client: client,
+	}, nil
+}
+
+func (b *redisBus) SetUser(ctx context.Context, uid, node, status string) error {
+	key := userKeyPrefix + uid
+	if len(status) == 0 {
+		return b.client.Do(ctx, b.client.B().Hdel().Key(key).Field(node).Build()).Error()
+	}
+
+	for _, resp := range b.client.DoMulti(ctx,
+		b.client.B().Hset().Key(key).FieldValue().FieldValue(node, status).Build(),
+		b.client.B().Expire().Key(key).Seconds(int64(userKeyExpire/time.Second)).Build(),
+	) {
+		if err := resp.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Users 过滤掉已经过期的实例，并顺便清除这些实例上的记录
+func (b *redisBus) Users(ctx context.Context, uids ...string) (map[string]map[string]string, error) {
+	res := make(map[string]map[string]string, len(uids))
+	if len(uids) == 0 {
+		return res, nil
+	}
+
+	cmds := make(rueidis.Commands, 0, len(uids))
+	for _, uid := range uids {
+		cmds = append(cmds, b.client.B().Hgetall().Key(userKeyPrefix+uid).Build())
+	}
+	var ids []string
+	for i, resp := range b.client.DoMulti(ctx, cmds...) {
+		nodes, err := resp.AsStrMap()
+		if err != nil {
+			return nil, err
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+		res[uids[i]] = nodes
+		for node := range nodes {
+			if !slices.Contains(ids, node) {
+				ids = append(ids, node)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, node := range ids {
+		keys = append(keys, nodeKeyPrefix+node)
+	}
+	alive, err := b.client.Do(ctx, b.client.B().Mget().Key(keys...).Build()).ToArray()
+	if err != nil {
+		return nil, err
+	}
+	for i := range alive {
+		if !alive[i].IsNil() {
+			continue
+		}
+		for uid, nodes := range res {
+			if _, ok := nodes[ids[i]]; !ok {
+				continue
+			}
+			delete(nodes, ids[i])
+			b.client.Do(ctx, b.client.B().Hdel().Key(userKeyPrefix+uid).Field(ids[i]).Build())
+			if len(nodes) == 0 {
+				delete(res, uid)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (b *redisBus) KeepAlive(ctx context.Context, node string, ttl time.Duration) error {
+	return b.client.Do(ctx, b.client.B().Set().Key(nodeKeyPrefix+node).Value("1").Ex(ttl).Build()).Error()
+}
+
+func (b *redisBus) Publish(ctx context.Context, channel string, payload []byte) error {
+	return b.client.Do(ctx, b.client.B().Publish().Channel(channel).Message(rueidis.BinaryString(payload)).Build()).Error()
+}
+
+func (b *redisBus) Subscribe(ctx context.Context, handle func(channel string, payload []byte), channels ...string) error {
+	return b.client.Receive(ctx, b.client.B().Subscribe().Channel(channels...).Build(), func(msg rueidis.PubSubMessage) {
+		handle(msg.Channel, []byte(msg.Message))
+	})
+}
+
+func (b *redisBus) Close() {
+	b.client.Close()
+}
@@ -0,0 +1,61 @@
+package wsbus
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// 需要本地的Redis，通过 WSBUS_REDIS_ADDR 指定地址，如 127.0.0.1:6379
+func TestRedisBus(t *testing.T) {
+	addr := os.Getenv("WSBUS_REDIS_ADDR")
+	if len(addr) == 0 {
+		t.Skip("WSBUS_REDIS_ADDR is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	bus, err := NewRedisBus(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	bus.KeepAlive(ctx, "test-node-1", time.Minute)
+	bus.SetUser(ctx, "test-u1", "test-node-1", "online")
+	bus.SetUser(ctx, "test-u1", "test-node-2", "online") // 没有存活标记的实例
+	defer bus.SetUser(ctx, "test-u1", "test-node-1", "")
+
+	got, err := bus.Users(ctx, "test-u1", "test-u2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]string{"test-u1": {"test-node-1": "online"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Users() = %v, want %v", got, want)
+	}
+
+	received := make(chan string, 1)
+	subCtx, subCancel := context.WithCancel(ctx)
+	defer subCancel()
+	go bus.Subscribe(subCtx, func(channel string, payload []byte) {
+		received <- string(payload)
+	}, "test-channel")
+
+	// 订阅建立前发布的消息会丢失，重复发布直到收到
+	for {
+		bus.Publish(ctx, "test-channel", []byte("hello"))
+		select {
+		case m := <-received:
+			if m != "hello" {
+				t.Fatalf("received %v, want hello", m)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("no message received")
+		}
+	}
+}
@@ -0,0 +1,266 @@
+// Package xlsx 提供读写xlsx表格的最小实现，只处理单个工作表中的文本数据，不处理样式、公式等内容
+package xlsx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+var ErrNoSheet = errors.New("xlsx文件中不存在工作表")
+
+type workbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RId  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationships struct {
+	Relationships []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type richText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r *richText) String() string {
+	if len(r.R) == 0 {
+		return r.T
+	}
+	var sb strings.Builder
+	for _, run := range r.R {
+		sb.WriteString(run.T)
+	}
+	return sb.String()
+}
+
+type sharedStrings struct {
+	Items []richText `xml:"si"`
+}
+
+type worksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string    `xml:"r,attr"`
+			T  string    `xml:"t,attr"`
+			V  string    `xml:"v"`
+			Is *richText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// Read 读取xlsx文件中第一个工作表的所有行，空行会保留为空切片
+func Read(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var shared sharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err = decode(f, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	f, ok := files[firstSheet(files)]
+	if !ok {
+		return nil, ErrNoSheet
+	}
+	var sheet worksheet
+	if err = decode(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for i, row := range sheet.Rows {
+		idx := row.R - 1
+		if idx < 0 {
+			idx = i
+		}
+		for len(rows) < idx {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for j, c := range row.Cells {
+			col := j
+			if len(c.R) > 0 {
+				if col, err = columnIndex(c.R); err != nil {
+					return nil, err
+				}
+			}
+			for len(values) < col {
+				values = append(values, "")
+			}
+
+			var value string
+			switch c.T {
+			case "s":
+				n, err := strconv.Atoi(c.V)
+				if err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, fmt.Errorf("单元格%s引用的共享字符串不存在", c.R)
+				}
+				value = shared.Items[n].String()
+			case "inlineStr":
+				if c.Is != nil {
+					value = c.Is.String()
+				}
+			default:
+				value = c.V
+			}
+			values = append(values, value)
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// Write 将数据写入只包含一个工作表的xlsx文件，所有单元格都按文本写入
+func Write(w io.Writer, sheetName string, rows [][]string) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbookXml, escape(sheetName))},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+
+	fw, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	sb.WriteString(xml.Header)
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(&sb, `<row r="%d">`, i+1)
+		for j, value := range row {
+			fmt.Fprintf(&sb, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`,
+				columnName(j), i+1, escape(value))
+		}
+		sb.WriteString(`</row>`)
+	}
+	sb.WriteString(`</sheetData></worksheet>`)
+	if _, err = io.WriteString(fw, sb.String()); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// firstSheet 根据workbook中的定义找到第一个工作表的文件路径
+func firstSheet(files map[string]*zip.File) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var wb workbook
+	var rels relationships
+	wf, ok1 := files["xl/workbook.xml"]
+	rf, ok2 := files["xl/_rels/workbook.xml.rels"]
+	if !ok1 || !ok2 || decode(wf, &wb) != nil || decode(rf, &rels) != nil || len(wb.Sheets) == 0 {
+		return fallback
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.Id != wb.Sheets[0].RId {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/")
+		}
+		return path.Join("xl", rel.Target)
+	}
+	return fallback
+}
+
+func decode(f *zip.File, v any) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// columnIndex 将单元格坐标如"AB12"的列转换为从0开始的下标
+func columnIndex(ref string) (int, error) {
+	col := 0
+	for i, ch := range ref {
+		if ch >= 'A' && ch <= 'Z' {
+			col = col*26 + int(ch-'A'+1)
+			continue
+		}
+		if i == 0 {
+			return 0, fmt.Errorf("无效的单元格坐标%s", ref)
+		}
+		break
+	}
+	return col - 1, nil
+}
+
+// columnName 将从0开始的列下标转换为列名，如0为A，26为AA
+func columnName(idx int) string {
+	name := ""
+	for idx++; idx > 0; idx = (idx - 1) / 26 {
+		name = string(rune('A'+(idx-1)%26)) + name
+	}
+	return name
+}
+
+func escape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+const (
+	contentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+
+	rootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	workbookXml = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	workbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+)
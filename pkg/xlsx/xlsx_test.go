@@ -0,0 +1,39 @@
+package xlsx
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestWriteRead(t *testing.T) {
+	rows := [][]string{
+		{"类型", "名称", "上级部门"},
+		{"部门", "研发部", ""},
+		{"用户", "张三 <dev> & co", "研发部"},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "组织", rows); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, rows) {
+		t.Fatalf("got %v, want %v", got, rows)
+	}
+}
+
+func TestColumn(t *testing.T) {
+	for idx, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(idx); got != name {
+			t.Fatalf("columnName(%d) = %s, want %s", idx, got, name)
+		}
+		if got, err := columnIndex(name + "12"); err != nil || got != idx {
+			t.Fatalf("columnIndex(%s12) = %d, %v, want %d", name, got, err, idx)
+		}
+	}
+}
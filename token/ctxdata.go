@@ -6,10 +6,25 @@ import (
 	"github.com/golang-jwt/jwt"
 )
 
-const Identify = "LunBoWang"
+const (
+	Identify        = "LunBoWang"
+	SessionIdentify = "sid" // 令牌所属的登录会话
+	// TwoFactorIdentify 二次验证令牌的标识，该令牌只能用于完成登录的二次验证
+	TwoFactorIdentify = "2fa"
+	// SsoIdentify 单点登录state令牌的标识，值为服务端保存的state的随机标识
+	SsoIdentify = "sso"
+	// ApiKeyIdentify 使用API密钥访问时，context中保存的密钥ID
+	ApiKeyIdentify = "apiKey"
+	// ApiKeyPrefix API密钥的前缀，用于与JWT令牌区分
+	ApiKeyPrefix = "pat_"
+	// TenantIdentify 用户所属的租户，默认租户为空
+	TenantIdentify = "tid"
+	// TraceIdentify 请求的追踪ID，与日志一同开始，审计日志中记录该ID
+	TraceIdentify = "traceId"
+)
 
-// GetJwtToken 生成Token
-func GetJwtToken(secretKey string, iat, seconds int64, uid string) (string, error) {
+// GetJwtToken 生成Token，tid 为用户所属的租户
+func GetJwtToken(secretKey string, iat, seconds int64, uid, sid, tid string) (string, error) {
 	// 创建JWT声明（claims），用于存储自定义数据和标准字段
 	claims := make(jwt.MapClaims)
 	// 设置令牌过期时间：签发时间+有效期
@@ -18,6 +33,10 @@ func GetJwtToken(secretKey string, iat, seconds int64, uid string) (string, erro
 	claims["iat"] = iat
 	// 存储用户唯一标识，键名为全局常量Identify
 	claims[Identify] = uid
+	// 存储会话ID，用于校验令牌是否已被撤销
+	claims[SessionIdentify] = sid
+	// 存储租户ID，默认租户不写入
+	setTenant(claims, tid)
 	// 创建一个使用HS256算法的JWT令牌实例
 	token := jwt.New(jwt.SigningMethodHS256)
 	// 将声明设置到令牌中
@@ -26,6 +45,61 @@ func GetJwtToken(secretKey string, iat, seconds int64, uid string) (string, erro
 	return token.SignedString([]byte(secretKey))
 }
 
+// GetTwoFactorToken 生成登录二次验证使用的令牌，令牌中没有会话ID，不能用于访问接口
+func GetTwoFactorToken(secretKey string, iat, seconds int64, uid, tid string) (string, error) {
+	claims := make(jwt.MapClaims)
+	claims["exp"] = iat + seconds
+	claims["iat"] = iat
+	claims[Identify] = uid
+	claims[TwoFactorIdentify] = true
+	setTenant(claims, tid)
+
+	token := jwt.New(jwt.SigningMethodHS256)
+	token.Claims = claims
+	return token.SignedString([]byte(secretKey))
+}
+
+// GetSsoStateToken 生成单点登录的state令牌，回调时校验令牌并取出state标识，令牌中没有用户ID，不能用于访问接口
+func GetSsoStateToken(secretKey string, iat, seconds int64, stateId, tid string) (string, error) {
+	claims := make(jwt.MapClaims)
+	claims["exp"] = iat + seconds
+	claims["iat"] = iat
+	claims[SsoIdentify] = stateId
+	setTenant(claims, tid)
+
+	token := jwt.New(jwt.SigningMethodHS256)
+	token.Claims = claims
+	return token.SignedString([]byte(secretKey))
+}
+
+func setTenant(claims jwt.MapClaims, tid string) {
+	if len(tid) > 0 {
+		claims[TenantIdentify] = tid
+	}
+}
+
+// GetTenantId 从context中获取租户ID，默认租户为空
+func GetTenantId(ctx context.Context) string {
+	tid, _ := ctx.Value(TenantIdentify).(string)
+	return tid
+}
+
+// WithTenantId 将租户ID写入context，用于登录等还没有令牌的请求
+func WithTenantId(ctx context.Context, tid string) context.Context {
+	return context.WithValue(ctx, TenantIdentify, tid)
+}
+
+// GetTraceId 从context中获取请求的追踪ID
+func GetTraceId(ctx context.Context) string {
+	id, _ := ctx.Value(TraceIdentify).(string)
+	return id
+}
+
+// WithTraceId 将追踪ID写入context
+func WithTraceId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, TraceIdentify, id)
+}
+
 // GetUId 从context中获取用户ID
 func GetUId(ctx context.Context) string {
 	var uid string
@@ -35,3 +109,15 @@ func GetUId(ctx context.Context) string {
 	}
 	return uid
 }
+
+// GetApiKeyId 从context中获取API密钥ID，使用登录令牌访问时为空
+func GetApiKeyId(ctx context.Context) string {
+	id, _ := ctx.Value(ApiKeyIdentify).(string)
+	return id
+}
+
+// GetSessionId 从context中获取令牌所属的会话ID
+func GetSessionId(ctx context.Context) string {
+	sid, _ := ctx.Value(SessionIdentify).(string)
+	return sid
+}
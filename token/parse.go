@@ -122,6 +122,11 @@ func (p *Parse) extractTokenFromHeader(r *http.Request) string {
 	return parts[1]
 }
 
+// GetRequestToken 从HTTP请求头中获取令牌字符串，可能是JWT令牌或API密钥
+func GetRequestToken(r *http.Request) string {
+	return (&Parse{}).extractTokenFromHeader(r)
+}
+
 // GetTokenStr 从上下文中获取存储的令牌字符串
 func GetTokenStr(ctx context.Context) string {
 	tokenStr, ok := ctx.Value(Authorization).(string)
@@ -12,7 +12,7 @@ var TestSecretKey = "LunBoWang"
 
 func TestGenToken(t *testing.T) {
 	now := time.Now().Unix()
-	t.Log(GetJwtToken(TestSecretKey, now, 60*60*60, "1"))
+	t.Log(GetJwtToken(TestSecretKey, now, 60*60*60, "1", "1", ""))
 }
 
 func TestVerifyJWTToken(t *testing.T) {